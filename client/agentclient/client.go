@@ -0,0 +1,109 @@
+// Package agentclient adalah contoh client untuk integrator agent. Package ini
+// sengaja tidak meng-import apa pun dari telo supaya bisa langsung di-copy ke
+// project lain.
+//
+// Setiap request ditandatangani dengan header:
+//
+//	X-Agent-Code: kode agent
+//	X-Timestamp:  unix detik
+//	X-Nonce:      string acak, sekali pakai
+//	X-Signature:  hex(HMAC-SHA256(key, canonical))
+//
+// dengan key = hex(sha256(secret)) dan canonical =
+// METHOD + "\n" + URI + "\n" + TIMESTAMP + "\n" + NONCE + "\n" + hex(sha256(body)).
+package agentclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	BaseURL   string
+	AgentCode string
+	Secret    string
+	HTTP      *http.Client
+}
+
+func New(baseURL, agentCode, secret string) *Client {
+	return &Client{
+		BaseURL:   strings.TrimRight(baseURL, "/"),
+		AgentCode: agentCode,
+		Secret:    secret,
+		HTTP:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Post mengirim payload sebagai JSON ke path (misalnya "/user/balance") dan
+// men-decode response ke out.
+func (c *Client) Post(path string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := Sign(req, c.AgentCode, c.Secret, body); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Sign menambahkan header signature ke req. body harus sama persis dengan
+// body yang dikirim.
+func Sign(req *http.Request, agentCode, secret string, body []byte) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		strings.ToUpper(req.Method),
+		req.URL.RequestURI(),
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	keySum := sha256.Sum256([]byte(secret))
+	mac := hmac.New(sha256.New, []byte(hex.EncodeToString(keySum[:])))
+	mac.Write([]byte(canonical))
+
+	req.Header.Set("X-Agent-Code", agentCode)
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Nonce", nonce)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,202 @@
+// Command mockagent adalah wallet agent palsu untuk mencoba mode seamless
+// secara lokal. Saldo disimpan di memori dan setiap request diverifikasi
+// dengan signature yang sama seperti client/agentclient.
+//
+//	MOCK_AGENT_ADDR=:4000 MOCK_AGENT_SECRET=<secret agent> go run ./client/mockagent
+//
+// lalu set wallet_url agent ke http://127.0.0.1:4000 lewat /agent/wallet/config.
+//
+// Env tambahan untuk simulasi gangguan:
+//
+//	MOCK_AGENT_START_BALANCE  saldo awal setiap user (default 1000)
+//	MOCK_AGENT_DELAY_MS       jeda sebelum menjawab, untuk uji timeout
+//	MOCK_AGENT_FAIL_EVERY     setiap request ke-N settle/rollback dijawab HTTP 500
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type request struct {
+	TxID       string          `json:"tx_id"`
+	RefTxID    string          `json:"ref_tx_id"`
+	UserCode   string          `json:"user_code"`
+	Currency   string          `json:"currency"`
+	Amount     decimal.Decimal `json:"amount"`
+	Provider   string          `json:"provider"`
+	Operation  string          `json:"operation"`
+	ExternalID string          `json:"external_id"`
+}
+
+type reply struct {
+	Status  string          `json:"status"`
+	Balance decimal.Decimal `json:"balance"`
+	Message string          `json:"message,omitempty"`
+}
+
+type wallet struct {
+	mu       sync.Mutex
+	start    decimal.Decimal
+	balances map[string]decimal.Decimal
+	// hasil per tx_id, supaya retry tidak memotong/menambah saldo dua kali
+	done     map[string]reply
+	requests int
+}
+
+func (w *wallet) balance(user string) decimal.Decimal {
+	b, ok := w.balances[user]
+	if !ok {
+		b = w.start
+		w.balances[user] = b
+	}
+	return b
+}
+
+func (w *wallet) apply(action string, req request) reply {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if r, ok := w.done[req.TxID]; ok {
+		r.Balance = w.balance(req.UserCode)
+		return r
+	}
+
+	bal := w.balance(req.UserCode)
+	var r reply
+	switch action {
+	case "bet":
+		if bal.Add(req.Amount).IsNegative() {
+			r = reply{Status: "INSUFFICIENT_FUNDS", Balance: bal}
+			break
+		}
+		bal = bal.Add(req.Amount)
+		r = reply{Status: "OK", Balance: bal}
+	case "settle", "rollback":
+		bal = bal.Add(req.Amount)
+		r = reply{Status: "OK", Balance: bal}
+	}
+	w.balances[req.UserCode] = bal
+	if req.TxID != "" {
+		w.done[req.TxID] = r
+	}
+	return r
+}
+
+func main() {
+	addr := envOr("MOCK_AGENT_ADDR", ":4000")
+	secret := os.Getenv("MOCK_AGENT_SECRET")
+	if secret == "" {
+		log.Fatal("MOCK_AGENT_SECRET is required")
+	}
+	keySum := sha256.Sum256([]byte(secret))
+	signingKey := hex.EncodeToString(keySum[:])
+
+	start, err := decimal.NewFromString(envOr("MOCK_AGENT_START_BALANCE", "1000"))
+	if err != nil {
+		log.Fatalf("invalid MOCK_AGENT_START_BALANCE: %v", err)
+	}
+	delay, _ := strconv.Atoi(os.Getenv("MOCK_AGENT_DELAY_MS"))
+	failEvery, _ := strconv.Atoi(os.Getenv("MOCK_AGENT_FAIL_EVERY"))
+
+	w := &wallet{
+		start:    start,
+		balances: map[string]decimal.Decimal{},
+		done:     map[string]reply{},
+	}
+
+	handle := func(action string) http.HandlerFunc {
+		return func(rw http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil || !verify(r, body, signingKey) {
+				writeJSON(rw, http.StatusUnauthorized, reply{Status: "INVALID_SIGNATURE"})
+				return
+			}
+
+			var req request
+			if err := json.Unmarshal(body, &req); err != nil || req.UserCode == "" {
+				writeJSON(rw, http.StatusBadRequest, reply{Status: "INVALID_REQUEST"})
+				return
+			}
+
+			if delay > 0 {
+				time.Sleep(time.Duration(delay) * time.Millisecond)
+			}
+
+			if action == "settle" || action == "rollback" {
+				w.mu.Lock()
+				w.requests++
+				fail := failEvery > 0 && w.requests%failEvery == 0
+				w.mu.Unlock()
+				if fail {
+					log.Printf("💥 %s tx=%s simulated failure", action, req.TxID)
+					writeJSON(rw, http.StatusInternalServerError, reply{Status: "INTERNAL_ERROR"})
+					return
+				}
+			}
+
+			var resp reply
+			if action == "balance" {
+				w.mu.Lock()
+				resp = reply{Status: "OK", Balance: w.balance(req.UserCode)}
+				w.mu.Unlock()
+			} else {
+				resp = w.apply(action, req)
+			}
+
+			log.Printf("📥 %s user=%s tx=%s amount=%s → %s balance=%s",
+				action, req.UserCode, req.TxID, req.Amount, resp.Status, resp.Balance)
+			writeJSON(rw, http.StatusOK, resp)
+		}
+	}
+
+	mux := http.NewServeMux()
+	for _, action := range []string{"balance", "bet", "settle", "rollback"} {
+		mux.HandleFunc("/"+action, handle(action))
+	}
+
+	log.Println("Mock agent wallet running at", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// verify memeriksa X-Signature = hex(HMAC-SHA256(key, canonical)).
+func verify(r *http.Request, body []byte, signingKey string) bool {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get("X-Timestamp"),
+		r.Header.Get("X-Nonce"),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(canonical))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(r.Header.Get("X-Signature"))))
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,29 @@
+// Command backfillgametx mengisi user_game_transactions dari tabel transaksi
+// provider (Evolution, FastSpin, SpadeGaming, Telo, SBO) yang ditulis sebelum
+// callback mencatat baris ternormalisasi. Aman dijalankan ulang.
+//
+//	go run ./cmd/backfillgametx
+package main
+
+import (
+	"log"
+
+	"telo/database"
+	"telo/services/gametx"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	database.Connect()
+
+	counts, err := gametx.Backfill(database.DB)
+	if err != nil {
+		log.Fatalf("❌ backfill failed: %v", err)
+	}
+	log.Printf("🏁 backfill done: %v", counts)
+}
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"errors"
+	"log"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RegisterSubAgentRequest struct {
+	Username string  `json:"username"`
+	GGR      float64 `json:"ggr"`
+}
+
+type TopupSubAgentRequest struct {
+	AgentCode string          `json:"agent_code"`
+	Amount    decimal.Decimal `json:"amount"`
+	Note      string          `json:"note"`
+}
+
+type DownlineSummaryRequest struct {
+	// AgentCode opsional; default agent yang login. Harus berada di subtree-nya.
+	AgentCode string `json:"agent_code"`
+}
+
+var errInsufficientAgentBalance = errors.New("insufficient agent balance")
+
+// RegisterSubAgent membuat sub-agent satu tier di bawah agent yang login.
+func RegisterSubAgent(c *fiber.Ctx) error {
+	var req RegisterSubAgentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.Username == "" {
+		return helpers.JSONError(c, "USERNAME_REQUIRED")
+	}
+
+	parent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	tier, ok := models.ChildTier(parent.Tier)
+	if !ok {
+		return helpers.JSONError(c, "TIER_CANNOT_HAVE_SUBAGENTS")
+	}
+
+	if req.GGR <= 0 {
+		req.GGR = parent.GGR
+	}
+
+	agentCode := helpers.GenerateAgentCode()
+	secretKey := uuid.New().String()
+
+	var existing models.Agent
+	if err := database.DB.Where("agent_code = ?", agentCode).First(&existing).Error; err == nil {
+		return helpers.JSONError(c, "AGENT_CODE_ALREADY_EXISTS")
+	}
+
+	signingKey, err := helpers.SealSigningKey(secretKey, agentCode)
+	if err != nil {
+		log.Printf("❌ Failed to seal signing key for %s: %v", agentCode, err)
+		return helpers.JSONError(c, "FAILED_TO_REGISTER_AGENT")
+	}
+
+	agent := models.Agent{
+		Username:      req.Username,
+		AgentCode:     agentCode,
+		SigningKeyEnc: signingKey,
+		Currency:      parent.Currency,
+		GGR:           req.GGR,
+		Balance:       decimal.Zero,
+		IsActive:      true,
+		Tier:          tier,
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return createAgent(tx, &agent, &parent)
+	}); err != nil {
+		return helpers.JSONError(c, "FAILED_TO_REGISTER_AGENT")
+	}
+
+	return helpers.JSONSuccess(c, "Sub-agent registered successfully", fiber.Map{
+		"username":    agent.Username,
+		"agent_code":  agent.AgentCode,
+		"secret_key":  secretKey,
+		"currency":    agent.Currency,
+		"ggr":         agent.GGR,
+		"tier":        agent.Tier,
+		"parent_code": agent.ParentCode,
+	})
+}
+
+// TopupSubAgent memindahkan saldo dari agent yang login ke sub-agent langsung.
+func TopupSubAgent(c *fiber.Ctx) error {
+	var req TopupSubAgentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.AgentCode == "" || !req.Amount.IsPositive() {
+		return helpers.JSONError(c, "AGENT_CODE_AND_VALID_AMOUNT_REQUIRED")
+	}
+
+	parent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	note := req.Note
+	if note == "" {
+		note = "Top-up from parent via API"
+	}
+	refID := uuid.New().String()
+
+	var child models.Agent
+	var childBefore decimal.Decimal
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_code = ? AND parent_id = ? AND is_active = true", req.AgentCode, parent.ID).
+			First(&child).Error; err != nil {
+			return errAgentNotFound
+		}
+
+		// Lock berurutan by ID supaya dua top-up berlawanan arah tidak deadlock.
+		var locked []models.Agent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{parent.ID, child.ID}).
+			Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		for _, a := range locked {
+			if a.ID == parent.ID {
+				parent = a
+			} else {
+				child = a
+			}
+		}
+
+		if parent.Balance.LessThan(req.Amount) {
+			return errInsufficientAgentBalance
+		}
+
+		parentBefore := parent.Balance
+		childBefore = child.Balance
+		parent.Balance = parent.Balance.Sub(req.Amount)
+		child.Balance = child.Balance.Add(req.Amount)
+
+		if err := tx.Model(&parent).Update("balance", parent.Balance).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&child).Update("balance", child.Balance).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&[]models.AgentTransaction{
+			{
+				AgentID:       parent.ID,
+				AgentCode:     parent.AgentCode,
+				TrxType:       "DOWNLINE_OUT",
+				Amount:        req.Amount,
+				BalanceBefore: parentBefore,
+				BalanceAfter:  parent.Balance,
+				Currency:      parent.Currency,
+				Note:          note + " (to: " + child.AgentCode + ")",
+				RefID:         refID,
+			},
+			{
+				AgentID:       child.ID,
+				AgentCode:     child.AgentCode,
+				TrxType:       "DOWNLINE_IN",
+				Amount:        req.Amount,
+				BalanceBefore: childBefore,
+				BalanceAfter:  child.Balance,
+				Currency:      child.Currency,
+				Note:          note + " (from: " + parent.AgentCode + ")",
+				RefID:         refID,
+			},
+		}).Error
+	})
+	if errors.Is(err, errAgentNotFound) {
+		return helpers.JSONError(c, "SUBAGENT_NOT_FOUND")
+	}
+	if errors.Is(err, errInsufficientAgentBalance) {
+		return helpers.JSONError(c, "INSUFFICIENT_AGENT_BALANCE")
+	}
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_UPDATE_BALANCE")
+	}
+
+	return helpers.JSONSuccess(c, "Sub-agent top-up successful", fiber.Map{
+		"agent_code":     child.AgentCode,
+		"parent_code":    parent.AgentCode,
+		"amount":         req.Amount,
+		"ref_id":         refID,
+		"balance_before": childBefore,
+		"balance_after":  child.Balance,
+		"parent_balance": parent.Balance,
+	})
+}
+
+type downlineRollup struct {
+	AgentCount   int64           `json:"agent_count"`
+	AgentBalance decimal.Decimal `json:"agent_balance"`
+	UserCount    int64           `json:"user_count"`
+	UserBalance  decimal.Decimal `json:"user_balance"`
+}
+
+// rollupSubtree menjumlahkan agent dan user di bawah pattern path (termasuk
+// agent pemilik path itu sendiri).
+func rollupSubtree(db *gorm.DB, pattern string) (downlineRollup, error) {
+	var agents, users struct {
+		Count   int64
+		Balance decimal.Decimal
+	}
+	if err := db.Model(&models.Agent{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(balance), 0) AS balance").
+		Where("path LIKE ?", pattern).
+		Scan(&agents).Error; err != nil {
+		return downlineRollup{}, err
+	}
+	if err := db.Model(&models.User{}).
+		Select("COUNT(users.id) AS count, COALESCE(SUM(users.balance), 0) AS balance").
+		Joins("JOIN agents ON agents.agent_code = users.agent_code AND agents.deleted_at IS NULL").
+		Where("agents.path LIKE ?", pattern).
+		Scan(&users).Error; err != nil {
+		return downlineRollup{}, err
+	}
+	return downlineRollup{
+		AgentCount:   agents.Count,
+		AgentBalance: agents.Balance,
+		UserCount:    users.Count,
+		UserBalance:  users.Balance,
+	}, nil
+}
+
+// DownlineSummary me-rollup jumlah agent, user, dan saldo di seluruh subtree,
+// plus rincian per sub-agent langsung.
+func DownlineSummary(c *fiber.Ctx) error {
+	var req DownlineSummaryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	caller, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	root := caller
+	if req.AgentCode != "" && req.AgentCode != caller.AgentCode {
+		if err := database.DB.Where("agent_code = ? AND path LIKE ?", req.AgentCode, caller.SubtreePattern()).
+			First(&root).Error; err != nil {
+			return helpers.JSONError(c, "AGENT_NOT_IN_DOWNLINE")
+		}
+	}
+
+	total, err := rollupSubtree(database.DB, root.SubtreePattern())
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_DOWNLINE")
+	}
+
+	var children []models.Agent
+	if err := database.DB.Where("parent_id = ?", root.ID).Order("id").Find(&children).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_DOWNLINE")
+	}
+
+	items := make([]fiber.Map, 0, len(children))
+	for _, child := range children {
+		r, err := rollupSubtree(database.DB, child.SubtreePattern())
+		if err != nil {
+			return helpers.JSONError(c, "FAILED_TO_FETCH_DOWNLINE")
+		}
+		items = append(items, fiber.Map{
+			"agent_code": child.AgentCode,
+			"username":   child.Username,
+			"tier":       child.Tier,
+			"balance":    child.Balance,
+			"is_active":  child.IsActive,
+			"downline":   r,
+		})
+	}
+
+	return helpers.JSONSuccess(c, "Downline summary retrieved successfully", fiber.Map{
+		"agent_code": root.AgentCode,
+		"tier":       root.Tier,
+		"balance":    root.Balance,
+		"downline":   total,
+		"children":   items,
+	})
+}
@@ -9,13 +9,9 @@ import (
 )
 
 func AgentInfo(c *fiber.Ctx) error {
-	agentCode := c.Get("X-Agent-Code")
-	secretKey := c.Get("X-Secret-Key")
-
-	var agent models.Agent
-	if err := database.DB.Where("agent_code = ? AND secret_key = ? AND is_active = true", agentCode, secretKey).
-		First(&agent).Error; err != nil {
-		return helpers.JSONError(c, "INVALID_AGENT_CREDENTIALS")
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
 	}
 
 	var totalUserBalance float64
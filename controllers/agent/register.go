@@ -1,12 +1,16 @@
 package agent
 
 import (
+	"fmt"
+	"log"
 	"telo/database"
 	"telo/helpers"
 	"telo/models"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 type RegisterAgentRequest struct {
@@ -33,25 +37,54 @@ func RegisterAgent(c *fiber.Ctx) error {
 		return helpers.JSONError(c, "AGENT_CODE_ALREADY_EXISTS")
 	}
 
+	signingKey, err := helpers.SealSigningKey(secretKey, agentCode)
+	if err != nil {
+		log.Printf("❌ Failed to seal signing key for %s: %v", agentCode, err)
+		return helpers.JSONError(c, "FAILED_TO_REGISTER_AGENT")
+	}
+
 	agent := models.Agent{
-		Username:  req.Username,
-		AgentCode: agentCode,
-		SecretKey: secretKey,
-		Currency:  req.Currency,
-		GGR:       req.GGR,
-		Balance:   0,
-		IsActive:  true,
+		Username:      req.Username,
+		AgentCode:     agentCode,
+		SigningKeyEnc: signingKey,
+		Currency:      req.Currency,
+		GGR:           req.GGR,
+		Balance:       decimal.Zero,
+		IsActive:      true,
+		Tier:          models.AgentTierMaster,
 	}
 
-	if err := database.DB.Create(&agent).Error; err != nil {
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return createAgent(tx, &agent, nil)
+	}); err != nil {
 		return helpers.JSONError(c, "FAILED_TO_REGISTER_AGENT")
 	}
 
 	return helpers.JSONSuccess(c, "Agent registered successfully", fiber.Map{
 		"username":   agent.Username,
 		"agent_code": agent.AgentCode,
-		"secret_key": agent.SecretKey,
+		"secret_key": secretKey,
 		"currency":   agent.Currency,
 		"ggr":        agent.GGR,
+		"tier":       agent.Tier,
 	})
 }
+
+// createAgent menyimpan agent baru di bawah parent (nil untuk root) dan
+// mengisi ParentID, ParentCode, dan Path-nya.
+func createAgent(tx *gorm.DB, agent *models.Agent, parent *models.Agent) error {
+	if parent != nil {
+		agent.ParentID = &parent.ID
+		agent.ParentCode = parent.AgentCode
+	}
+	if err := tx.Create(agent).Error; err != nil {
+		return err
+	}
+
+	prefix := "/"
+	if parent != nil {
+		prefix = parent.Path
+	}
+	agent.Path = fmt.Sprintf("%s%d/", prefix, agent.ID)
+	return tx.Model(agent).Update("path", agent.Path).Error
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"os"
+	"strconv"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultSecretGrace = 24 * time.Hour
+	maxSecretGrace     = 7 * 24 * time.Hour
+)
+
+type RotateSecretRequest struct {
+	// AgentCode hanya dipakai oleh endpoint master.
+	AgentCode string `json:"agent_code"`
+	// GraceSeconds: berapa lama secret lama masih diterima. Kosong = default
+	// AGENT_SECRET_GRACE_SECONDS (24 jam), 0 = secret lama langsung mati.
+	GraceSeconds *int   `json:"grace_seconds"`
+	Reason       string `json:"reason"`
+}
+
+func secretGrace(req RotateSecretRequest) time.Duration {
+	grace := defaultSecretGrace
+	if v, err := strconv.Atoi(os.Getenv("AGENT_SECRET_GRACE_SECONDS")); err == nil && v >= 0 {
+		grace = time.Duration(v) * time.Second
+	}
+	if req.GraceSeconds != nil && *req.GraceSeconds >= 0 {
+		grace = time.Duration(*req.GraceSeconds) * time.Second
+	}
+	if grace > maxSecretGrace {
+		grace = maxSecretGrace
+	}
+	return grace
+}
+
+// rotateSecret membuat secret baru untuk agent, memindahkan signing key lama
+// (terenkripsi) ke PreviousSigningKeyEnc selama grace period, dan mencatat
+// audit rotasi.
+func rotateSecret(agentID uint, rotatedBy, ip, reason string, grace time.Duration) (models.Agent, string, time.Time, error) {
+	secret := uuid.New().String()
+	graceUntil := time.Now().Add(grace)
+
+	var agent models.Agent
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&agent, agentID).Error; err != nil {
+			return err
+		}
+
+		keys, err := agent.SigningKeys(time.Now())
+		if err != nil {
+			return err
+		}
+		previous, err := helpers.SealSecret(keys[0], agent.AgentCode)
+		if err != nil {
+			return err
+		}
+		current, err := helpers.SealSigningKey(secret, agent.AgentCode)
+		if err != nil {
+			return err
+		}
+		agent.SigningKeyEnc = current
+		agent.PreviousSigningKeyEnc = previous
+		agent.PreviousSecretExpiresAt = &graceUntil
+
+		if err := tx.Model(&agent).Updates(map[string]any{
+			"signing_key_enc":            agent.SigningKeyEnc,
+			"previous_signing_key_enc":   agent.PreviousSigningKeyEnc,
+			"previous_secret_expires_at": agent.PreviousSecretExpiresAt,
+			"secret_key":                 "",
+			"secret_hash":                "",
+			"previous_secret_hash":       "",
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.AgentSecretRotation{
+			AgentID:    agent.ID,
+			AgentCode:  agent.AgentCode,
+			RotatedBy:  rotatedBy,
+			GraceUntil: graceUntil,
+			IP:         ip,
+			Reason:     reason,
+		}).Error
+	})
+	return agent, secret, graceUntil, err
+}
+
+// RotateSecret: agent merotasi secret-nya sendiri.
+func RotateSecret(c *fiber.Ctx) error {
+	var req RotateSecretRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	caller, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	agent, secret, graceUntil, err := rotateSecret(caller.ID, caller.AgentCode, c.IP(), req.Reason, secretGrace(req))
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_ROTATE_SECRET")
+	}
+
+	return helpers.JSONSuccess(c, "Secret rotated successfully", fiber.Map{
+		"agent_code":  agent.AgentCode,
+		"secret_key":  secret,
+		"grace_until": graceUntil,
+	})
+}
+
+// RotateAgentSecret: master (env) merotasi secret agent mana pun, misalnya
+// kalau secret agent hilang atau bocor.
+func RotateAgentSecret(c *fiber.Ctx) error {
+	var req RotateSecretRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.AgentCode == "" {
+		return helpers.JSONError(c, "AGENT_CODE_REQUIRED")
+	}
+
+	var target models.Agent
+	if err := database.DB.Where("agent_code = ?", req.AgentCode).First(&target).Error; err != nil {
+		return helpers.JSONError(c, "AGENT_NOT_FOUND")
+	}
+
+	agent, secret, graceUntil, err := rotateSecret(target.ID, "MASTER", c.IP(), req.Reason, secretGrace(req))
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_ROTATE_SECRET")
+	}
+
+	return helpers.JSONSuccess(c, "Secret rotated successfully", fiber.Map{
+		"agent_code":  agent.AgentCode,
+		"secret_key":  secret,
+		"grace_until": graceUntil,
+	})
+}
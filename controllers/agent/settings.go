@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"errors"
+	"sort"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"telo/providers"
+	"telo/services/agentsettings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+)
+
+type UpdateSettingsRequest struct {
+	// AgentCode: sub-agent langsung (endpoint downline) atau agent mana pun
+	// (endpoint master).
+	AgentCode  string                         `json:"agent_code"`
+	Categories []agentsettings.CategoryUpdate `json:"categories"`
+	Providers  []agentsettings.ProviderUpdate `json:"providers"`
+}
+
+// effectiveSettings merangkum setting yang berlaku untuk agent: per kategori
+// (aktif + rate) dan per provider launcher.
+func effectiveSettings(agent models.Agent) (fiber.Map, error) {
+	var cats []models.AgentCategorySetting
+	if err := database.DB.Where("agent_id = ?", agent.ID).Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	var provs []models.AgentProviderSetting
+	if err := database.DB.Where("agent_id = ?", agent.ID).Find(&provs).Error; err != nil {
+		return nil, err
+	}
+
+	catEnabled := map[string]bool{}
+	categories := make([]fiber.Map, 0, len(providers.Categories))
+	for _, name := range providers.Categories {
+		enabled, rate := true, decimal.NewFromFloat(agent.GGR)
+		for _, cs := range cats {
+			if cs.Category == name {
+				enabled = cs.Enabled
+				if cs.GGRRate.Valid {
+					rate = cs.GGRRate.Decimal
+				}
+			}
+		}
+		catEnabled[name] = enabled
+		categories = append(categories, fiber.Map{
+			"category": name,
+			"enabled":  enabled,
+			"ggr_rate": rate,
+		})
+	}
+
+	override := map[string]bool{}
+	for _, ps := range provs {
+		override[ps.ProviderCode] = ps.Enabled
+	}
+
+	codes := make([]string, 0, len(providers.GameLaunchers))
+	for code := range providers.GameLaunchers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	list := make([]fiber.Map, 0, len(codes))
+	for _, code := range codes {
+		category := providers.GetCategory(code)
+		enabled, overridden := override[code]
+		if !overridden {
+			enabled = catEnabled[category]
+			if category == "" {
+				enabled = true
+			}
+		}
+		list = append(list, fiber.Map{
+			"provider_code": code,
+			"category":      category,
+			"enabled":       enabled,
+			"overridden":    overridden,
+		})
+	}
+
+	return fiber.Map{
+		"agent_code": agent.AgentCode,
+		"ggr":        agent.GGR,
+		"categories": categories,
+		"providers":  list,
+	}, nil
+}
+
+func settingsError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, agentsettings.ErrUnknownCategory):
+		return helpers.JSONError(c, "UNKNOWN_CATEGORY")
+	case errors.Is(err, agentsettings.ErrUnknownProvider):
+		return helpers.JSONError(c, "UNKNOWN_PROVIDER")
+	case errors.Is(err, agentsettings.ErrInvalidRate):
+		return helpers.JSONError(c, "INVALID_GGR_RATE")
+	default:
+		return helpers.JSONError(c, "FAILED_TO_UPDATE_SETTINGS")
+	}
+}
+
+// GetSettings: setting provider & rate GGR milik agent yang login.
+func GetSettings(c *fiber.Ctx) error {
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	data, err := effectiveSettings(agent)
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_SETTINGS")
+	}
+	return helpers.JSONSuccess(c, "Settings retrieved successfully", data)
+}
+
+// UpdateDownlineSettings: parent mengatur setting sub-agent langsung. Parent
+// tidak bisa mengaktifkan provider/kategori yang untuk dirinya sendiri mati.
+func UpdateDownlineSettings(c *fiber.Ctx) error {
+	var req UpdateSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	parent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	var child models.Agent
+	if err := database.DB.Where("agent_code = ? AND parent_id = ?", req.AgentCode, parent.ID).
+		First(&child).Error; err != nil {
+		return helpers.JSONError(c, "SUBAGENT_NOT_FOUND")
+	}
+
+	for _, u := range req.Providers {
+		if u.Enabled == nil || !*u.Enabled {
+			continue
+		}
+		enabled, err := agentsettings.IsProviderEnabled(database.DB, parent.ID, u.ProviderCode)
+		if err != nil {
+			return helpers.JSONError(c, "FAILED_TO_UPDATE_SETTINGS")
+		}
+		if !enabled {
+			return helpers.JSONError(c, "PROVIDER_DISABLED_FOR_PARENT")
+		}
+	}
+	for _, u := range req.Categories {
+		if u.Enabled == nil || !*u.Enabled {
+			continue
+		}
+		var cs models.AgentCategorySetting
+		if err := database.DB.Where("agent_id = ? AND category = ? AND enabled = false", parent.ID, u.Category).
+			First(&cs).Error; err == nil {
+			return helpers.JSONError(c, "CATEGORY_DISABLED_FOR_PARENT")
+		}
+	}
+
+	if err := agentsettings.Apply(database.DB, child.ID, req.Categories, req.Providers); err != nil {
+		return settingsError(c, err)
+	}
+
+	data, err := effectiveSettings(child)
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_SETTINGS")
+	}
+	return helpers.JSONSuccess(c, "Settings updated successfully", data)
+}
+
+// UpdateAgentSettings: master (env) mengatur setting agent mana pun.
+func UpdateAgentSettings(c *fiber.Ctx) error {
+	var req UpdateSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	var agent models.Agent
+	if err := database.DB.Where("agent_code = ?", req.AgentCode).First(&agent).Error; err != nil {
+		return helpers.JSONError(c, "AGENT_NOT_FOUND")
+	}
+
+	if err := agentsettings.Apply(database.DB, agent.ID, req.Categories, req.Providers); err != nil {
+		return settingsError(c, err)
+	}
+
+	data, err := effectiveSettings(agent)
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_SETTINGS")
+	}
+	return helpers.JSONSuccess(c, "Settings updated successfully", data)
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ListSettlementsRequest struct {
+	Provider string `json:"provider"`
+	// From / To format YYYY-MM-DD (UTC), inklusif.
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// ListSettlements mengembalikan statement GGR milik agent yang login.
+func ListSettlements(c *fiber.Ctx) error {
+	var req ListSettlementsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	if req.Limit <= 0 || req.Limit > 500 {
+		req.Limit = 100
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	q := database.DB.Model(&models.AgentSettlement{}).Where("agent_id = ?", agent.ID)
+	if req.Provider != "" {
+		q = q.Where("provider = ?", req.Provider)
+	}
+	if req.From != "" {
+		from, err := time.Parse("2006-01-02", req.From)
+		if err != nil {
+			return helpers.JSONError(c, "INVALID_FROM_DATE")
+		}
+		q = q.Where("period_start >= ?", from)
+	}
+	if req.To != "" {
+		to, err := time.Parse("2006-01-02", req.To)
+		if err != nil {
+			return helpers.JSONError(c, "INVALID_TO_DATE")
+		}
+		q = q.Where("period_start < ?", to.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_SETTLEMENTS")
+	}
+
+	var items []models.AgentSettlement
+	if err := q.Order("period_start DESC, provider").
+		Limit(req.Limit).Offset(req.Offset).
+		Find(&items).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_SETTLEMENTS")
+	}
+
+	return helpers.JSONSuccess(c, "Settlements retrieved successfully", fiber.Map{
+		"agent_code": agent.AgentCode,
+		"total":      total,
+		"limit":      req.Limit,
+		"offset":     req.Offset,
+		"items":      items,
+	})
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"errors"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AgentStatusRequest struct {
+	AgentCode string `json:"agent_code"`
+	Reason    string `json:"reason"`
+}
+
+var errStatusUnchanged = errors.New("status unchanged")
+
+// SuspendAgent: master (env) menonaktifkan agent. Agent tidak bisa memanggil
+// API dan user-nya tidak bisa pasang bet baru, tapi settle/refund dari
+// provider tetap diterima.
+func SuspendAgent(c *fiber.Ctx) error {
+	return setAgentStatus(c, false, models.AccountActionSuspend)
+}
+
+// ReactivateAgent: master (env) mengaktifkan kembali agent yang di-suspend.
+func ReactivateAgent(c *fiber.Ctx) error {
+	return setAgentStatus(c, true, models.AccountActionReactivate)
+}
+
+func setAgentStatus(c *fiber.Ctx, active bool, action string) error {
+	var req AgentStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.AgentCode == "" || req.Reason == "" {
+		return helpers.JSONError(c, "AGENT_CODE_AND_REASON_REQUIRED")
+	}
+	if len(req.Reason) > 255 {
+		return helpers.JSONError(c, "REASON_TOO_LONG")
+	}
+
+	var agent models.Agent
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_code = ?", req.AgentCode).First(&agent).Error; err != nil {
+			return errAgentNotFound
+		}
+		if agent.IsActive == active {
+			return errStatusUnchanged
+		}
+
+		if err := tx.Model(&agent).Update("is_active", active).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.AccountStatusChange{
+			SubjectType: models.AccountSubjectAgent,
+			SubjectID:   agent.ID,
+			SubjectCode: agent.AgentCode,
+			Action:      action,
+			WasActive:   !active,
+			IsActive:    active,
+			Reason:      req.Reason,
+			ChangedBy:   "MASTER",
+			IP:          c.IP(),
+		}).Error
+	})
+	if errors.Is(err, errAgentNotFound) {
+		return helpers.JSONError(c, "AGENT_NOT_FOUND")
+	}
+	if errors.Is(err, errStatusUnchanged) {
+		if active {
+			return helpers.JSONError(c, "AGENT_ALREADY_ACTIVE")
+		}
+		return helpers.JSONError(c, "AGENT_ALREADY_SUSPENDED")
+	}
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_UPDATE_AGENT_STATUS")
+	}
+
+	return helpers.JSONSuccess(c, "Agent status updated successfully", fiber.Map{
+		"agent_code": agent.AgentCode,
+		"is_active":  active,
+		"action":     action,
+	})
+}
@@ -1,67 +1,96 @@
 package agent
 
 import (
+	"errors"
 	"telo/database"
 	"telo/helpers"
 	"telo/models"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TopupAgentRequest struct {
-	AgentCode string `json:"agent_code"`
-	Amount    int64  `json:"amount"`
-	Note      string `json:"note"`
+	AgentCode string          `json:"agent_code"`
+	Amount    decimal.Decimal `json:"amount"`
+	Note      string          `json:"note"`
 }
 
+var (
+	errAgentNotFound = errors.New("agent not found")
+	errInvalidGGR    = errors.New("agent ggr rate must be positive")
+)
+
 func TopupAgentBalance(c *fiber.Ctx) error {
 	var req TopupAgentRequest
 	if err := c.BodyParser(&req); err != nil {
 		return helpers.JSONError(c, "INVALID_JSON")
 	}
 
-	if req.AgentCode == "" || req.Amount <= 0 {
+	if req.AgentCode == "" || !req.Amount.IsPositive() {
 		return helpers.JSONError(c, "AGENT_CODE_AND_VALID_AMOUNT_REQUIRED")
 	}
 
-	var agent models.Agent
-	if err := database.DB.Where("agent_code = ? AND is_active = true", req.AgentCode).First(&agent).Error; err != nil {
-		return helpers.JSONError(c, "AGENT_NOT_FOUND")
+	refID := uuid.New().String()
+
+	note := req.Note
+	if note == "" {
+		note = "Top-up via API"
 	}
 
-	before := agent.Balance
-	amountTopup := float64(req.Amount)
-	ggrRate := agent.GGR
+	// Top-up dari master env hanya untuk root agent; sub-agent diisi oleh
+	// parent-nya lewat /agent/downline/topup.
+	var agent models.Agent
+	var before decimal.Decimal
+	var trx models.AgentTransaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_code = ? AND is_active = true AND parent_id IS NULL", req.AgentCode).
+			First(&agent).Error; err != nil {
+			return errAgentNotFound
+		}
 
-	totalTopup := amountTopup / (ggrRate / 100.0)
+		// Saldo yang dikreditkan = amount / (GGR% / 100); GGR nol tidak
+		// punya arti dan akan membagi dengan nol.
+		ggrRate := decimal.NewFromFloat(agent.GGR).Div(decimal.NewFromInt(100))
+		if !ggrRate.IsPositive() {
+			return errInvalidGGR
+		}
 
-	agent.Balance = int64(float64(agent.Balance) + totalTopup)
+		before = agent.Balance
+		totalTopup := req.Amount.Div(ggrRate).Truncate(0)
 
-	if err := database.DB.Save(&agent).Error; err != nil {
-		return helpers.JSONError(c, "FAILED_TO_UPDATE_BALANCE")
-	}
+		agent.Balance = agent.Balance.Add(totalTopup)
 
-	refID := uuid.New().String()
+		if err := tx.Model(&agent).Update("balance", agent.Balance).Error; err != nil {
+			return err
+		}
 
-	note := req.Note
-	if note == "" {
-		note = "Top-up via API"
+		trx = models.AgentTransaction{
+			AgentID:       agent.ID,
+			AgentCode:     agent.AgentCode,
+			TrxType:       "TOP_UP",
+			Amount:        req.Amount,
+			BalanceBefore: before,
+			BalanceAfter:  agent.Balance,
+			Currency:      agent.Currency,
+			Note:          note,
+			RefID:         refID,
+		}
+		return tx.Create(&trx).Error
+	})
+	if errors.Is(err, errAgentNotFound) {
+		return helpers.JSONError(c, "AGENT_NOT_FOUND")
 	}
-
-	trx := models.AgentTransaction{
-		AgentID:       agent.ID,
-		AgentCode:     agent.AgentCode,
-		TrxType:       "TOP_UP",
-		Amount:        req.Amount,
-		BalanceBefore: int64(before),
-		BalanceAfter:  int64(agent.Balance),
-		Currency:      agent.Currency,
-		Note:          note,
-		RefID:         refID,
+	if errors.Is(err, errInvalidGGR) {
+		return helpers.JSONError(c, "AGENT_GGR_NOT_CONFIGURED")
+	}
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_UPDATE_BALANCE")
 	}
-
-	_ = database.DB.Create(&trx)
 
 	return helpers.JSONSuccess(c, "Agent top-up successful", fiber.Map{
 		"agent_code":     agent.AgentCode,
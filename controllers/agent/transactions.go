@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"errors"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ListAgentTransactionsRequest struct {
+	helpers.PageRequest
+	Type  string `json:"type"`
+	RefID string `json:"ref_id"`
+}
+
+// ListAgentTransactions: mutasi saldo agent yang login (top-up, transfer ke
+// user, downline, GGR), terbaru dulu, dengan cursor pagination.
+func ListAgentTransactions(c *fiber.Ctx) error {
+	var req ListAgentTransactionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	q := database.DB.Model(&models.AgentTransaction{}).Where("agent_id = ?", agent.ID)
+	if req.Type != "" {
+		q = q.Where("trx_type = ?", req.Type)
+	}
+	if req.RefID != "" {
+		q = q.Where("ref_id = ?", req.RefID)
+	}
+
+	q, limit, err := helpers.Paginate(q, req.PageRequest)
+	if errors.Is(err, helpers.ErrInvalidCursor) {
+		return helpers.JSONError(c, "INVALID_CURSOR")
+	}
+	if err != nil {
+		return helpers.JSONError(c, "INVALID_DATE_RANGE")
+	}
+
+	var rows []models.AgentTransaction
+	if err := q.Find(&rows).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_TRANSACTIONS")
+	}
+	rows, next := helpers.PageResult(rows, limit, func(t models.AgentTransaction) uint { return t.ID })
+
+	items := make([]fiber.Map, 0, len(rows))
+	for _, t := range rows {
+		items = append(items, fiber.Map{
+			"id":             t.ID,
+			"trx_type":       t.TrxType,
+			"amount":         t.Amount,
+			"balance_before": t.BalanceBefore,
+			"balance_after":  t.BalanceAfter,
+			"currency":       t.Currency,
+			"note":           t.Note,
+			"ref_id":         t.RefID,
+			"created_at":     t.CreatedAt,
+		})
+	}
+
+	return helpers.JSONSuccess(c, "Transactions retrieved successfully", fiber.Map{
+		"agent_code":  agent.AgentCode,
+		"items":       items,
+		"next_cursor": next,
+	})
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"errors"
+	"net/url"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"telo/services/agentwallet"
+	"telo/services/ledger"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WalletConfigRequest struct {
+	AgentCode  string `json:"agent_code"`
+	WalletMode string `json:"wallet_mode"`
+	WalletURL  string `json:"wallet_url"`
+}
+
+var errUsersHaveBalance = errors.New("users still have balance")
+
+// UpdateWalletConfig: master (env) mengganti mode wallet agent.
+//
+// transfer → seamless hanya boleh kalau saldo semua user agent sudah nol
+// (saldo harus ditarik dulu lewat /user/transfer). seamless → transfer
+// mengenolkan saldo cermin user karena uangnya ada di wallet agent.
+func UpdateWalletConfig(c *fiber.Ctx) error {
+	var req WalletConfigRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.WalletMode != agentwallet.ModeTransfer && req.WalletMode != agentwallet.ModeSeamless {
+		return helpers.JSONError(c, "INVALID_WALLET_MODE")
+	}
+	if req.WalletMode == agentwallet.ModeSeamless {
+		u, err := url.Parse(req.WalletURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return helpers.JSONError(c, "INVALID_WALLET_URL")
+		}
+	}
+
+	var agent models.Agent
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_code = ?", req.AgentCode).First(&agent).Error; err != nil {
+			return errAgentNotFound
+		}
+
+		current := agent.WalletMode
+		if current == "" {
+			current = agentwallet.ModeTransfer
+		}
+
+		if current != req.WalletMode {
+			var users []models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("agent_code = ? AND balance <> 0", agent.AgentCode).
+				Find(&users).Error; err != nil {
+				return err
+			}
+
+			if req.WalletMode == agentwallet.ModeSeamless && len(users) > 0 {
+				return errUsersHaveBalance
+			}
+			for _, u := range users {
+				if err := ledger.PostUserMovement(tx, u, ledger.AgentAccount(agent.AgentCode), u.Balance.Neg(),
+					"AGENT", "SYNC", "Switch to transfer wallet mode", nil); err != nil {
+					return err
+				}
+				if err := tx.Model(&models.User{}).Where("id = ?", u.ID).Update("balance", 0).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		agent.WalletMode = req.WalletMode
+		agent.WalletURL = req.WalletURL
+		return tx.Model(&agent).Updates(map[string]any{
+			"wallet_mode": agent.WalletMode,
+			"wallet_url":  agent.WalletURL,
+		}).Error
+	})
+	if errors.Is(err, errAgentNotFound) {
+		return helpers.JSONError(c, "AGENT_NOT_FOUND")
+	}
+	if errors.Is(err, errUsersHaveBalance) {
+		return helpers.JSONError(c, "USERS_HAVE_BALANCE")
+	}
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_UPDATE_WALLET_CONFIG")
+	}
+
+	return helpers.JSONSuccess(c, "Wallet config updated successfully", fiber.Map{
+		"agent_code":  agent.AgentCode,
+		"wallet_mode": agent.WalletMode,
+		"wallet_url":  agent.WalletURL,
+	})
+}
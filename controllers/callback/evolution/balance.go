@@ -0,0 +1,111 @@
+package evolution
+
+import (
+	"errors"
+	"log"
+	"telo/models"
+	"telo/services/evosession"
+	"telo/services/wallet"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type BalanceRequest struct {
+	SID      string       `json:"sid"`
+	UserID   string       `json:"userId"`
+	Currency string       `json:"currency"`
+	Game     *GamePayload `json:"game"`
+	UUID     string       `json:"uuid"`
+}
+
+type GamePayload struct {
+	Type    string       `json:"type"`
+	Details *GameDetails `json:"details"`
+}
+
+type GameDetails struct {
+	Table *GameTable `json:"table"`
+}
+
+type GameTable struct {
+	ID  string `json:"id"`
+	VID string `json:"vid"`
+}
+
+// BalanceHandler melayani /check dan /balance.
+func (p Product) BalanceHandler(c *fiber.Ctx) error {
+	start := time.Now()
+	db := c.Locals("db").(*gorm.DB)
+
+	log.Printf("[%s] 📩 Incoming Balance Request: %s", p.Tag, string(c.Body()))
+
+	var req BalanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("[%s] ❌ Failed to parse balance request: %v", p.Tag, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID PARAMETER",
+			"uuid":    req.UUID,
+		})
+	}
+
+	log.Printf("[%s] 🧪 Parsed Request => SID=%s | UserID=%s | Currency=%s | UUID=%s",
+		p.Tag, req.SID, req.UserID, req.Currency, req.UUID)
+
+	if req.Game != nil && req.Game.Details != nil && req.Game.Details.Table != nil {
+		log.Printf("[%s] 🎮 Game Info => Type=%s | TableID=%s | VID=%s",
+			p.Tag, req.Game.Type, req.Game.Details.Table.ID, req.Game.Details.Table.VID)
+	}
+
+	// === 1. Cek user ===
+	var user models.User
+	if err := db.Where("user_code = ?", req.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[%s] ❌ User not found: %s", p.Tag, req.UserID)
+		} else {
+			log.Printf("[%s] ❌ Database error while fetching user: %v", p.Tag, err)
+		}
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "INVALID_TOKEN_ID",
+			"message": "INVALID TOKEN ID",
+			"uuid":    req.UUID,
+		})
+	}
+	if !p.currencyOK(req.Currency, user) {
+		log.Printf("[%s] ❌ Currency mismatch: request=%s user=%s", p.Tag, req.Currency, p.Currency(user))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID CURRENCY",
+			"uuid":    req.UUID,
+		})
+	}
+
+	// === 2. Cek session ===
+	session, err := evosession.Validate(db, req.SID, user.ID, p.Session)
+	if err != nil {
+		log.Printf("[%s] ❌ Invalid session: SID=%s for user %s (%v)", p.Tag, req.SID, req.UserID, err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "INVALID_SID",
+			"message": "INVALID SID",
+			"uuid":    req.UUID,
+		})
+	}
+	log.Printf("[%s] ✅ Session valid: SID=%s | UserID=%d | ExpiresAt=%v",
+		p.Tag, session.SID, session.UserID, session.ExpiresAt)
+
+	if err := wallet.RefreshBalance(db, &user); err != nil {
+		log.Printf("[%s] ⚠️ Failed to refresh seamless balance user=%s: %v", p.Tag, user.UserCode, err)
+	}
+
+	// === 3. Kirim response sukses ===
+	resp := fiber.Map{
+		"status":  "OK",
+		"balance": user.Balance.Round(2),
+		"uuid":    req.UUID,
+	}
+	log.Printf("[%s] 📤 Balance response for user %s: %+v | Duration=%v", p.Tag, req.UserID, resp, time.Since(start))
+
+	return c.JSON(resp)
+}
@@ -0,0 +1,153 @@
+package evolution
+
+import (
+	"log"
+	"telo/models"
+	"telo/services/evosession"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CancelRequest struct {
+	SID         string      `json:"sid"`
+	UserID      string      `json:"userId"`
+	Currency    string      `json:"currency"`
+	Game        GameWithID  `json:"game"`
+	Transaction Transaction `json:"transaction"`
+	UUID        string      `json:"uuid"`
+}
+
+func (p Product) CancelHandler(c *fiber.Ctx) error {
+	db := c.Locals("db").(*gorm.DB)
+
+	var req CancelRequest
+	log.Printf("[%s] 📥 Cancel raw body: %s", p.Tag, string(c.Body()))
+
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("[%s] username=%s ❌ Failed to parse cancel request: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID PARAMETER",
+			"uuid":    req.UUID,
+		})
+	}
+
+	log.Printf("[%s] 🔍 Checking user: %s", p.Tag, req.UserID)
+
+	var user models.User
+	if err := db.Where("user_code = ?", req.UserID).First(&user).Error; err != nil {
+		log.Printf("[%s] username=%s ❌ Cancel: User not found", p.Tag, req.UserID)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "INVALID_TOKEN_ID",
+			"message": "INVALID TOKEN ID",
+			"uuid":    req.UUID,
+		})
+	}
+
+	// Cancel tetap diproses walau SID sudah expired/di-revoke; hanya dicatat.
+	if req.SID != "" {
+		if _, err := evosession.Validate(db, req.SID, user.ID, p.Session); err != nil {
+			log.Printf("[%s] username=%s ⚠️ Cancel: SID %s invalid (%v)", p.Tag, req.UserID, req.SID, err)
+		}
+	}
+
+	log.Printf("[%s] 🔍 Checking ref transaction: %s", p.Tag, req.Transaction.RefID)
+
+	var tx models.EvolutionTransaction
+	if err := db.Where("ref_id = ? AND type = ?", req.Transaction.RefID, "DEBIT").First(&tx).Error; err != nil {
+		log.Printf("[%s] username=%s ❌ Cancel: RefID not found: %s", p.Tag, req.UserID, req.Transaction.RefID)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "BET_DOES_NOT_EXIST",
+			"message": "Referenced bet not found",
+			"uuid":    req.UUID,
+		})
+	}
+
+	key := idempotency.Key{Provider: p.Provider, Operation: "CANCEL", ExternalID: req.Transaction.ID}
+	resp, err := idempotency.Do(db, key, func(txn *gorm.DB) (int, any, error) {
+		// Serialisasi dengan credit dan auto-refund untuk bet yang sama
+		if err := wallet.LockBet(txn, p.Provider, req.Transaction.RefID); err != nil {
+			return 0, nil, err
+		}
+		// Lock bet asal supaya cancel bersamaan tidak mengembalikan saldo dua kali
+		if err := txn.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tx, tx.ID).Error; err != nil {
+			return 0, nil, err
+		}
+		if tx.Status == "CANCEL" {
+			log.Printf("[%s] username=%s ⚠️ Bet already cancelled for RefID=%s", p.Tag, req.UserID, req.Transaction.RefID)
+			return fiber.StatusOK, fiber.Map{
+				"status":  "BET_ALREADY_SETTLED",
+				"balance": user.Balance.Round(2),
+				"uuid":    req.UUID,
+			}, nil
+		}
+
+		res, err := wallet.Cancel(txn, wallet.Request{
+			UserCode:   user.UserCode,
+			Provider:   p.Provider,
+			ExternalID: req.Transaction.RefID,
+			Amount:     req.Transaction.Amount,
+			RefID:      req.Transaction.RefID,
+			Note:       "Evolution cancel",
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		user.Balance = res.BalanceAfter
+
+		tx.Status = "CANCEL"
+		if err := txn.Save(&tx).Error; err != nil {
+			return 0, nil, err
+		}
+
+		if err := gametx.Record(txn, gametx.Entry{
+			User:         user,
+			Provider:     p.Provider,
+			ProviderTx:   req.Transaction.RefID,
+			RoundID:      req.Game.ID,
+			GameID:       req.Game.Type,
+			Refund:       req.Transaction.Amount,
+			Status:       gametx.StatusRefund,
+			BalanceAfter: user.Balance,
+			RefID:        req.Transaction.ID,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		if err := gameround.Apply(txn, gameround.Event{
+			User:     user,
+			Provider: p.Provider,
+			RoundID:  req.Transaction.RefID,
+			GameID:   req.Game.Type,
+			Refund:   req.Transaction.Amount,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		log.Printf("[%s] username=%s ✅ Cancel success. RefID=%s Amount=%s NewBalance=%s",
+			p.Tag, req.UserID, req.Transaction.RefID, req.Transaction.Amount, user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":  "OK",
+			"balance": user.Balance.Round(2),
+			"uuid":    req.UUID,
+		}, nil
+	})
+
+	if err != nil {
+		log.Printf("[%s] username=%s ❌ DB transaction error on cancel: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "TEMPORARY_ERROR",
+			"message": "There is a temporary problem with the game server.",
+			"uuid":    req.UUID,
+		})
+	}
+
+	return resp.Send(c)
+}
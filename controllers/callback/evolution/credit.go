@@ -0,0 +1,170 @@
+package evolution
+
+import (
+	"log"
+	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CreditRequest struct {
+	SID         string      `json:"sid"`
+	UserID      string      `json:"userId"`
+	Currency    string      `json:"currency"`
+	Game        GameWithID  `json:"game"`
+	Transaction Transaction `json:"transaction"`
+	UUID        string      `json:"uuid"`
+}
+
+func (p Product) CreditHandler(c *fiber.Ctx) error {
+	db := c.Locals("db").(*gorm.DB)
+
+	var req CreditRequest
+	log.Printf("[%s] 📥 Credit raw body: %s", p.Tag, string(c.Body()))
+
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("[%s] username=%s ❌ Failed to parse credit request: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID PARAMETER",
+			"uuid":    req.UUID,
+		})
+	}
+
+	log.Printf("[%s] 🔍 Checking user: %s", p.Tag, req.UserID)
+
+	var user models.User
+	if err := db.Where("user_code = ?", req.UserID).First(&user).Error; err != nil {
+		log.Printf("[%s] username=%s ❌ Credit: User not found", p.Tag, req.UserID)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "INVALID_TOKEN_ID",
+			"message": "INVALID TOKEN ID",
+			"uuid":    req.UUID,
+		})
+	}
+
+	var refTx models.EvolutionTransaction
+	if err := db.Where("ref_id = ? AND type = ?", req.Transaction.RefID, "DEBIT").First(&refTx).Error; err != nil {
+		log.Printf("[%s] username=%s ❌ Credit: RefID not found: %s", p.Tag, req.UserID, req.Transaction.RefID)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "BET_DOES_NOT_EXIST",
+			"message": "Referenced bet not found",
+			"uuid":    req.UUID,
+		})
+	}
+
+	key := idempotency.Key{Provider: p.Provider, Operation: "CREDIT", ExternalID: req.Transaction.ID}
+	resp, err := idempotency.Do(db, key, func(tx *gorm.DB) (int, any, error) {
+		// Credit yang sudah tercatat sebelum tabel idempotency ada
+		var existingTx models.EvolutionTransaction
+		if err := tx.Where("tx_id = ?", req.Transaction.ID).First(&existingTx).Error; err == nil {
+			log.Printf("[%s] username=%s ⚠️ Credit duplicate transaction id=%s", p.Tag, req.UserID, req.Transaction.ID)
+			return fiber.StatusOK, fiber.Map{
+				"status":  "BET_ALREADY_EXIST",
+				"balance": user.Balance.Round(2),
+				"uuid":    req.UUID,
+			}, nil
+		}
+
+		// Bet yang sudah di-cancel (oleh Evolution atau auto-refund stale
+		// round) tidak boleh dibayar lagi di atas stake yang sudah kembali.
+		if err := wallet.LockBet(tx, p.Provider, req.Transaction.RefID); err != nil {
+			return 0, nil, err
+		}
+		refunded, err := wallet.Refunded(tx, user.ID, p.Provider, req.Transaction.RefID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if refunded {
+			log.Printf("[%s] username=%s ❌ Credit rejected, bet already cancelled RefID=%s", p.Tag, req.UserID, req.Transaction.RefID)
+			return fiber.StatusOK, fiber.Map{
+				"status":  "BET_ALREADY_SETTLED",
+				"balance": user.Balance.Round(2),
+				"uuid":    req.UUID,
+			}, nil
+		}
+
+		res, err := wallet.Credit(tx, wallet.Request{
+			UserCode:   user.UserCode,
+			Provider:   p.Provider,
+			ExternalID: req.Transaction.ID,
+			Amount:     req.Transaction.Amount,
+			RefID:      req.Transaction.RefID,
+			Note:       "Evolution credit",
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		user.Balance = res.BalanceAfter
+
+		evoTx := models.EvolutionTransaction{
+			UserID:   user.ID,
+			SID:      req.SID,
+			TxID:     req.Transaction.ID,
+			RefID:    req.Transaction.RefID,
+			Amount:   req.Transaction.Amount,
+			Currency: req.Currency,
+			Type:     "CREDIT",
+			GameID:   req.Game.ID,
+			GameType: req.Game.Type,
+			TableID:  req.Game.table().ID,
+			TableVID: req.Game.table().VID,
+			UUID:     req.UUID,
+			Status:   "SUCCESS",
+			Provider: p.Label,
+		}
+		if err := tx.Create(&evoTx).Error; err != nil {
+			return 0, nil, err
+		}
+
+		if err := gametx.Record(tx, gametx.Entry{
+			User:         user,
+			Provider:     p.Provider,
+			ProviderTx:   req.Transaction.RefID,
+			RoundID:      req.Game.ID,
+			GameID:       req.Game.Type,
+			Win:          req.Transaction.Amount,
+			Status:       gametx.StatusSettled,
+			BalanceAfter: user.Balance,
+			RefID:        req.Transaction.ID,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: p.Provider,
+			RoundID:  req.Transaction.RefID,
+			GameID:   req.Game.Type,
+			Win:      req.Transaction.Amount,
+			Close:    true,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		log.Printf("[%s] username=%s ✅ Credit success. Bet ID=%s RefID=%s Amount=%s NewBalance=%s",
+			p.Tag, req.UserID, req.Transaction.ID, req.Transaction.RefID, req.Transaction.Amount, user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":  "OK",
+			"balance": user.Balance.Round(2),
+			"uuid":    req.UUID,
+		}, nil
+	})
+
+	if err != nil {
+		log.Printf("[%s] username=%s ❌ DB transaction error on credit: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "TEMPORARY_ERROR",
+			"message": "There is a temporary problem with the game server.",
+			"uuid":    req.UUID,
+		})
+	}
+
+	return resp.Send(c)
+}
@@ -0,0 +1,198 @@
+package evolution
+
+import (
+	"errors"
+	"log"
+	"telo/models"
+	"telo/services/evosession"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type DebitRequest struct {
+	SID         string      `json:"sid"`
+	UserID      string      `json:"userId"`
+	Currency    string      `json:"currency"`
+	Game        GameWithID  `json:"game"`
+	Transaction Transaction `json:"transaction"`
+	UUID        string      `json:"uuid"`
+}
+
+type GameWithID struct {
+	ID      string       `json:"id"`
+	Type    string       `json:"type"`
+	Details *GameDetails `json:"details"`
+}
+
+// table: details.table tidak selalu dikirim (mis. game slot).
+func (g GameWithID) table() GameTable {
+	if g.Details == nil || g.Details.Table == nil {
+		return GameTable{}
+	}
+	return *g.Details.Table
+}
+
+type Transaction struct {
+	ID     string          `json:"id"`
+	RefID  string          `json:"refId"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func (p Product) DebitHandler(c *fiber.Ctx) error {
+	db := c.Locals("db").(*gorm.DB)
+
+	var req DebitRequest
+	log.Printf("[%s] 📥 Debit raw body: %s", p.Tag, string(c.Body()))
+
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("[%s] username=%s ❌ Failed to parse debit request: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID PARAMETER",
+			"uuid":    req.UUID,
+		})
+	}
+
+	log.Printf("[%s] 🔍 Checking user: %s", p.Tag, req.UserID)
+
+	var user models.User
+	if err := db.Where("user_code = ?", req.UserID).First(&user).Error; err != nil {
+		log.Printf("[%s] username=%s ❌ User not found", p.Tag, req.UserID)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "INVALID_TOKEN_ID",
+			"message": "INVALID TOKEN ID",
+			"uuid":    req.UUID,
+		})
+	}
+
+	if !p.currencyOK(req.Currency, user) {
+		log.Printf("[%s] username=%s ❌ Currency mismatch: request=%s user=%s", p.Tag, req.UserID, req.Currency, p.Currency(user))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID CURRENCY",
+			"uuid":    req.UUID,
+		})
+	}
+
+	if _, err := evosession.Validate(db, req.SID, user.ID, p.Session); err != nil {
+		log.Printf("[%s] username=%s ❌ Invalid or expired SID: %s (%v)", p.Tag, req.UserID, req.SID, err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "INVALID_SID",
+			"message": "Invalid or expired SID",
+			"uuid":    req.UUID,
+		})
+	}
+
+	key := idempotency.Key{Provider: p.Provider, Operation: "DEBIT", ExternalID: req.Transaction.ID}
+	resp, err := idempotency.Do(db, key, func(tx *gorm.DB) (int, any, error) {
+		// Bet yang sudah tercatat sebelum tabel idempotency ada
+		var existingTx models.EvolutionTransaction
+		if err := tx.Where("tx_id = ?", req.Transaction.ID).First(&existingTx).Error; err == nil {
+			log.Printf("[%s] username=%s ⚠️ Debit duplicate bet id=%s", p.Tag, req.UserID, req.Transaction.ID)
+			return fiber.StatusOK, fiber.Map{
+				"status":  "BET_ALREADY_EXIST",
+				"balance": user.Balance.Round(2),
+				"uuid":    req.UUID,
+			}, nil
+		}
+
+		res, err := wallet.Debit(tx, wallet.Request{
+			UserCode:   user.UserCode,
+			Provider:   p.Provider,
+			ExternalID: req.Transaction.ID,
+			Amount:     req.Transaction.Amount,
+			RefID:      req.Transaction.RefID,
+			Note:       "Evolution debit",
+		})
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			log.Printf("[%s] username=%s ❌ Insufficient balance", p.Tag, req.UserID)
+			return fiber.StatusBadRequest, fiber.Map{
+				"status":  "INSUFFICIENT_FUNDS",
+				"message": "Insufficient balance",
+				"uuid":    req.UUID,
+			}, nil
+		}
+		if errors.Is(err, wallet.ErrUserInactive) {
+			log.Printf("[%s] username=%s ❌ Account suspended", p.Tag, req.UserID)
+			return fiber.StatusOK, fiber.Map{
+				"status":  "ACCOUNT_LOCKED",
+				"message": "Account locked",
+				"uuid":    req.UUID,
+			}, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		user.Balance = res.BalanceAfter
+
+		evoTx := models.EvolutionTransaction{
+			UserID:   user.ID,
+			SID:      req.SID,
+			TxID:     req.Transaction.ID,
+			RefID:    req.Transaction.RefID,
+			Amount:   req.Transaction.Amount,
+			Currency: req.Currency,
+			Type:     "DEBIT",
+			GameID:   req.Game.ID,
+			GameType: req.Game.Type,
+			TableID:  req.Game.table().ID,
+			TableVID: req.Game.table().VID,
+			UUID:     req.UUID,
+			Status:   "SUCCESS",
+			Provider: p.Label,
+		}
+		if err := tx.Create(&evoTx).Error; err != nil {
+			return 0, nil, err
+		}
+
+		if err := gametx.Record(tx, gametx.Entry{
+			User:         user,
+			Provider:     p.Provider,
+			ProviderTx:   req.Transaction.RefID,
+			RoundID:      req.Game.ID,
+			GameID:       req.Game.Type,
+			Bet:          req.Transaction.Amount,
+			Status:       gametx.StatusRunning,
+			BalanceAfter: user.Balance,
+			RefID:        req.Transaction.ID,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: p.Provider,
+			RoundID:  req.Transaction.RefID,
+			GameID:   req.Game.Type,
+			Bet:      req.Transaction.Amount,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		log.Printf("[%s] username=%s ✅ Debit success. Bet ID=%s Amount=%s NewBalance=%s",
+			p.Tag, req.UserID, req.Transaction.ID, req.Transaction.Amount, user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":  "OK",
+			"balance": user.Balance.Round(2),
+			"uuid":    req.UUID,
+		}, nil
+	})
+
+	if err != nil {
+		log.Printf("[%s] username=%s ❌ DB transaction error: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "TEMPORARY_ERROR",
+			"message": "There is a temporary problem with the game server.",
+			"uuid":    req.UUID,
+		})
+	}
+
+	return resp.Send(c)
+}
@@ -0,0 +1,71 @@
+// Package evolution adalah implementasi One Wallet Evolution yang dipakai
+// bersama oleh produk slot dan live casino. Perbedaan antar produk (token
+// auth, label provider, scope session, aturan currency) ada di Product.
+package evolution
+
+import (
+	"strings"
+
+	"telo/models"
+	"telo/services/evosession"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Product struct {
+	// Tag dipakai sebagai prefix log, misalnya [EVOLUTIONSLOT].
+	Tag string
+	// Label disimpan di EvolutionTransaction.Provider.
+	Label string
+	// Provider dipakai di wallet, gametx, gameround, idempotency dan LockBet.
+	// Slot dan live punya label sendiri supaya GGR di-settle dengan rate
+	// kategori masing-masing dan id transaksinya tidak saling dedupe.
+	Provider string
+	// AuthTokenEnv: env berisi authToken yang dikirim Evolution di query.
+	AuthTokenEnv string
+	// Session: scope SID di evosession, SID slot tidak berlaku di live.
+	Session string
+	// Currency mengembalikan kode currency user menurut Evolution. Request
+	// dengan currency lain ditolak.
+	Currency func(user models.User) string
+}
+
+var Slot = Product{
+	Tag:          "EVOLUTIONSLOT",
+	Label:        "Evolution Slot",
+	Provider:     "EVOLUTION_SLOT",
+	AuthTokenEnv: "EVOLUTION_AUTH_TOKEN_SLOT",
+	Session:      evosession.ProviderSlot,
+	Currency:     userCurrency,
+}
+
+var Live = Product{
+	Tag:          "EVOLUTIONLIVE",
+	Label:        "Evolution Live",
+	Provider:     "EVOLUTION_LIVE",
+	AuthTokenEnv: "EVOLUTION_AUTH_TOKEN_LIVE",
+	Session:      evosession.ProviderLive,
+	Currency:     userCurrency,
+}
+
+func userCurrency(user models.User) string {
+	return strings.ToUpper(user.Currency)
+}
+
+// Mount mendaftarkan endpoint wallet Evolution untuk satu produk. Router
+// harus sudah dilindungi middlewares.CheckEvolutionToken(p.AuthTokenEnv).
+func Mount(r fiber.Router, p Product) {
+	r.Post("/check", p.BalanceHandler)
+	r.Post("/balance", p.BalanceHandler)
+	r.Post("/debit", p.DebitHandler)
+	r.Post("/credit", p.CreditHandler)
+	r.Post("/cancel", p.CancelHandler)
+	r.Post("/sid", p.UserHandler)
+	r.Post("/promo_payout", p.PromoPayoutHandler)
+}
+
+// currencyOK: currency kosong diterima (tidak semua request Evolution
+// mengirimnya).
+func (p Product) currencyOK(currency string, user models.User) bool {
+	return currency == "" || strings.EqualFold(currency, p.Currency(user))
+}
@@ -0,0 +1,156 @@
+package evolution
+
+import (
+	"log"
+	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type PromoPayoutRequest struct {
+	SID              string           `json:"sid"`
+	UserID           string           `json:"userId"`
+	Currency         string           `json:"currency"`
+	Game             *GameWithID      `json:"game"`
+	PromoTransaction PromoTransaction `json:"promoTransaction"`
+	UUID             string           `json:"uuid"`
+}
+
+type PromoTransaction struct {
+	// Type: FreeRoundPlayableSpent, JackpotWin, RewardGamePlayableSpent, dll.
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	Amount    decimal.Decimal `json:"amount"`
+	VoucherID string          `json:"voucherId,omitempty"`
+}
+
+// PromoPayoutHandler mengkreditkan payout promo Evolution (free round,
+// jackpot, reward game). Payout tidak terikat bet, jadi dicatat sebagai
+// round tersendiri dan tetap diterima walau SID sudah tidak aktif.
+func (p Product) PromoPayoutHandler(c *fiber.Ctx) error {
+	db := c.Locals("db").(*gorm.DB)
+
+	var req PromoPayoutRequest
+	log.Printf("[%s] 📥 Promo payout raw body: %s", p.Tag, string(c.Body()))
+
+	if err := c.BodyParser(&req); err != nil || req.PromoTransaction.ID == "" || req.PromoTransaction.Amount.IsNegative() {
+		log.Printf("[%s] username=%s ❌ Invalid promo payout request: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "INVALID_PARAMETER",
+			"message": "INVALID PARAMETER",
+			"uuid":    req.UUID,
+		})
+	}
+
+	var user models.User
+	if err := db.Where("user_code = ?", req.UserID).First(&user).Error; err != nil {
+		log.Printf("[%s] username=%s ❌ Promo payout: User not found", p.Tag, req.UserID)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "INVALID_TOKEN_ID",
+			"message": "INVALID TOKEN ID",
+			"uuid":    req.UUID,
+		})
+	}
+
+	promo := req.PromoTransaction
+	ref := "PROMO-" + promo.ID
+	var game GameWithID
+	if req.Game != nil {
+		game = *req.Game
+	}
+
+	key := idempotency.Key{Provider: p.Provider, Operation: "PROMO_PAYOUT", ExternalID: promo.ID}
+	resp, err := idempotency.Do(db, key, func(tx *gorm.DB) (int, any, error) {
+		res, err := wallet.Credit(tx, wallet.Request{
+			UserCode:   user.UserCode,
+			Provider:   p.Provider,
+			ExternalID: ref,
+			Amount:     promo.Amount,
+			RefID:      ref,
+			Note:       "Evolution promo payout " + promo.Type,
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		user.Balance = res.BalanceAfter
+		if res.Duplicate {
+			return fiber.StatusOK, fiber.Map{
+				"status":  "OK",
+				"balance": user.Balance.Round(2),
+				"uuid":    req.UUID,
+			}, nil
+		}
+
+		evoTx := models.EvolutionTransaction{
+			UserID:   user.ID,
+			SID:      req.SID,
+			TxID:     ref,
+			RefID:    ref,
+			Amount:   promo.Amount,
+			Currency: req.Currency,
+			Type:     "PROMO_PAYOUT",
+			GameID:   game.ID,
+			GameType: game.Type,
+			TableID:  game.table().ID,
+			TableVID: game.table().VID,
+			UUID:     req.UUID,
+			Status:   "SUCCESS",
+			Provider: p.Label,
+		}
+		if err := tx.Create(&evoTx).Error; err != nil {
+			return 0, nil, err
+		}
+
+		if err := gametx.Record(tx, gametx.Entry{
+			User:         user,
+			Provider:     p.Provider,
+			ProviderTx:   ref,
+			RoundID:      game.ID,
+			GameID:       game.Type,
+			Bonus:        promo.Amount,
+			Status:       gametx.StatusSettled,
+			BalanceAfter: user.Balance,
+			Note:         promo.Type,
+			RefID:        promo.ID,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: p.Provider,
+			RoundID:  ref,
+			GameID:   game.Type,
+			Promo:    promo.Amount,
+			Close:    true,
+		}); err != nil {
+			return 0, nil, err
+		}
+
+		log.Printf("[%s] username=%s ✅ Promo payout success. Type=%s ID=%s Amount=%s NewBalance=%s",
+			p.Tag, req.UserID, promo.Type, promo.ID, promo.Amount, user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":  "OK",
+			"balance": user.Balance.Round(2),
+			"uuid":    req.UUID,
+		}, nil
+	})
+
+	if err != nil {
+		log.Printf("[%s] username=%s ❌ DB transaction error on promo payout: %v", p.Tag, req.UserID, err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "TEMPORARY_ERROR",
+			"message": "There is a temporary problem with the game server.",
+			"uuid":    req.UUID,
+		})
+	}
+
+	return resp.Send(c)
+}
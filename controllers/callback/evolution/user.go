@@ -0,0 +1,104 @@
+package evolution
+
+import (
+	"errors"
+	"log"
+	"telo/models"
+	"telo/services/evosession"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type Channel struct {
+	Type string `json:"type"`
+}
+
+type CheckUserRequest struct {
+	UserID  string  `json:"userId"`
+	SID     string  `json:"sid"`
+	Channel Channel `json:"channel"`
+	UUID    string  `json:"uuid"`
+}
+
+func (p Product) UserHandler(c *fiber.Ctx) error {
+	db := c.Locals("db").(*gorm.DB)
+
+	log.Printf("[%s] 📥 Incoming request to UserHandler (SID refresh)", p.Tag)
+
+	// === 1. Log body mentah yang diterima ===
+	rawBody := c.Body()
+	log.Printf("[%s] 📩 Raw Request Body: %s", p.Tag, string(rawBody))
+
+	// === 2. Parse request JSON ===
+	var req CheckUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("[%s] ❌ Failed to parse request: %v", p.Tag, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status": "INVALID_PARAMETER",
+			"uuid":   req.UUID,
+		})
+	}
+
+	// === 3. Log hasil parsing ===
+	log.Printf("[%s] 🧪 Parsed Request => UserID=%s | SID=%s | ChannelType=%s | UUID=%s",
+		p.Tag, req.UserID, req.SID, req.Channel.Type, req.UUID)
+
+	// === 4. Cek apakah user ada ===
+	var user models.User
+	if err := db.Where("user_code = ?", req.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[%s] ❌ User not found: %s", p.Tag, req.UserID)
+		} else {
+			log.Printf("[%s] ❌ Database error while fetching user: %v", p.Tag, err)
+		}
+
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status": "INVALID_TOKEN_ID",
+			"uuid":   req.UUID,
+		})
+	}
+
+	log.Printf("[%s] ✅ User found: ID=%d | Code=%s | Balance=%s",
+		p.Tag, user.ID, user.UserCode, user.Balance)
+
+	if err := wallet.CheckActive(db, user); err != nil {
+		log.Printf("[%s] ❌ Account suspended: %s (%v)", p.Tag, req.UserID, err)
+		return c.JSON(fiber.Map{
+			"status": "ACCOUNT_LOCKED",
+			"uuid":   req.UUID,
+		})
+	}
+
+	// === 5. Refresh SID: session lama harus masih aktif, lalu diganti SID baru ===
+	session, err := evosession.Refresh(db, req.SID, user.ID, p.Session)
+	if err != nil {
+		if errors.Is(err, evosession.ErrNotFound) || errors.Is(err, evosession.ErrExpired) || errors.Is(err, evosession.ErrRevoked) {
+			log.Printf("[%s] ❌ Cannot refresh SID=%s | User=%s | %v", p.Tag, req.SID, req.UserID, err)
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"status": "INVALID_SID",
+				"uuid":   req.UUID,
+			})
+		}
+
+		log.Printf("[%s] ❌ Database error while refreshing session: %v", p.Tag, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status": "INTERNAL_ERROR",
+			"uuid":   req.UUID,
+		})
+	}
+
+	log.Printf("[%s] ✅ Session refreshed: OldSID=%s | NewSID=%s | UserID=%d | ExpiresAt=%v",
+		p.Tag, session.RefreshedFrom, session.SID, session.UserID, session.ExpiresAt)
+
+	// === 6. Kirim response akhir ===
+	resp := fiber.Map{
+		"status": "OK",
+		"sid":    session.SID,
+		"uuid":   req.UUID,
+	}
+
+	log.Printf("[%s] 📤 Response for UserID=%s: %+v", p.Tag, req.UserID, resp)
+	return c.JSON(resp)
+}
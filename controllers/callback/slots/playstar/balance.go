@@ -1,14 +1,15 @@
 package playstar
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 
 	"telo/database" // pastikan package ini ada dan expose var DB *gorm.DB
 	"telo/models"
+	"telo/services/wallet"
 )
 
 type GetBalanceResponse struct {
@@ -17,29 +18,28 @@ type GetBalanceResponse struct {
 }
 
 func GetBalanceHandler(c *fiber.Ctx) error {
-	accessToken := c.Query("access_token")
-	if strings.TrimSpace(accessToken) == "" {
-		// invalid token dianggap sama dengan invalid member ID
-		return c.Status(http.StatusOK).JSON(GetBalanceResponse{StatusCode: 1})
-	}
-
-	memberID := c.Query("member_id")
-	if strings.TrimSpace(memberID) == "" {
-		return c.Status(http.StatusOK).JSON(GetBalanceResponse{StatusCode: 1})
-	}
+	// access_token dan member_id sudah divalidasi PlaystarAuth
+	user := c.Locals("user").(models.User)
 
-	// cari user
-	var user models.User
-	if err := database.DB.Where("user_code = ?", memberID).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(http.StatusOK).JSON(GetBalanceResponse{StatusCode: 1})
-		}
-		return c.Status(http.StatusOK).JSON(GetBalanceResponse{StatusCode: 5})
+	// return balance dalam cents
+	if err := wallet.RefreshBalance(database.DB, &user); err != nil {
+		log.Printf("[PLAYSTAR] ⚠️ Failed to refresh seamless balance user=%s: %v", user.UserCode, err)
 	}
 
-	// return balance dalam cents
 	return c.Status(http.StatusOK).JSON(GetBalanceResponse{
 		StatusCode: 0,
-		Balance:    uint64(user.Balance),
+		Balance:    uint64(user.Balance.IntPart()),
 	})
 }
+
+// walletStatusCode memetakan error wallet service ke status_code Playstar.
+func walletStatusCode(err error) int {
+	switch {
+	case errors.Is(err, wallet.ErrUserNotFound):
+		return 1
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return 3
+	default:
+		return 5
+	}
+}
@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 
 	"telo/database" // pastikan ada package ini yg expose `var DB *gorm.DB`
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/wallet"
 )
 
 type BetResponse struct {
@@ -55,89 +57,98 @@ func BetHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 5})
 	}
 
-	// --- cari user & lock row ---
-	var user models.User
-	if err := database.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", memberID).
-		First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 1})
+	// access_token dan member_id sudah divalidasi PlaystarAuth
+	user := c.Locals("user").(models.User)
+
+	var balanceAfter decimal.Decimal
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// debit saldo user (lock + idempotency di wallet service)
+		res, err := wallet.Debit(tx, wallet.Request{
+			UserCode:   user.UserCode,
+			Provider:   "PLAYSTAR",
+			ExternalID: txnIDStr,
+			Amount:     decimal.NewFromUint64(totalBet),
+			RefID:      fmt.Sprintf("PSBET-%d", txnID),
+			Note:       fmt.Sprintf("Playstar Bet %s", gameID),
+		})
+		if err != nil {
+			return err
+		}
+		user = res.User
+		balanceAfter = res.BalanceAfter
+		if res.Duplicate {
+			return nil
+		}
+		balanceBefore := res.BalanceBefore
+
+		// simpan transaksi provider (opsional)
+		playTxn := models.PlaystarTransaction{
+			AccessToken: accessToken,
+			TxnID:       txnID,
+			GameID:      gameID,
+			SubGameID:   subgameID,
+			TS:          ts,
+			BetAmt:      totalBet,
+			MemberID:    memberID,
+		}
+		if err := tx.Create(&playTxn).Error; err != nil {
+			return err
 		}
-		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 5})
-	}
-
-	// cek saldo cukup
-	if user.Balance < float64(totalBet) {
-		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 3})
-	}
-
-	// hitung saldo baru
-	balanceBefore := user.Balance
-	balanceAfter := balanceBefore - float64(totalBet)
-
-	// update saldo user
-	user.Balance = balanceAfter
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 5})
-	}
-
-	// simpan transaksi provider (opsional)
-	playTxn := models.PlaystarTransaction{
-		AccessToken: accessToken,
-		TxnID:       txnID,
-		GameID:      gameID,
-		SubGameID:   subgameID,
-		TS:          ts,
-		BetAmt:      totalBet,
-		MemberID:    memberID,
-	}
-	if err := database.DB.Create(&playTxn).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 5})
-	}
 
-	// catat transaksi umum (financial log)
-	userTrx := models.UserTransaction{
-		UserID:        user.ID,
-		AgentCode:     user.AgentCode,
-		UserCode:      user.UserCode,
-		TrxType:       "BET",
-		Amount:        int64(totalBet),
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  balanceAfter,
-		Currency:      user.Currency,
-		Note:          fmt.Sprintf("Playstar Bet %s", gameID),
-		RefID:         fmt.Sprintf("PSBET-%d", txnID),
-	}
-	if err := database.DB.Create(&userTrx).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 5})
-	}
+		// catat transaksi umum (financial log)
+		userTrx := models.UserTransaction{
+			UserID:        user.ID,
+			AgentCode:     user.AgentCode,
+			UserCode:      user.UserCode,
+			TrxType:       "BET",
+			Amount:        decimal.NewFromUint64(totalBet),
+			BalanceBefore: balanceBefore,
+			BalanceAfter:  balanceAfter,
+			Currency:      user.Currency,
+			Note:          fmt.Sprintf("Playstar Bet %s", gameID),
+			RefID:         fmt.Sprintf("PSBET-%d", txnID),
+		}
+		if err := tx.Create(&userTrx).Error; err != nil {
+			return err
+		}
 
-	// catat transaksi game detail
-	gameTrx := models.UserGameTransaction{
-		UserID:        user.ID,
-		UserCode:      user.UserCode,
-		AgentCode:     user.AgentCode,
-		Provider:      "Playstar",
-		GameID:        gameID,
-		SubGameID:     subgameID,
-		ProviderTx:    fmt.Sprintf("%d", txnID),
-		BetAmount:     int64(totalBet),
-		WinAmount:     0,
-		BonusAmount:   0,
-		JPContrib:     0,
-		Currency:      user.Currency,
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  balanceAfter,
-		Status:        "BET",
-		Note:          "Bet request received",
-		RefID:         fmt.Sprintf("PSBET-%d", txnID),
-	}
-	if err := database.DB.Create(&gameTrx).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 5})
+		// catat transaksi game detail
+		gameTrx := models.UserGameTransaction{
+			UserID:        user.ID,
+			UserCode:      user.UserCode,
+			AgentCode:     user.AgentCode,
+			Provider:      "PLAYSTAR",
+			GameID:        gameID,
+			SubGameID:     subgameID,
+			ProviderTx:    fmt.Sprintf("%d", txnID),
+			BetAmount:     decimal.NewFromUint64(totalBet),
+			WinAmount:     decimal.Zero,
+			BonusAmount:   decimal.Zero,
+			JPContrib:     decimal.Zero,
+			Currency:      user.Currency,
+			BalanceBefore: balanceBefore,
+			BalanceAfter:  balanceAfter,
+			Status:        "BET",
+			Note:          "Bet request received",
+			RefID:         fmt.Sprintf("PSBET-%d", txnID),
+		}
+		if err := tx.Create(&gameTrx).Error; err != nil {
+			return err
+		}
+		return gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PLAYSTAR",
+			RoundID:  txnIDStr,
+			GameID:   gameID,
+			Bet:      decimal.NewFromUint64(totalBet),
+		})
+	})
+	if err != nil {
+		return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: walletStatusCode(err)})
 	}
 
-	fmt.Printf("[Playstar][Bet] %s User=%s Bet=%d NewBalance=%.2f\n",
+	fmt.Printf("[Playstar][Bet] %s User=%s Bet=%d NewBalance=%s\n",
 		now.Format("2006-01-02 15:04:05"), user.UserCode, totalBet, balanceAfter)
 
-	return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 0, Balance: uint64(balanceAfter)})
+	return c.Status(http.StatusOK).JSON(BetResponse{StatusCode: 0, Balance: uint64(balanceAfter.IntPart())})
 }
@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 
 	"telo/database" // pastikan ada package ini yg expose var DB *gorm.DB
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/wallet"
 )
 
 type BonusResponse struct {
@@ -58,51 +60,68 @@ func BonusAwardHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 5})
 	}
 
-	// === Cari user & lock row ===
 	var user models.User
-	if err := database.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", memberID).
-		First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 1})
+	var balanceAfter decimal.Decimal
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// === Credit bonus ===
+		res, err := wallet.Credit(tx, wallet.Request{
+			UserCode:   memberID,
+			Provider:   "PLAYSTAR",
+			ExternalID: fmt.Sprintf("BONUS-%d", bonusID),
+			Amount:     decimal.NewFromUint64(bonusReward),
+			RefID:      fmt.Sprintf("PSBONUS-%d", bonusID),
+			Note:       fmt.Sprintf("Playstar Bonus type=%s", bonusType),
+		})
+		if err != nil {
+			return err
+		}
+		user = res.User
+		balanceBefore := res.BalanceBefore
+		balanceAfter = res.BalanceAfter
+		if res.Duplicate {
+			return nil
 		}
-		return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 5})
-	}
-
-	// === Update saldo ===
-	balanceBefore := user.Balance
-	balanceAfter := balanceBefore + float64(bonusReward)
 
-	user.Balance = balanceAfter
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 5})
-	}
+		// Bonus ikut round txn_id kalau ada; bonus lepas jadi round sendiri.
+		round := gameround.Event{
+			User:     user,
+			Provider: "PLAYSTAR",
+			RoundID:  txnIDStr,
+			GameID:   gameID,
+			Promo:    decimal.NewFromUint64(bonusReward),
+		}
+		if txnID == 0 {
+			round.RoundID = fmt.Sprintf("BONUS-%d", bonusID)
+			round.Close = true
+		}
 
-	// === Update UserGameTransaction (tambah Bonus) ===
-	var gameTrx models.UserGameTransaction
-	if err := database.DB.Where("provider = ? AND provider_tx = ?", "Playstar", fmt.Sprintf("%d", txnID)).
-		First(&gameTrx).Error; err == nil {
-		gameTrx.BonusAmount += int64(bonusReward)
-		gameTrx.BalanceBefore = balanceBefore
-		gameTrx.BalanceAfter = balanceAfter
-		gameTrx.Status = "BONUS"
-		gameTrx.Note = fmt.Sprintf("Bonus awarded type=%s id=%d", bonusType, bonusID)
-		if err := database.DB.Save(&gameTrx).Error; err != nil {
-			return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 5})
+		// === Update UserGameTransaction (tambah Bonus) ===
+		var gameTrx models.UserGameTransaction
+		if err := tx.Where("provider = ? AND provider_tx = ?", "PLAYSTAR", fmt.Sprintf("%d", txnID)).
+			First(&gameTrx).Error; err == nil {
+			gameTrx.BonusAmount = gameTrx.BonusAmount.Add(decimal.NewFromUint64(bonusReward))
+			gameTrx.BalanceBefore = balanceBefore
+			gameTrx.BalanceAfter = balanceAfter
+			gameTrx.Status = "BONUS"
+			gameTrx.Note = fmt.Sprintf("Bonus awarded type=%s id=%d", bonusType, bonusID)
+			if err := tx.Save(&gameTrx).Error; err != nil {
+				return err
+			}
+			return gameround.Apply(tx, round)
 		}
-	} else {
+
 		// fallback: create baru kalau belum ada transaksi game sebelumnya
 		gameTrx = models.UserGameTransaction{
 			UserID:        user.ID,
 			UserCode:      user.UserCode,
 			AgentCode:     user.AgentCode,
-			Provider:      "Playstar",
+			Provider:      "PLAYSTAR",
 			GameID:        gameID,
 			SubGameID:     subgameID,
 			ProviderTx:    fmt.Sprintf("%d", txnID),
-			BetAmount:     0,
-			WinAmount:     0,
-			BonusAmount:   int64(bonusReward),
+			BetAmount:     decimal.Zero,
+			WinAmount:     decimal.Zero,
+			BonusAmount:   decimal.NewFromUint64(bonusReward),
 			Currency:      user.Currency,
 			BalanceBefore: balanceBefore,
 			BalanceAfter:  balanceAfter,
@@ -110,13 +129,17 @@ func BonusAwardHandler(c *fiber.Ctx) error {
 			Note:          fmt.Sprintf("Bonus awarded type=%s id=%d", bonusType, bonusID),
 			RefID:         fmt.Sprintf("PSBONUS-%d", bonusID),
 		}
-		if err := database.DB.Create(&gameTrx).Error; err != nil {
-			return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 5})
+		if err := tx.Create(&gameTrx).Error; err != nil {
+			return err
 		}
+		return gameround.Apply(tx, round)
+	})
+	if err != nil {
+		return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: walletStatusCode(err)})
 	}
 
-	fmt.Printf("[Playstar][Bonus] %s User=%s Bonus=%d NewBalance=%.2f\n",
+	fmt.Printf("[Playstar][Bonus] %s User=%s Bonus=%d NewBalance=%s\n",
 		now.Format("2006-01-02 15:04:05"), user.UserCode, bonusReward, balanceAfter)
 
-	return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 0, Balance: uint64(balanceAfter)})
+	return c.Status(http.StatusOK).JSON(BonusResponse{StatusCode: 0, Balance: uint64(balanceAfter.IntPart())})
 }
@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 
 	"telo/database" // pastikan ada package database yg expose var DB *gorm.DB
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/wallet"
 )
 
 type RefundResponse struct {
@@ -56,49 +58,62 @@ func RefundHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 5})
 	}
 
-	// === Lock user ===
 	var user models.User
-	if err := database.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", memberID).
-		First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 1})
+	var balanceAfter decimal.Decimal
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialisasi dengan result dan auto-refund untuk txn yang sama
+		if err := wallet.LockBet(tx, "PLAYSTAR", txnIDStr); err != nil {
+			return err
 		}
-		return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 5})
-	}
-
-	// === Update saldo ===
-	balanceBefore := user.Balance
-	betAmt := float64(betTxn.BetAmt)
-	balanceAfter := balanceBefore + betAmt
 
-	user.Balance = balanceAfter
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 5})
-	}
-
-	// === Update UserGameTransaction (BET -> REFUND) ===
-	var gameTrx models.UserGameTransaction
-	if err := database.DB.Where("provider = ? AND provider_tx = ?", "Playstar", fmt.Sprintf("%d", txnID)).
-		First(&gameTrx).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// tidak ada log BET sebelumnya → system error
-			return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 5})
+		// === Kembalikan stake ===
+		res, err := wallet.Cancel(tx, wallet.Request{
+			UserCode:   memberID,
+			Provider:   "PLAYSTAR",
+			ExternalID: txnIDStr,
+			Amount:     decimal.NewFromUint64(betTxn.BetAmt),
+			RefID:      fmt.Sprintf("PSREF-%d", txnID),
+			Note:       fmt.Sprintf("Playstar Refund %s", gameID),
+		})
+		if err != nil {
+			return err
+		}
+		user = res.User
+		balanceAfter = res.BalanceAfter
+		if res.Duplicate {
+			return nil
 		}
-		return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 5})
-	}
 
-	gameTrx.BalanceBefore = balanceBefore
-	gameTrx.BalanceAfter = balanceAfter
-	gameTrx.Status = "REFUND"
-	gameTrx.Note = fmt.Sprintf("Refunded bet for game %s", gameID)
+		// === Update UserGameTransaction (BET -> REFUND) ===
+		// tidak ada log BET sebelumnya → system error
+		var gameTrx models.UserGameTransaction
+		if err := tx.Where("provider = ? AND provider_tx = ?", "PLAYSTAR", fmt.Sprintf("%d", txnID)).
+			First(&gameTrx).Error; err != nil {
+			return err
+		}
 
-	if err := database.DB.Save(&gameTrx).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 5})
+		gameTrx.BalanceBefore = res.BalanceBefore
+		gameTrx.BalanceAfter = balanceAfter
+		gameTrx.RefundAmount = decimal.NewFromUint64(betTxn.BetAmt)
+		gameTrx.Status = "REFUND"
+		gameTrx.Note = fmt.Sprintf("Refunded bet for game %s", gameID)
+		if err := tx.Save(&gameTrx).Error; err != nil {
+			return err
+		}
+		return gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PLAYSTAR",
+			RoundID:  txnIDStr,
+			GameID:   gameID,
+			Refund:   decimal.NewFromUint64(betTxn.BetAmt),
+		})
+	})
+	if err != nil {
+		return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: walletStatusCode(err)})
 	}
 
-	fmt.Printf("[Playstar][Refund] %s User=%s Refund=%d NewBalance=%.2f\n",
+	fmt.Printf("[Playstar][Refund] %s User=%s Refund=%d NewBalance=%s\n",
 		now.Format("2006-01-02 15:04:05"), user.UserCode, betTxn.BetAmt, balanceAfter)
 
-	return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 0, Balance: uint64(balanceAfter)})
+	return c.Status(http.StatusOK).JSON(RefundResponse{StatusCode: 0, Balance: uint64(balanceAfter.IntPart())})
 }
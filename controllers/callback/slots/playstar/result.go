@@ -1,20 +1,28 @@
 package playstar
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"math"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/wallet"
 )
 
+// errBetRefunded: result untuk txn yang stake-nya sudah dikembalikan. Dijawab
+// status_code 2 (transaksi tidak valid).
+var errBetRefunded = errors.New("playstar: bet already refunded")
+
 type ResultResponse struct {
 	StatusCode int    `json:"status_code"`
 	Balance    uint64 `json:"balance,omitempty"`
@@ -52,58 +60,94 @@ func ResultHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 5})
 	}
 
-	// --- lock user ---
-	var user models.User
-	if err := database.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", memberID).
-		First(&user).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 1})
-	}
+	var balanceAfter decimal.Decimal
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Bet yang sudah di-refund (oleh PlayStar atau auto-refund stale
+		// round) tidak boleh dibayar lagi di atas stake yang sudah kembali.
+		if err := wallet.LockBet(tx, "PLAYSTAR", txnIDStr); err != nil {
+			return err
+		}
+		var user models.User
+		if err := tx.Where("user_code = ?", memberID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return wallet.ErrUserNotFound
+			}
+			return err
+		}
+		refunded, err := wallet.Refunded(tx, user.ID, "PLAYSTAR", txnIDStr)
+		if err != nil {
+			return err
+		}
+		if refunded {
+			return errBetRefunded
+		}
 
-	// --- update balance ---
-	balanceBefore := user.Balance
-	balanceAfter := balanceBefore + float64(totalWin)
-	user.Balance = balanceAfter
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 5})
-	}
+		// --- credit win ---
+		res, err := wallet.Credit(tx, wallet.Request{
+			UserCode:   memberID,
+			Provider:   "PLAYSTAR",
+			ExternalID: txnIDStr,
+			Amount:     decimal.NewFromUint64(totalWin),
+			RefID:      fmt.Sprintf("PSRES-%d", txnID),
+			Note:       fmt.Sprintf("Playstar Result %s", gameID),
+		})
+		if err != nil {
+			return err
+		}
+		balanceAfter = res.BalanceAfter
+		if res.Duplicate {
+			return nil
+		}
 
-	// --- update PlaystarTransaction ---
-	betTxn.TotalWin = totalWin
-	betTxn.BonusWin = bonusWin
-	betTxn.GameID = gameID
-	betTxn.SubGameID = subgameID
-	betTxn.TS = ts
-	betTxn.JPContrib = jpContrib
-	betTxn.WinAmt = winAmt
-	betTxn.MemberID = memberID
-	if err := database.DB.Save(&betTxn).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 5})
-	}
+		// --- update PlaystarTransaction ---
+		betTxn.TotalWin = totalWin
+		betTxn.BonusWin = bonusWin
+		betTxn.GameID = gameID
+		betTxn.SubGameID = subgameID
+		betTxn.TS = ts
+		betTxn.JPContrib = jpContrib
+		betTxn.WinAmt = winAmt
+		betTxn.MemberID = memberID
+		if err := tx.Save(&betTxn).Error; err != nil {
+			return err
+		}
 
-	// --- update UserGameTransaction (dari BET -> RESULT) ---
-	var gameTrx models.UserGameTransaction
-	if err := database.DB.Where("provider = ? AND provider_tx = ?", "Playstar", fmt.Sprintf("%d", txnID)).
-		First(&gameTrx).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// kalau belum ada BET → error system
-			return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 5})
+		// --- update UserGameTransaction (dari BET -> RESULT) ---
+		// kalau belum ada BET → error system
+		var gameTrx models.UserGameTransaction
+		if err := tx.Where("provider = ? AND provider_tx = ?", "PLAYSTAR", fmt.Sprintf("%d", txnID)).
+			First(&gameTrx).Error; err != nil {
+			return err
 		}
-		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 5})
-	}
 
-	gameTrx.WinAmount = int64(totalWin)
-	gameTrx.BonusAmount = int64(bonusWin)
-	gameTrx.JPContrib = jpContrib
-	gameTrx.GameID = gameID
-	gameTrx.SubGameID = subgameID
-	gameTrx.BalanceBefore = balanceBefore
-	gameTrx.BalanceAfter = balanceAfter
-	gameTrx.Status = "RESULT"
-	gameTrx.Note = "Result credited"
-	if err := database.DB.Save(&gameTrx).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 5})
+		gameTrx.WinAmount = decimal.NewFromUint64(totalWin)
+		gameTrx.BonusAmount = decimal.NewFromUint64(bonusWin)
+		gameTrx.JPContrib = decimal.NewFromFloat(jpContrib)
+		gameTrx.GameID = gameID
+		gameTrx.SubGameID = subgameID
+		gameTrx.BalanceBefore = res.BalanceBefore
+		gameTrx.BalanceAfter = balanceAfter
+		gameTrx.Status = "RESULT"
+		gameTrx.Note = "Result credited"
+		if err := tx.Save(&gameTrx).Error; err != nil {
+			return err
+		}
+		return gameround.Apply(tx, gameround.Event{
+			User:     res.User,
+			Provider: "PLAYSTAR",
+			RoundID:  txnIDStr,
+			GameID:   gameID,
+			Win:      decimal.NewFromUint64(totalWin),
+			Close:    true,
+		})
+	})
+	if errors.Is(err, errBetRefunded) {
+		log.Printf("[PLAYSTAR] ❌ Result rejected, txn %s already refunded", txnIDStr)
+		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 2})
+	}
+	if err != nil {
+		return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: walletStatusCode(err)})
 	}
 
-	return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 0, Balance: uint64(balanceAfter)})
+	return c.Status(http.StatusOK).JSON(ResultResponse{StatusCode: 0, Balance: uint64(balanceAfter.IntPart())})
 }
@@ -2,16 +2,18 @@
 package pragmatic
 
 import (
-	"math"
-	"net/http"
+	"errors"
 	"strconv"
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/idempotency"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/shopspring/decimal"
-	"gorm.io/gorm/clause"
+	"gorm.io/gorm"
 )
 
 // POST /adjustment.html (x-www-form-urlencoded)
@@ -38,149 +40,155 @@ func Adjustment(c *fiber.Ctx) error {
 		return c.JSON(errorAdjustment("USD", 1001, "Missing required parameters"))
 	}
 
-	// TODO: validasi providerId & hash kalau perlu
-
 	// Parse amount
-	adjAmt, err := strconv.ParseFloat(amountStr, 64)
+	adjAmt, err := decimal.NewFromString(amountStr)
 	if err != nil {
 		return c.JSON(errorAdjustment("USD", 3002, "Invalid amount"))
 	}
-	adjCents := int64(math.Round(adjAmt * 100))
 
-	// Idempotent check (sudah pernah Adjusted?)
-	var existed models.UserGameTransaction
-	if err := database.DB.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Adjusted").
-		First(&existed).Error; err == nil {
-		return c.Status(http.StatusOK).JSON(fiber.Map{
-			"transactionId": existed.ID,
-			"currency":      existed.Currency,
-			"cash":          existed.BalanceAfter,
-			"bonus":         0.0,
-			"error":         0,
-			"description":   "Success (idempotent)",
-		})
-	}
-
-	// TX begin
-	tx := database.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	key := idempotency.Key{Provider: "PRAGMATIC", Operation: "ADJUSTMENT", ExternalID: reference}
+	resp, err := idempotency.Do(database.DB, key, func(tx *gorm.DB) (int, any, error) {
+		// Adjustment yang sudah diproses sebelum tabel idempotency ada
+		var existed models.UserGameTransaction
+		if err := tx.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Adjusted").
+			First(&existed).Error; err == nil {
+			return fiber.StatusOK, fiber.Map{
+				"transactionId": existed.ID,
+				"currency":      existed.Currency,
+				"cash":          existed.BalanceAfter,
+				"bonus":         0.0,
+				"error":         0,
+				"description":   "Success (idempotent)",
+			}, nil
 		}
-	}()
 
-	// Lock user
-	var user models.User
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", userId).First(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorAdjustment("USD", 2001, "User not found"))
-	}
-	if !user.IsActive {
-		tx.Rollback()
-		return c.JSON(errorAdjustment(user.Currency, 2002, "User inactive"))
-	}
-
-	before := user.Balance
-	newBalance := before + adjAmt
-	if adjAmt < 0 && before < -adjAmt {
-		tx.Rollback()
-		return c.JSON(errorAdjustment(user.Currency, 1, "Insufficient balance"))
-	}
-
-	user.Balance = newBalance
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorAdjustment(user.Currency, 5002, "Failed to update balance"))
-	}
-
-	// Update/create UserGameTransaction
-	var gameTx models.UserGameTransaction
-	if err := tx.Where("ref_id = ? AND provider = ?", reference, "PRAGMATIC").First(&gameTx).Error; err == nil {
-		// Update existing
-		gameTx.Status = "Adjusted"
-		gameTx.BalanceAfter = user.Balance
-		gameTx.Note = "Pragmatic Adjustment " + gameId + " round " + roundId
-		if err := tx.Save(&gameTx).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(errorAdjustment(user.Currency, 5003, "Failed to update UserGameTransaction"))
-		}
-	} else {
-		// Create baru
-		gameTx = models.UserGameTransaction{
-			UserID:        user.ID,
-			UserCode:      user.UserCode,
-			AgentCode:     user.AgentCode,
-			GameID:        gameId,
-			ProviderTx:    reference,
-			Provider:      "PRAGMATIC",
-			BetAmount:     0,
-			WinAmount:     0,
-			BonusAmount:   0,
-			Currency:      user.Currency,
-			BalanceBefore: before,
-			BalanceAfter:  user.Balance,
-			Status:        "Adjusted",
-			Note:          "Pragmatic Adjustment " + gameId + " round " + roundId,
-			RefID:         reference,
+		walletReq := wallet.Request{
+			UserCode:   userId,
+			Provider:   "PRAGMATIC",
+			ExternalID: reference,
+			Amount:     adjAmt.Abs(),
+			RefID:      reference,
+			Note:       "Pragmatic Adjustment " + gameId + " round " + roundId,
 		}
-		if adjAmt > 0 {
-			gameTx.WinAmount = adjCents
+		var res *wallet.Result
+		if adjAmt.IsNegative() {
+			res, err = wallet.Debit(tx, walletReq)
 		} else {
-			gameTx.BetAmount = adjCents
+			res, err = wallet.Credit(tx, walletReq)
 		}
-		if err := tx.Create(&gameTx).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(errorAdjustment(user.Currency, 5004, "Failed to create UserGameTransaction"))
+		if err != nil {
+			code, desc := walletErrorCode(err)
+			if errors.Is(err, wallet.ErrInsufficientFunds) {
+				code, desc = 1, "Insufficient balance"
+			}
+			currency := res.User.Currency
+			if currency == "" {
+				currency = "USD"
+			}
+			if code == 5002 {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorAdjustment(currency, code, desc)}
+			}
+			return fiber.StatusOK, errorAdjustment(currency, code, desc), nil
 		}
-	}
-
-	// Update/insert PragmaticTransaction
-	var prTx models.PragmaticTransaction
-	if err := tx.Where("reference = ?", reference).First(&prTx).Error; err == nil {
-		prTx.Cash = decimal.NewFromFloat(user.Balance)
-		prTx.Amount = decimal.NewFromFloat(adjAmt)
-		prTx.TotalBalance = decimal.NewFromFloat(user.Balance)
-		prTx.ErrorCode = intPtr(0)
-		prTx.Description = strPtr("Adjustment Success")
-		if err := tx.Save(&prTx).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(errorAdjustment(user.Currency, 5005, "Failed to update PragmaticTransaction"))
+		user := res.User
+		before := res.BalanceBefore
+
+		// Update/create UserGameTransaction
+		var gameTx models.UserGameTransaction
+		if err := tx.Where("ref_id = ? AND provider = ?", reference, "PRAGMATIC").First(&gameTx).Error; err == nil {
+			// Update existing
+			gameTx.Status = "Adjusted"
+			gameTx.BalanceAfter = user.Balance
+			gameTx.Note = "Pragmatic Adjustment " + gameId + " round " + roundId
+			if err := tx.Save(&gameTx).Error; err != nil {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorAdjustment(user.Currency, 5003, "Failed to update UserGameTransaction")}
+			}
+		} else {
+			// Create baru
+			gameTx = models.UserGameTransaction{
+				UserID:        user.ID,
+				UserCode:      user.UserCode,
+				AgentCode:     user.AgentCode,
+				GameID:        gameId,
+				ProviderTx:    reference,
+				Provider:      "PRAGMATIC",
+				BetAmount:     decimal.Zero,
+				WinAmount:     decimal.Zero,
+				BonusAmount:   decimal.Zero,
+				Currency:      user.Currency,
+				BalanceBefore: before,
+				BalanceAfter:  user.Balance,
+				Status:        "Adjusted",
+				Note:          "Pragmatic Adjustment " + gameId + " round " + roundId,
+				RefID:         reference,
+				RoundID:       roundId,
+			}
+			if adjAmt.IsPositive() {
+				gameTx.WinAmount = adjAmt.Abs()
+			} else {
+				gameTx.BetAmount = adjAmt.Abs()
+			}
+			if err := tx.Create(&gameTx).Error; err != nil {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorAdjustment(user.Currency, 5004, "Failed to create UserGameTransaction")}
+			}
 		}
-	} else {
-		newTx := models.PragmaticTransaction{
-			UserID:        strconv.FormatUint(uint64(user.ID), 10),
-			Currency:      user.Currency,
-			Country:       &user.Country,
-			Cash:          decimal.NewFromFloat(user.Balance),
-			Amount:        decimal.NewFromFloat(adjAmt),
-			TotalBalance:  decimal.NewFromFloat(user.Balance),
-			GameID:        &gameId,
-			RoundID:       nil,
-			Reference:     reference,
-			TransactionID: reference,
-			Token:         user.UserCode,
-			ErrorCode:     intPtr(0),
-			Description:   strPtr("Adjustment Success"),
+
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PRAGMATIC",
+			RoundID:  roundId,
+			GameID:   gameId,
+			Win:      adjAmt,
+		}); err != nil {
+			return 0, nil, err
 		}
-		if err := tx.Create(&newTx).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(errorAdjustment(user.Currency, 5006, "Failed to create PragmaticTransaction"))
+
+		// Update/insert PragmaticTransaction
+		var prTx models.PragmaticTransaction
+		if err := tx.Where("reference = ?", reference).First(&prTx).Error; err == nil {
+			prTx.Cash = user.Balance
+			prTx.Amount = adjAmt
+			prTx.TotalBalance = user.Balance
+			prTx.ErrorCode = intPtr(0)
+			prTx.Description = strPtr("Adjustment Success")
+			if err := tx.Save(&prTx).Error; err != nil {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorAdjustment(user.Currency, 5005, "Failed to update PragmaticTransaction")}
+			}
+		} else {
+			newTx := models.PragmaticTransaction{
+				UserID:        strconv.FormatUint(uint64(user.ID), 10),
+				Currency:      user.Currency,
+				Country:       &user.Country,
+				Cash:          user.Balance,
+				Amount:        adjAmt,
+				TotalBalance:  user.Balance,
+				GameID:        &gameId,
+				RoundID:       nil,
+				Reference:     reference,
+				TransactionID: reference,
+				Token:         user.UserCode,
+				ErrorCode:     intPtr(0),
+				Description:   strPtr("Adjustment Success"),
+			}
+			if err := tx.Create(&newTx).Error; err != nil {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorAdjustment(user.Currency, 5006, "Failed to create PragmaticTransaction")}
+			}
 		}
-	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.JSON(errorAdjustment(user.Currency, 5007, "Commit failed"))
+		return fiber.StatusOK, fiber.Map{
+			"transactionId": gameTx.ID,
+			"currency":      user.Currency,
+			"cash":          user.Balance,
+			"bonus":         0.0,
+			"error":         0,
+			"description":   "Adjustment Success",
+		}, nil
+	})
+	if err != nil {
+		return c.JSON(errorAdjustment("USD", 5007, "Commit failed"))
 	}
 
-	return c.Status(http.StatusOK).JSON(fiber.Map{
-		"transactionId": gameTx.ID,
-		"currency":      user.Currency,
-		"cash":          user.Balance,
-		"bonus":         0.0,
-		"error":         0,
-		"description":   "Adjustment Success",
-	})
+	return resp.Send(c)
 }
 
 // helper error response
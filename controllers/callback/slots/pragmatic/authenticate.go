@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"telo/database"
-	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -52,19 +52,16 @@ func AuthenticateHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: validasi hash (sama kayak BalanceHandler, bisa pakai verifyHashPragmatic)
-
-	// 🔍 Cari user dari token
-	var user models.User
-	if err := database.DB.Where("user_code = ?", req.Token).First(&user).Error; err != nil {
-		log.Printf("[PRAGMATIC] ❌ User not found: %s", req.Token)
+	// 🔍 Cari user dari launch token
+	user, ok := resolveToken(req.Token, "")
+	if !ok {
 		return c.JSON(fiber.Map{
-			"error":       2001,
-			"description": "User not found",
+			"error":       4,
+			"description": "Invalid token",
 		})
 	}
 
-	if !user.IsActive {
+	if err := wallet.CheckActive(database.DB, user); err != nil {
 		log.Printf("[PRAGMATIC] ❌ User inactive: %s", req.Token)
 		return c.JSON(fiber.Map{
 			"error":       2002,
@@ -72,8 +69,8 @@ func AuthenticateHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Printf("[PRAGMATIC] ✅ Auth Success | user=%s | balance=%.2f | duration=%v",
-		user.UserCode, user.Balance, time.Since(start))
+	log.Printf("[PRAGMATIC] ✅ Auth Success | user=%s | balance=%s | duration=%v",
+		user.UserCode, user.Balance.StringFixed(2), time.Since(start))
 
 	// 🔹 Response sukses
 	return c.JSON(fiber.Map{
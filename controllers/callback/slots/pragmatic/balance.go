@@ -1,6 +1,7 @@
 package pragmatic
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -8,6 +9,8 @@ import (
 
 	"telo/database"
 	"telo/models"
+	"telo/services/launchtoken"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -31,8 +34,9 @@ func Balance(c *fiber.Ctx) error {
 	providerId := c.FormValue("providerId")
 	userId := c.FormValue("userId")
 	hash := c.FormValue("hash")
+	token := c.FormValue("token")
 
-	if providerId == "" || userId == "" || hash == "" {
+	if providerId == "" || userId == "" || hash == "" || token == "" {
 		return c.Status(http.StatusOK).JSON(fiber.Map{
 			"currency":    "IDR",
 			"cash":        0.0,
@@ -42,21 +46,18 @@ func Balance(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: kalau mau, validasi providerId & hash di sini
-	// ...
-
-	var user models.User
-	if err := database.DB.Where("user_code = ?", userId).First(&user).Error; err != nil {
+	user, ok := resolveToken(token, userId)
+	if !ok {
 		return c.Status(http.StatusOK).JSON(fiber.Map{
 			"currency":    "IDR",
 			"cash":        0.0,
 			"bonus":       0.0,
-			"error":       2001,
-			"description": "User not found",
+			"error":       4,
+			"description": "Invalid token",
 		})
 	}
 
-	if !user.IsActive {
+	if err := wallet.CheckActive(database.DB, user); err != nil {
 		return c.Status(http.StatusOK).JSON(fiber.Map{
 			"currency":    user.Currency,
 			"cash":        0.0,
@@ -66,8 +67,12 @@ func Balance(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Printf("[PRAGMATIC] ✅ Balance success | user=%s | balance=%.2f | duration=%v",
-		user.UserCode, user.Balance, time.Since(start))
+	if err := wallet.RefreshBalance(database.DB, &user); err != nil {
+		log.Printf("[PRAGMATIC] ⚠️ Failed to refresh seamless balance user=%s: %v", user.UserCode, err)
+	}
+
+	log.Printf("[PRAGMATIC] ✅ Balance success | user=%s | balance=%s | duration=%v",
+		user.UserCode, user.Balance.StringFixed(2), time.Since(start))
 
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"currency":    user.Currency,
@@ -86,3 +91,32 @@ func intPtr(v int) *int32 {
 func strPtr(s string) *string {
 	return &s
 }
+
+// resolveToken me-resolve user dari launch token Pragmatic. Kalau userId
+// dikirim, harus sama dengan pemilik token.
+func resolveToken(token, userId string) (models.User, bool) {
+	user, _, err := launchtoken.ResolveOrLegacy(database.DB, token, "PRAGMATIC", userId)
+	if err != nil {
+		log.Printf("[PRAGMATIC] ❌ Invalid token: %v", err)
+		return user, false
+	}
+	if userId != "" && user.UserCode != userId {
+		log.Printf("[PRAGMATIC] ❌ Token user mismatch | token_user=%s | userId=%s", user.UserCode, userId)
+		return user, false
+	}
+	return user, true
+}
+
+// walletErrorCode memetakan error dari services/wallet ke kode error Pragmatic.
+func walletErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, wallet.ErrUserNotFound):
+		return 2001, "User not found"
+	case errors.Is(err, wallet.ErrUserInactive):
+		return 2002, "User inactive"
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return 3001, "Insufficient funds"
+	default:
+		return 5002, "Failed to update balance"
+	}
+}
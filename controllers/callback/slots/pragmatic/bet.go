@@ -1,18 +1,18 @@
 package pragmatic
 
 import (
-	"errors"
-	"math"
 	"strconv"
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/idempotency"
+	"telo/services/wallet"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 func Bet(c *fiber.Ctx) error {
@@ -40,9 +40,10 @@ func Bet(c *fiber.Ctx) error {
 	reference := c.FormValue("reference")
 	hash := c.FormValue("hash")
 	timestamp := c.FormValue("timestamp")
+	token := c.FormValue("token")
 
 	if providerId == "" || userId == "" || gameId == "" || roundId == "" ||
-		amountStr == "" || reference == "" || hash == "" || timestamp == "" {
+		amountStr == "" || reference == "" || hash == "" || timestamp == "" || token == "" {
 		return c.JSON(fiber.Map{
 			"currency":    "USD",
 			"cash":        0.0,
@@ -53,168 +54,161 @@ func Bet(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: providerId + hash validation (optional)
-	// if providerId != "pragmaticplay" { ... }
-	// if !verifyHashPragmatic(c, "yoursecret") { ... }
-
-	// Parse amount
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount < 0 {
+	if _, ok := resolveToken(token, userId); !ok {
 		return c.JSON(fiber.Map{
 			"currency":    "USD",
 			"cash":        0.0,
 			"bonus":       0.0,
 			"usedPromo":   0,
-			"error":       3002,
-			"description": "Invalid amount",
+			"error":       4,
+			"description": "Invalid token",
 		})
 	}
-	amountCents := int64(math.Round(amount * 100))
-
-	// === Idempotency check ===
-	var existing models.UserGameTransaction
-	err = database.DB.Where("provider_tx = ? AND provider = ?", reference, "PRAGMATIC").
-		First(&existing).Error
-	if err == nil {
-		var user models.User
-		_ = database.DB.First(&user, existing.UserID).Error
-		return c.JSON(fiber.Map{
-			"transactionId": existing.ID,
-			"currency":      user.Currency,
-			"cash":          user.Balance,
-			"bonus":         0.0,
-			"usedPromo":     0,
-			"error":         0,
-			"description":   "Success",
-		})
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+
+	// Parse amount
+	amount, err := decimal.NewFromString(amountStr)
+	if err != nil || amount.IsNegative() {
 		return c.JSON(fiber.Map{
 			"currency":    "USD",
 			"cash":        0.0,
 			"bonus":       0.0,
 			"usedPromo":   0,
-			"error":       5001,
-			"description": "DB error",
+			"error":       3002,
+			"description": "Invalid amount",
 		})
 	}
 
-	// === Start TX ===
-	tx := database.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	key := idempotency.Key{Provider: "PRAGMATIC", Operation: "BET", ExternalID: reference}
+	resp, err := idempotency.Do(database.DB, key, func(tx *gorm.DB) (int, any, error) {
+		res, err := wallet.Debit(tx, wallet.Request{
+			UserCode:   userId,
+			Provider:   "PRAGMATIC",
+			ExternalID: reference,
+			Amount:     amount,
+			RefID:      reference,
+			Note:       "Pragmatic Bet round " + roundId,
+		})
+		if err != nil {
+			code, desc := walletErrorCode(err)
+			currency := res.User.Currency
+			if currency == "" {
+				currency = "USD"
+			}
+			cash := decimal.Zero
+			if code == 3001 {
+				cash = res.User.Balance
+			}
+			body := fiber.Map{
+				"currency":    currency,
+				"cash":        cash,
+				"bonus":       0.0,
+				"usedPromo":   0,
+				"error":       code,
+				"description": desc,
+			}
+			if code == 5002 {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: body}
+			}
+			return fiber.StatusOK, body, nil
 		}
-	}()
 
-	var user models.User
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", userId).First(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    "USD",
-			"cash":        0.0,
-			"bonus":       0.0,
-			"usedPromo":   0,
-			"error":       2001,
-			"description": "User not found",
-		})
-	}
-	if !user.IsActive {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        0.0,
-			"bonus":       0.0,
-			"usedPromo":   0,
-			"error":       2002,
-			"description": "User inactive",
-		})
-	}
-	if user.Balance < amount {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        user.Balance,
-			"bonus":       0.0,
-			"usedPromo":   0,
-			"error":       3001,
-			"description": "Insufficient funds",
-		})
-	}
+		user := res.User
+		before := res.BalanceBefore
+
+		// Sudah diproses sebelum tabel idempotency ada
+		if res.Duplicate {
+			var existing models.UserGameTransaction
+			_ = tx.Where("provider_tx = ? AND provider = ?", reference, "PRAGMATIC").First(&existing).Error
+			return fiber.StatusOK, fiber.Map{
+				"transactionId": existing.ID,
+				"currency":      user.Currency,
+				"cash":          user.Balance,
+				"bonus":         0.0,
+				"usedPromo":     0,
+				"error":         0,
+				"description":   "Success",
+			}, nil
+		}
 
-	before := user.Balance
-	user.Balance -= amount
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
-			"bonus":       0.0,
-			"usedPromo":   0,
-			"error":       5002,
-			"description": "Failed to update balance",
-		})
-	}
+		// Save UserGameTransaction
+		ugtx := models.UserGameTransaction{
+			UserID:        user.ID,
+			UserCode:      user.UserCode,
+			AgentCode:     user.AgentCode,
+			GameID:        gameId,
+			ProviderTx:    reference,
+			Provider:      "PRAGMATIC",
+			BetAmount:     amount,
+			Currency:      user.Currency,
+			BalanceBefore: before,
+			BalanceAfter:  user.Balance,
+			Status:        "Running",
+			Note:          "Pragmatic Bet round " + roundId,
+			RefID:         reference,
+			RoundID:       roundId,
+		}
+		if err := tx.Create(&ugtx).Error; err != nil {
+			return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fiber.Map{
+				"currency":    user.Currency,
+				"cash":        before,
+				"bonus":       0.0,
+				"usedPromo":   0,
+				"error":       5003,
+				"description": "Failed to create UserGameTransaction",
+			}}
+		}
 
-	// Save UserGameTransaction
-	ugtx := models.UserGameTransaction{
-		UserID:        user.ID,
-		UserCode:      user.UserCode,
-		AgentCode:     user.AgentCode,
-		GameID:        gameId,
-		ProviderTx:    reference,
-		Provider:      "PRAGMATIC",
-		BetAmount:     amountCents,
-		Currency:      user.Currency,
-		BalanceBefore: before,
-		BalanceAfter:  user.Balance,
-		Status:        "Running",
-		Note:          "Pragmatic Bet round " + roundId,
-		RefID:         reference,
-	}
-	if err := tx.Create(&ugtx).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
-			"bonus":       0.0,
-			"usedPromo":   0,
-			"error":       5003,
-			"description": "Failed to create UserGameTransaction",
-		})
-	}
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PRAGMATIC",
+			RoundID:  roundId,
+			GameID:   gameId,
+			Bet:      amount,
+		}); err != nil {
+			return 0, nil, err
+		}
 
-	// Save PragmaticTransaction
-	prTx := models.PragmaticTransaction{
-		UserID:        strconv.FormatUint(uint64(user.ID), 10),
-		Currency:      user.Currency,
-		Country:       &user.Country,
-		Cash:          decimal.NewFromFloat(user.Balance),
-		Amount:        decimal.NewFromFloat(amount),
-		TotalBalance:  decimal.NewFromFloat(user.Balance),
-		GameID:        &gameId,
-		Reference:     reference,
-		TransactionID: reference,
-		Token:         user.UserCode,
-		ErrorCode:     intPtr(0),
-		Description:   strPtr("Success"),
-	}
-	if err := tx.Create(&prTx).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
-			"bonus":       0.0,
-			"usedPromo":   0,
-			"error":       5004,
-			"description": "Failed to create PragmaticTransaction",
-		})
-	}
+		// Save PragmaticTransaction
+		prTx := models.PragmaticTransaction{
+			UserID:        strconv.FormatUint(uint64(user.ID), 10),
+			Currency:      user.Currency,
+			Country:       &user.Country,
+			Cash:          user.Balance,
+			Amount:        amount,
+			TotalBalance:  user.Balance,
+			GameID:        &gameId,
+			Reference:     reference,
+			TransactionID: reference,
+			Token:         user.UserCode,
+			ErrorCode:     intPtr(0),
+			Description:   strPtr("Success"),
+		}
+		if err := tx.Create(&prTx).Error; err != nil {
+			return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fiber.Map{
+				"currency":    user.Currency,
+				"cash":        before,
+				"bonus":       0.0,
+				"usedPromo":   0,
+				"error":       5004,
+				"description": "Failed to create PragmaticTransaction",
+			}}
+		}
 
-	if err := tx.Commit().Error; err != nil {
+		return fiber.StatusOK, fiber.Map{
+			"transactionId": ugtx.ID,
+			"currency":      user.Currency,
+			"cash":          user.Balance,
+			"bonus":         0.0,
+			"usedPromo":     0,
+			"error":         0,
+			"description":   "Success",
+			"duration":      time.Since(start).String(),
+		}, nil
+	})
+	if err != nil {
 		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
+			"currency":    "USD",
+			"cash":        0.0,
 			"bonus":       0.0,
 			"usedPromo":   0,
 			"error":       5005,
@@ -222,14 +216,5 @@ func Bet(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"transactionId": ugtx.ID,
-		"currency":      user.Currency,
-		"cash":          user.Balance,
-		"bonus":         0.0,
-		"usedPromo":     0,
-		"error":         0,
-		"description":   "Success",
-		"duration":      time.Since(start).String(),
-	})
+	return resp.Send(c)
 }
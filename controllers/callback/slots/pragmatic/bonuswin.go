@@ -1,15 +1,17 @@
 package pragmatic
 
 import (
-	"math"
 	"strconv"
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/idempotency"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/shopspring/decimal"
-	"gorm.io/gorm/clause"
+	"gorm.io/gorm"
 )
 
 func BonusWin(c *fiber.Ctx) error {
@@ -43,11 +45,9 @@ func BonusWin(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: validasi providerId & hash kalau perlu
-
 	// Parse amount
-	winAmt, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || winAmt < 0 {
+	winAmt, err := decimal.NewFromString(amountStr)
+	if err != nil || winAmt.IsNegative() {
 		return c.JSON(fiber.Map{
 			"currency":    "USD",
 			"cash":        0.0,
@@ -56,138 +56,132 @@ func BonusWin(c *fiber.Ctx) error {
 			"description": "Invalid amount",
 		})
 	}
-	winCents := int64(math.Round(winAmt * 100))
 
-	// Idempotency check
-	var existed models.UserGameTransaction
-	if err := database.DB.Where("provider_tx = ? AND provider = ?", reference, "PRAGMATIC").
-		First(&existed).Error; err == nil {
-		var user models.User
-		_ = database.DB.First(&user, existed.UserID).Error
-		return c.JSON(fiber.Map{
-			"transactionId": existed.ID,
-			"currency":      user.Currency,
-			"cash":          user.Balance,
-			"bonus":         0.0,
-			"error":         0,
-			"description":   "Success",
-		})
-	}
-
-	// Start TX
-	tx := database.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	key := idempotency.Key{Provider: "PRAGMATIC", Operation: "BONUS_WIN", ExternalID: reference}
+	resp, err := idempotency.Do(database.DB, key, func(tx *gorm.DB) (int, any, error) {
+		// Sudah diproses sebelum tabel idempotency ada
+		var existed models.UserGameTransaction
+		if err := tx.Where("provider_tx = ? AND provider = ?", reference, "PRAGMATIC").
+			First(&existed).Error; err == nil {
+			var user models.User
+			_ = tx.First(&user, existed.UserID).Error
+			return fiber.StatusOK, fiber.Map{
+				"transactionId": existed.ID,
+				"currency":      user.Currency,
+				"cash":          user.Balance,
+				"bonus":         0.0,
+				"error":         0,
+				"description":   "Success",
+			}, nil
 		}
-	}()
 
-	var user models.User
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", userId).First(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    "USD",
-			"cash":        0.0,
-			"bonus":       0.0,
-			"error":       2001,
-			"description": "User not found",
-		})
-	}
-	if !user.IsActive {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        0.0,
-			"bonus":       0.0,
-			"error":       2002,
-			"description": "User inactive",
+		res, err := wallet.Credit(tx, wallet.Request{
+			UserCode:   userId,
+			Provider:   "PRAGMATIC",
+			ExternalID: reference,
+			Amount:     winAmt,
+			RefID:      reference,
+			Note:       "Pragmatic BonusWin",
 		})
-	}
+		if err != nil {
+			code, desc := walletErrorCode(err)
+			currency := res.User.Currency
+			if currency == "" {
+				currency = "USD"
+			}
+			body := fiber.Map{
+				"currency":    currency,
+				"cash":        0.0,
+				"bonus":       0.0,
+				"error":       code,
+				"description": desc,
+			}
+			if code == 5002 {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: body}
+			}
+			return fiber.StatusOK, body, nil
+		}
+		user := res.User
+		before := res.BalanceBefore
 
-	before := user.Balance
-	if winAmt > 0 {
-		user.Balance += winAmt
-		if err := tx.Save(&user).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(fiber.Map{
+		// Save UserGameTransaction
+		ugtx := models.UserGameTransaction{
+			UserID:        user.ID,
+			UserCode:      user.UserCode,
+			AgentCode:     user.AgentCode,
+			GameID:        "",
+			ProviderTx:    reference,
+			Provider:      "PRAGMATIC",
+			WinAmount:     winAmt,
+			Currency:      user.Currency,
+			BalanceBefore: before,
+			BalanceAfter:  user.Balance,
+			Status:        "Settled",
+			Note:          "Pragmatic BonusWin",
+			RefID:         reference,
+		}
+		if err := tx.Create(&ugtx).Error; err != nil {
+			return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fiber.Map{
 				"currency":    user.Currency,
 				"cash":        before,
 				"bonus":       0.0,
-				"error":       5002,
-				"description": "Failed to update balance",
-			})
+				"error":       5003,
+				"description": "Failed to create UserGameTransaction",
+			}}
 		}
-	}
 
-	// Save UserGameTransaction
-	ugtx := models.UserGameTransaction{
-		UserID:        user.ID,
-		UserCode:      user.UserCode,
-		AgentCode:     user.AgentCode,
-		GameID:        "",
-		ProviderTx:    reference,
-		Provider:      "PRAGMATIC",
-		WinAmount:     winCents,
-		Currency:      user.Currency,
-		BalanceBefore: before,
-		BalanceAfter:  user.Balance,
-		Status:        "Settled",
-		Note:          "Pragmatic BonusWin",
-		RefID:         reference,
-	}
-	if err := tx.Create(&ugtx).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
-			"bonus":       0.0,
-			"error":       5003,
-			"description": "Failed to create UserGameTransaction",
-		})
-	}
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PRAGMATIC",
+			RoundID:  reference,
+			Win:      winAmt,
+			Close:    true,
+		}); err != nil {
+			return 0, nil, err
+		}
 
-	// Simpan juga PragmaticTransaction
-	prTx := models.PragmaticTransaction{
-		UserID:        strconv.FormatUint(uint64(user.ID), 10),
-		Currency:      user.Currency,
-		Country:       &user.Country,
-		Cash:          decimal.NewFromFloat(user.Balance),
-		Amount:        decimal.NewFromFloat(winAmt),
-		TotalBalance:  decimal.NewFromFloat(user.Balance),
-		Reference:     reference,
-		TransactionID: reference,
-		Token:         user.UserCode,
-		ErrorCode:     intPtr(0),
-		Description:   strPtr("Success"),
-	}
-	if err := tx.Create(&prTx).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
-			"bonus":       0.0,
-			"error":       5005,
-			"description": "Failed to create PragmaticTransaction",
-		})
-	}
+		// Simpan juga PragmaticTransaction
+		prTx := models.PragmaticTransaction{
+			UserID:        strconv.FormatUint(uint64(user.ID), 10),
+			Currency:      user.Currency,
+			Country:       &user.Country,
+			Cash:          user.Balance,
+			Amount:        winAmt,
+			TotalBalance:  user.Balance,
+			Reference:     reference,
+			TransactionID: reference,
+			Token:         user.UserCode,
+			ErrorCode:     intPtr(0),
+			Description:   strPtr("Success"),
+		}
+		if err := tx.Create(&prTx).Error; err != nil {
+			return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fiber.Map{
+				"currency":    user.Currency,
+				"cash":        before,
+				"bonus":       0.0,
+				"error":       5005,
+				"description": "Failed to create PragmaticTransaction",
+			}}
+		}
 
-	if err := tx.Commit().Error; err != nil {
+		return fiber.StatusOK, fiber.Map{
+			"transactionId": ugtx.ID,
+			"currency":      user.Currency,
+			"cash":          user.Balance,
+			"bonus":         0.0,
+			"error":         0,
+			"description":   "Success",
+		}, nil
+	})
+	if err != nil {
 		return c.JSON(fiber.Map{
-			"currency":    user.Currency,
-			"cash":        before,
+			"currency":    "USD",
+			"cash":        0.0,
 			"bonus":       0.0,
 			"error":       5006,
 			"description": "Commit failed",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"transactionId": ugtx.ID,
-		"currency":      user.Currency,
-		"cash":          user.Balance,
-		"bonus":         0.0,
-		"error":         0,
-		"description":   "Success",
-	})
+	return resp.Send(c)
 }
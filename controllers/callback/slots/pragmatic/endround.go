@@ -6,6 +6,7 @@ import (
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/shopspring/decimal"
@@ -59,46 +60,52 @@ func EndRound(c *fiber.Ctx) error {
 			"description": "User not found",
 		})
 	}
-	if !user.IsActive {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"cash":        0.0,
-			"bonus":       0.0,
-			"error":       2002,
-			"description": "User inactive",
-		})
-	}
+	// User/agent yang di-suspend tetap boleh menutup round yang sudah berjalan.
 
-	// Cari transaksi bet yang masih Running dengan roundId
-	var ugtx models.UserGameTransaction
-	if err := tx.Where("ref_id = ? AND provider = ? AND status = ?", roundId, "PRAGMATIC", "Running").
-		First(&ugtx).Error; err != nil {
+	roundFound, err := gameround.Close(tx, "PRAGMATIC", roundId, user.ID)
+	if err != nil {
 		tx.Rollback()
 		return c.JSON(fiber.Map{
 			"cash":        user.Balance,
 			"bonus":       0.0,
-			"error":       3003,
-			"description": "Running bet not found",
+			"error":       5003,
+			"description": "Failed to update GameRound",
 		})
 	}
 
-	// Update status ke Ended
-	ugtx.Status = "Ended"
-	if err := tx.Save(&ugtx).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(fiber.Map{
-			"cash":        user.Balance,
-			"bonus":       0.0,
-			"error":       5003,
-			"description": "Failed to update UserGameTransaction",
-		})
+	// Cari transaksi bet yang masih Running dengan roundId. Kalau result
+	// sudah men-settle semua bet, cukup round-nya yang ditutup.
+	var ugtx models.UserGameTransaction
+	if err := tx.Where("(round_id = ? OR ref_id = ?) AND provider = ? AND status = ?", roundId, roundId, "PRAGMATIC", "Running").
+		First(&ugtx).Error; err != nil {
+		if !roundFound {
+			tx.Rollback()
+			return c.JSON(fiber.Map{
+				"cash":        user.Balance,
+				"bonus":       0.0,
+				"error":       3003,
+				"description": "Running bet not found",
+			})
+		}
+	} else {
+		// Update status ke Ended
+		ugtx.Status = "Ended"
+		if err := tx.Save(&ugtx).Error; err != nil {
+			tx.Rollback()
+			return c.JSON(fiber.Map{
+				"cash":        user.Balance,
+				"bonus":       0.0,
+				"error":       5003,
+				"description": "Failed to update UserGameTransaction",
+			})
+		}
 	}
 
 	var prTx models.PragmaticTransaction
 	if err := tx.Where("reference = ? AND provider_id = ?", roundId, "PRAGMATIC").
 		First(&prTx).Error; err == nil {
-		prTx.Cash = decimal.NewFromFloat(user.Balance)
-		prTx.TotalBalance = decimal.NewFromFloat(user.Balance)
+		prTx.Cash = user.Balance
+		prTx.TotalBalance = user.Balance
 		prTx.ErrorCode = intPtr(0)
 		prTx.Description = strPtr("EndRound Success")
 		if err := tx.Save(&prTx).Error; err != nil {
@@ -115,9 +122,9 @@ func EndRound(c *fiber.Ctx) error {
 			UserID:        strconv.FormatUint(uint64(user.ID), 10),
 			Currency:      user.Currency,
 			Country:       &user.Country,
-			Cash:          decimal.NewFromFloat(user.Balance),
-			Amount:        decimal.NewFromFloat(0),
-			TotalBalance:  decimal.NewFromFloat(user.Balance),
+			Cash:          user.Balance,
+			Amount:        decimal.Zero,
+			TotalBalance:  user.Balance,
 			GameID:        &gameId,
 			Reference:     roundId,
 			TransactionID: roundId,
@@ -2,15 +2,16 @@
 package pragmatic
 
 import (
-	"net/http"
-	"strconv"
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/idempotency"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/shopspring/decimal"
-	"gorm.io/gorm/clause"
+	"gorm.io/gorm"
 )
 
 // POST /refund.html (x-www-form-urlencoded)
@@ -34,103 +35,137 @@ func Refund(c *fiber.Ctx) error {
 		return c.JSON(errorRefund("USD", 1001, "Missing required parameters"))
 	}
 
-	// --- idempotent check ---
-	var existed models.UserGameTransaction
-	if err := db.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Refund").
-		First(&existed).Error; err == nil {
-		return c.Status(http.StatusOK).JSON(fiber.Map{
-			"transactionId": existed.ID,
-			"currency":      existed.Currency,
-			"cash":          existed.BalanceAfter,
-			"bonus":         0.0,
-			"error":         0,
-			"description":   "Success (idempotent)",
-		})
-	}
+	key := idempotency.Key{Provider: "PRAGMATIC", Operation: "REFUND", ExternalID: reference}
+	resp, err := idempotency.Do(db, key, func(tx *gorm.DB) (int, any, error) {
+		// Refund yang sudah diproses sebelum tabel idempotency ada
+		var existed models.UserGameTransaction
+		if err := tx.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Refund").
+			First(&existed).Error; err == nil {
+			return fiber.StatusOK, fiber.Map{
+				"transactionId": existed.ID,
+				"currency":      existed.Currency,
+				"cash":          existed.BalanceAfter,
+				"bonus":         0.0,
+				"error":         0,
+				"description":   "Success (idempotent)",
+			}, nil
+		}
 
-	// cari bet asal
-	var bet models.UserGameTransaction
-	if err := db.Where("ref_id = ? AND provider = ?", reference, "PRAGMATIC").
-		First(&bet).Error; err != nil {
-		return c.Status(http.StatusOK).JSON(fiber.Map{
-			"transactionId": "",
-			"currency":      "USD",
-			"cash":          0.0,
-			"bonus":         0.0,
-			"error":         0,
-			"description":   "Success (no bet found)",
-		})
-	}
+		// Lock bet yang sama dengan result dan job stale round.
+		if err := wallet.LockBet(tx, "PRAGMATIC", reference); err != nil {
+			return 0, nil, err
+		}
 
-	// hitung refund
-	refundAmt := float64(bet.BetAmount) / 100.0
-	if amountStr != "" {
-		if v, err := strconv.ParseFloat(amountStr, 64); err == nil && v >= 0 && v < refundAmt {
-			refundAmt = v
+		// cari bet asal
+		var bet models.UserGameTransaction
+		if err := tx.Where("ref_id = ? AND provider = ?", reference, "PRAGMATIC").
+			First(&bet).Error; err != nil {
+			return fiber.StatusOK, fiber.Map{
+				"transactionId": "",
+				"currency":      "USD",
+				"cash":          0.0,
+				"bonus":         0.0,
+				"error":         0,
+				"description":   "Success (no bet found)",
+			}, nil
 		}
-	}
 
-	// TX begin
-	tx := db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+		if bet.UserCode != userId {
+			return fiber.StatusOK, errorRefund(bet.Currency, 2001, "User not found"), nil
 		}
-	}()
 
-	var user models.User
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("id = ? AND user_code = ?", bet.UserID, userId).First(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorRefund(bet.Currency, 2001, "User not found"))
-	}
-	if !user.IsActive {
-		tx.Rollback()
-		return c.JSON(errorRefund(user.Currency, 2002, "User inactive"))
-	}
+		// hitung refund
+		refundAmt := bet.BetAmount
+		if amountStr != "" {
+			if v, err := decimal.NewFromString(amountStr); err == nil && !v.IsNegative() && v.LessThan(refundAmt) {
+				refundAmt = v
+			}
+		}
 
-	user.Balance += refundAmt
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorRefund(user.Currency, 5002, "Failed to update balance"))
-	}
+		res, err := wallet.Cancel(tx, wallet.Request{
+			UserCode:   userId,
+			Provider:   "PRAGMATIC",
+			ExternalID: reference,
+			Amount:     refundAmt,
+			RefID:      reference,
+			Note:       "Pragmatic Refund",
+		})
+		if err != nil {
+			code, desc := walletErrorCode(err)
+			if code == 5002 {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorRefund(bet.Currency, code, desc)}
+			}
+			return fiber.StatusOK, errorRefund(bet.Currency, code, desc), nil
+		}
+		user := res.User
+
+		// Stake sudah dikembalikan job stale round: bet dan round sudah
+		// ditutup di sana.
+		if res.Duplicate {
+			return fiber.StatusOK, fiber.Map{
+				"transactionId": bet.ID,
+				"currency":      user.Currency,
+				"cash":          res.BalanceAfter,
+				"bonus":         0.0,
+				"error":         0,
+				"description":   "Success (idempotent)",
+			}, nil
+		}
 
-	// update bet jadi Refund
-	bet.Status = "Refund"
-	bet.WinAmount = 0
-	bet.BonusAmount = 0
-	bet.BalanceAfter = user.Balance
-	if err := tx.Save(&bet).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorRefund(user.Currency, 5003, "Failed to update UserGameTransaction"))
-	}
+		// update bet jadi Refund, hanya kalau belum di-refund
+		upd := tx.Model(&models.UserGameTransaction{}).
+			Where("id = ? AND status <> ?", bet.ID, "Refund").
+			Updates(map[string]any{
+				"status":        "Refund",
+				"win_amount":    decimal.Zero,
+				"bonus_amount":  decimal.Zero,
+				"refund_amount": refundAmt,
+				"balance_after": user.Balance,
+			})
+		if upd.Error != nil {
+			return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorRefund(user.Currency, 5003, "Failed to update UserGameTransaction")}
+		}
+		if upd.RowsAffected == 0 {
+			return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorRefund(user.Currency, 5003, "Bet already refunded")}
+		}
 
-	// update PragmaticTransaction
-	var prTx models.PragmaticTransaction
-	if err := tx.Where("reference = ?", reference).First(&prTx).Error; err == nil {
-		prTx.Cash = decimal.NewFromFloat(user.Balance)
-		prTx.Amount = decimal.NewFromFloat(refundAmt)
-		prTx.TotalBalance = decimal.NewFromFloat(user.Balance)
-		prTx.ErrorCode = intPtr(0)
-		prTx.Description = strPtr("Refund Success")
-		if err := tx.Save(&prTx).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(errorRefund(user.Currency, 5004, "Failed to update PragmaticTransaction"))
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PRAGMATIC",
+			RoundID:  bet.RoundID,
+			GameID:   bet.GameID,
+			Refund:   refundAmt,
+		}); err != nil {
+			return 0, nil, err
 		}
-	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.JSON(errorRefund(user.Currency, 5005, "Commit failed"))
-	}
+		// update PragmaticTransaction
+		var prTx models.PragmaticTransaction
+		if err := tx.Where("reference = ?", reference).First(&prTx).Error; err == nil {
+			prTx.Cash = user.Balance
+			prTx.Amount = refundAmt
+			prTx.TotalBalance = user.Balance
+			prTx.ErrorCode = intPtr(0)
+			prTx.Description = strPtr("Refund Success")
+			if err := tx.Save(&prTx).Error; err != nil {
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: errorRefund(user.Currency, 5004, "Failed to update PragmaticTransaction")}
+			}
+		}
 
-	return c.Status(http.StatusOK).JSON(fiber.Map{
-		"transactionId": bet.ID,
-		"currency":      user.Currency,
-		"cash":          user.Balance,
-		"bonus":         0.0,
-		"error":         0,
-		"description":   "Refund Success",
+		return fiber.StatusOK, fiber.Map{
+			"transactionId": bet.ID,
+			"currency":      user.Currency,
+			"cash":          user.Balance,
+			"bonus":         0.0,
+			"error":         0,
+			"description":   "Refund Success",
+		}, nil
 	})
+	if err != nil {
+		return c.JSON(errorRefund("USD", 5005, "Commit failed"))
+	}
+
+	return resp.Send(c)
 }
 
 // helper
@@ -2,16 +2,17 @@
 package pragmatic
 
 import (
-	"math"
 	"net/http"
-	"strconv"
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/idempotency"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/shopspring/decimal"
-	"gorm.io/gorm/clause"
+	"gorm.io/gorm"
 )
 
 // POST /result.html (x-www-form-urlencoded)
@@ -38,106 +39,131 @@ func Result(c *fiber.Ctx) error {
 		return c.JSON(errorResult("USD", 1001, "Missing required parameters"))
 	}
 
-	// TODO: validasi providerId & hash kalau perlu
-
 	// Parse win amount
-	winAmt, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || winAmt < 0 {
+	winAmt, err := decimal.NewFromString(amountStr)
+	if err != nil || winAmt.IsNegative() {
 		return c.JSON(errorResult("USD", 3002, "Invalid amount"))
 	}
 	// Tambahkan promoWinAmount kalau ada
+	promoAmt := decimal.Zero
 	if promoStr := c.FormValue("promoWinAmount"); promoStr != "" {
-		if v, err := strconv.ParseFloat(promoStr, 64); err == nil && v >= 0 {
-			winAmt += v
+		if v, err := decimal.NewFromString(promoStr); err == nil && !v.IsNegative() {
+			promoAmt = v
+			winAmt = winAmt.Add(v)
 		}
 	}
-	winCents := int64(math.Round(winAmt * 100))
-
-	// Idempotent check → sudah ada Result untuk refId ini
-	var existed models.UserGameTransaction
-	if err := db.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Settled").
-		First(&existed).Error; err == nil {
-		var user models.User
-		_ = db.First(&user, existed.UserID).Error
-		return c.Status(http.StatusOK).JSON(fiber.Map{
-			"transactionId": existed.ID,
-			"currency":      existed.Currency,
-			"cash":          existed.BalanceAfter,
-			"bonus":         0.0,
-			"error":         0,
-			"description":   "Success (idempotent)",
-		})
-	}
 
-	// TX begin
-	tx := db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	key := idempotency.Key{Provider: "PRAGMATIC", Operation: "RESULT", ExternalID: reference}
+	resp, err := idempotency.Do(db, key, func(tx *gorm.DB) (int, any, error) {
+		// Result lama yang sudah Settled sebelum tabel idempotency ada
+		var existed models.UserGameTransaction
+		if err := tx.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Settled").
+			First(&existed).Error; err == nil {
+			return http.StatusOK, fiber.Map{
+				"transactionId": existed.ID,
+				"currency":      existed.Currency,
+				"cash":          existed.BalanceAfter,
+				"bonus":         0.0,
+				"error":         0,
+				"description":   "Success (idempotent)",
+			}, nil
 		}
-	}()
-
-	// Ambil user
-	var user models.User
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("user_code = ?", userId).First(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorResult("USD", 2001, "User not found"))
-	}
-	if !user.IsActive {
-		tx.Rollback()
-		return c.JSON(errorResult(user.Currency, 2002, "User inactive"))
-	}
 
-	// Ambil bet asal yang masih Running
-	var bet models.UserGameTransaction
-	if err := tx.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Running").
-		First(&bet).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorResult(user.Currency, 2003, "Original bet not found or not Running"))
-	}
+		// Lock bet yang sama dengan refund (provider maupun job stale round),
+		// supaya result tidak membayar win di atas stake yang sudah kembali.
+		if err := wallet.LockBet(tx, "PRAGMATIC", reference); err != nil {
+			return 0, nil, err
+		}
 
-	user.Balance += winAmt
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorResult(user.Currency, 5002, "Failed to update balance"))
-	}
+		// Ambil bet asal yang masih Running
+		var bet models.UserGameTransaction
+		if err := tx.Where("ref_id = ? AND provider = ? AND status = ?", reference, "PRAGMATIC", "Running").
+			First(&bet).Error; err != nil {
+			return 0, nil, &idempotency.Abort{Status: http.StatusOK, Body: errorResult("USD", 2003, "Original bet not found or not Running")}
+		}
+		refunded, err := wallet.Refunded(tx, bet.UserID, "PRAGMATIC", reference)
+		if err != nil {
+			return 0, nil, err
+		}
+		if refunded {
+			return http.StatusOK, errorResult(bet.Currency, 2003, "Original bet already refunded"), nil
+		}
 
-	// Update bet → Settled
-	bet.Status = "Settled"
-	bet.WinAmount = winCents
-	bet.BalanceAfter = user.Balance
-	if err := tx.Save(&bet).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(errorResult(user.Currency, 5003, "Failed to update UserGameTransaction"))
-	}
+		res, err := wallet.Credit(tx, wallet.Request{
+			UserCode:   userId,
+			Provider:   "PRAGMATIC",
+			ExternalID: reference,
+			Amount:     winAmt,
+			RefID:      reference,
+			Note:       "Pragmatic Result round " + roundId,
+		})
+		if err != nil {
+			code, desc := walletErrorCode(err)
+			currency := res.User.Currency
+			if currency == "" {
+				currency = "USD"
+			}
+			if code == 5002 {
+				return 0, nil, &idempotency.Abort{Status: http.StatusOK, Body: errorResult(currency, code, desc)}
+			}
+			return http.StatusOK, errorResult(currency, code, desc), nil
+		}
+		user := res.User
+
+		// Update bet → Settled, hanya kalau masih Running
+		upd := tx.Model(&models.UserGameTransaction{}).
+			Where("id = ? AND status = ?", bet.ID, "Running").
+			Updates(map[string]any{
+				"status":        "Settled",
+				"win_amount":    winAmt,
+				"balance_after": user.Balance,
+			})
+		if upd.Error != nil {
+			return 0, nil, &idempotency.Abort{Status: http.StatusOK, Body: errorResult(user.Currency, 5003, "Failed to update UserGameTransaction")}
+		}
+		if upd.RowsAffected == 0 {
+			return 0, nil, &idempotency.Abort{Status: http.StatusOK, Body: errorResult(user.Currency, 2003, "Original bet not found or not Running")}
+		}
 
-	// Update/Insert PragmaticTransaction
-	var prTx models.PragmaticTransaction
-	if err := tx.Where("reference = ?", reference).First(&prTx).Error; err == nil {
-		prTx.Cash = decimal.NewFromFloat(user.Balance)
-		prTx.Amount = decimal.NewFromFloat(winAmt)
-		prTx.TotalBalance = decimal.NewFromFloat(user.Balance)
-		prTx.ErrorCode = intPtr(0)
-		prTx.Description = strPtr("Result Success")
-		if err := tx.Save(&prTx).Error; err != nil {
-			tx.Rollback()
-			return c.JSON(errorResult(user.Currency, 5005, "Failed to update PragmaticTransaction"))
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "PRAGMATIC",
+			RoundID:  roundId,
+			GameID:   gameId,
+			Win:      winAmt.Sub(promoAmt),
+			Promo:    promoAmt,
+			Close:    true,
+		}); err != nil {
+			return 0, nil, err
 		}
-	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.JSON(errorResult(user.Currency, 5006, "Commit failed"))
-	}
+		// Update/Insert PragmaticTransaction
+		var prTx models.PragmaticTransaction
+		if err := tx.Where("reference = ?", reference).First(&prTx).Error; err == nil {
+			prTx.Cash = user.Balance
+			prTx.Amount = winAmt
+			prTx.TotalBalance = user.Balance
+			prTx.ErrorCode = intPtr(0)
+			prTx.Description = strPtr("Result Success")
+			if err := tx.Save(&prTx).Error; err != nil {
+				return 0, nil, &idempotency.Abort{Status: http.StatusOK, Body: errorResult(user.Currency, 5005, "Failed to update PragmaticTransaction")}
+			}
+		}
 
-	return c.Status(http.StatusOK).JSON(fiber.Map{
-		"transactionId": bet.ID,
-		"currency":      user.Currency,
-		"cash":          user.Balance,
-		"bonus":         0.0,
-		"error":         0,
-		"description":   "Success",
+		return http.StatusOK, fiber.Map{
+			"transactionId": bet.ID,
+			"currency":      user.Currency,
+			"cash":          user.Balance,
+			"bonus":         0.0,
+			"error":         0,
+			"description":   "Success",
+		}, nil
 	})
+	if err != nil {
+		return c.JSON(errorResult("USD", 5006, "Commit failed"))
+	}
+
+	return resp.Send(c)
 }
 
 // helper
@@ -1,12 +1,18 @@
 package telo
 
 import (
-	"fmt"
+	"errors"
 	"telo/database"
 	"telo/helpers"
 	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 func ProcessSlotTransaction(c *fiber.Ctx) error {
@@ -15,98 +21,227 @@ func ProcessSlotTransaction(c *fiber.Ctx) error {
 		return helpers.TeloError(c, "INVALID_JSON")
 	}
 
-	// Cek transaksi berdasarkan txn_id + txn_type
+	txnID := string(txn.Slot.TxnID)
+	if txnID == "" {
+		return helpers.TeloError(c, "INVALID_TXN_ID")
+	}
+	if txn.Slot.TxnType == "" {
+		return helpers.TeloError(c, "INVALID_TXN_TYPE")
+	}
+
+	key := idempotency.Key{Provider: "TELO", Operation: txn.Slot.TxnType, ExternalID: txnID}
+	resp, err := idempotency.Do(database.DB, key, func(tx *gorm.DB) (int, any, error) {
+		body, err := processTeloSlot(tx, &txn)
+		if err != nil && !isTeloBusinessError(err) {
+			return 0, nil, err
+		}
+		if err != nil {
+			return fiber.StatusOK, helpers.TeloErrorBody(teloWalletError(err)), nil
+		}
+		return fiber.StatusOK, body, nil
+	})
+	if err != nil {
+		return helpers.TeloError(c, teloWalletError(err))
+	}
+
+	return resp.Send(c)
+}
+
+var (
+	errInvalidBetAmount = errors.New("INVALID_BET_AMOUNT")
+	errInvalidWinAmount = errors.New("INVALID_WIN_AMOUNT")
+	errInvalidTxnType   = errors.New("INVALID_TXN_TYPE")
+)
+
+func processTeloSlot(tx *gorm.DB, txn *models.TeloSlotTransaction) (fiber.Map, error) {
+	txnID := string(txn.Slot.TxnID)
+
+	// Cek transaksi berdasarkan txn_id + txn_type (data sebelum tabel idempotency ada)
 	var existingTxn models.TeloSlotTransaction
-	db := database.DB.Where("txn_id = ? AND slot_txn_type = ?", txn.Slot.TxnID, txn.Slot.TxnType).First(&existingTxn)
-	if db.Error == nil {
+	if err := tx.Where("txn_id = ? AND slot_txn_type = ?", txnID, txn.Slot.TxnType).First(&existingTxn).Error; err == nil {
 		// Jika sudah ada transaksi dengan kombinasi ini, abaikan, tapi kembalikan saldo user saat ini
 		var user models.User
-		if err := database.DB.Where("user_code = ?", txn.UserCode).First(&user).Error; err == nil {
-			return helpers.TeloSuccess(c, int64(user.Balance))
+		if err := tx.Where("user_code = ?", txn.UserCode).First(&user).Error; err != nil {
+			return nil, wallet.ErrUserNotFound
 		}
-		return helpers.TeloError(c, "USER_NOT_FOUND")
+		return helpers.TeloSuccessBody(user.Balance.IntPart()), nil
 	}
 
 	// Cek apakah sudah ada transaksi dgn txn_id saja (berarti debit sudah diproses sebelumnya)
 	var previousTxn models.TeloSlotTransaction
-	if err := database.DB.Where("txn_id = ?", txn.Slot.TxnID).First(&previousTxn).Error; err == nil {
+	if err := tx.Where("txn_id = ?", txnID).First(&previousTxn).Error; err == nil {
 		// Update saja transaksi lama ini dengan data tambahan sesuai txn_type baru
-		var user models.User
-		if err := database.DB.Where("user_code = ? AND is_active = true", txn.UserCode).First(&user).Error; err != nil {
-			return helpers.TeloError(c, "USER_NOT_FOUND")
-		}
-
 		bet, _ := txn.Slot.Bet.ToInt64()
 		win, _ := txn.Slot.Win.ToInt64()
 
+		var balance decimal.Decimal
 		switch txn.Slot.TxnType {
 		case "credit":
-			user.Balance += float64(win)
+			balance, err = moveTeloBalance(tx, txn, txnID, 0, win)
 		case "debit_credit":
-			user.Balance = user.Balance - float64(bet) + float64(win)
+			balance, err = moveTeloBalance(tx, txn, txnID, bet, win)
+		default:
+			balance, err = moveTeloBalance(tx, txn, txnID, 0, 0)
 		}
-
-		if err := database.DB.Save(&user).Error; err != nil {
-			return helpers.TeloError(c, "FAILED_TO_UPDATE_USER")
+		if err != nil {
+			return nil, err
 		}
 
 		// Update field balance & after balance
-		previousTxn.UserBalance = models.FlexibleString(fmt.Sprintf("%f", user.Balance))
-		previousTxn.Slot.UserAfterBalance = models.FlexibleString(fmt.Sprintf("%f", user.Balance))
+		previousTxn.UserBalance = models.FlexibleString(balance.StringFixed(6))
+		previousTxn.Slot.UserAfterBalance = models.FlexibleString(balance.StringFixed(6))
 		previousTxn.Slot.TxnType = txn.Slot.TxnType
 		previousTxn.Slot.Win = txn.Slot.Win
 		previousTxn.Slot.Bet = txn.Slot.Bet
 
-		_ = database.DB.Save(&previousTxn)
-		return helpers.TeloSuccess(c, int64(user.Balance))
+		if err := tx.Save(&previousTxn).Error; err != nil {
+			return nil, err
+		}
+		return helpers.TeloSuccessBody(balance.IntPart()), nil
 	}
 
 	// Transaksi baru (debit pertama kali)
-	var user models.User
-	if err := database.DB.Where("user_code = ? AND is_active = true", txn.UserCode).First(&user).Error; err != nil {
-		return helpers.TeloError(c, "USER_NOT_FOUND")
-	}
-
 	bet, err := txn.Slot.Bet.ToInt64()
 	if err != nil {
-		return helpers.TeloError(c, "INVALID_BET_AMOUNT")
+		return nil, errInvalidBetAmount
 	}
 
 	win, err := txn.Slot.Win.ToInt64()
 	if err != nil {
-		return helpers.TeloError(c, "INVALID_WIN_AMOUNT")
+		return nil, errInvalidWinAmount
 	}
 
-	beforeBalance := user.Balance
-
 	switch txn.Slot.TxnType {
 	case "debit":
-		if int64(user.Balance) < bet {
-			return helpers.TeloError(c, "INSUFFICIENT_USER_FUNDS")
-		}
-		user.Balance -= float64(bet)
+		win = 0
 	case "credit":
-		user.Balance += float64(win)
+		bet = 0
 	case "debit_credit":
-		if int64(user.Balance) < bet {
-			return helpers.TeloError(c, "INSUFFICIENT_USER_FUNDS")
-		}
-		user.Balance = user.Balance - float64(bet) + float64(win)
 	default:
-		return helpers.TeloError(c, "INVALID_TXN_TYPE")
+		return nil, errInvalidTxnType
+	}
+
+	var user models.User
+	if err := tx.Where("user_code = ?", txn.UserCode).First(&user).Error; err != nil {
+		return nil, wallet.ErrUserNotFound
+	}
+	beforeBalance := user.Balance
+
+	balance, err := moveTeloBalance(tx, txn, txnID, bet, win)
+	if err != nil {
+		return nil, err
 	}
 
-	txn.UserBalance = models.FlexibleString(fmt.Sprintf("%f", user.Balance))
-	txn.Slot.UserBeforeBalance = models.FlexibleString(fmt.Sprintf("%f", beforeBalance))
-	txn.Slot.UserAfterBalance = models.FlexibleString(fmt.Sprintf("%f", user.Balance))
+	txn.UserBalance = models.FlexibleString(balance.StringFixed(6))
+	txn.Slot.UserBeforeBalance = models.FlexibleString(beforeBalance.StringFixed(6))
+	txn.Slot.UserAfterBalance = models.FlexibleString(balance.StringFixed(6))
 
-	if err := database.DB.Create(&txn).Error; err != nil {
-		return helpers.TeloError(c, "FAILED_TO_SAVE_TRANSACTION")
+	if err := tx.Create(txn).Error; err != nil {
+		return nil, err
 	}
+	return helpers.TeloSuccessBody(balance.IntPart()), nil
+}
 
-	if err := database.DB.Save(&user).Error; err != nil {
-		return helpers.TeloError(c, "FAILED_TO_UPDATE_USER")
+// moveTeloBalance men-debit bet lalu men-credit win lewat wallet service,
+// mencatat game transaction ternormalisasi dan round, lalu mengembalikan saldo
+// akhir user.
+//
+// Telo memproses setiap txn_type sekali per txn_id: debit_credit setelah debit
+// (atau credit) dengan txn_id yang sama tetap memotong bet dan menambah win.
+// Karena itu ExternalID wallet untuk debit_credit diberi akhiran txn_type,
+// supaya tidak dianggap duplikat dari debit/credit sebelumnya.
+func moveTeloBalance(tx *gorm.DB, txn *models.TeloSlotTransaction, txnID string, bet, win int64) (decimal.Decimal, error) {
+	req := wallet.Request{
+		UserCode:   txn.UserCode,
+		Provider:   "TELO",
+		ExternalID: txnID,
+	}
+	if txn.Slot.TxnType == "debit_credit" {
+		req.ExternalID = txnID + ":" + txn.Slot.TxnType
+	}
+
+	var user models.User
+	if err := tx.Where("user_code = ?", txn.UserCode).First(&user).Error; err != nil {
+		return decimal.Zero, wallet.ErrUserNotFound
+	}
+	balance := user.Balance
+
+	entry := gametx.Entry{
+		User:       user,
+		Provider:   "TELO",
+		ProviderTx: txnID,
+		RoundID:    string(txn.Slot.RoundID),
+		GameID:     string(txn.Slot.GameCode),
+		RefID:      txnID,
+	}
+	moved := false
+
+	if bet > 0 {
+		req.Amount = decimal.NewFromInt(bet)
+		res, err := wallet.Debit(tx, req)
+		if err != nil {
+			return res.BalanceAfter, err
+		}
+		balance = res.BalanceAfter
+		if !res.Duplicate {
+			entry.Bet = req.Amount
+			moved = true
+		}
 	}
 
-	return helpers.TeloSuccess(c, int64(user.Balance))
+	if win > 0 {
+		req.Amount = decimal.NewFromInt(win)
+		res, err := wallet.Credit(tx, req)
+		if err != nil {
+			return res.BalanceAfter, err
+		}
+		balance = res.BalanceAfter
+		if !res.Duplicate {
+			entry.Win = req.Amount
+			moved = true
+		}
+	}
+
+	if moved || txn.Slot.IsRoundFinished {
+		entry.BalanceAfter = balance
+		if txn.Slot.IsRoundFinished || win > 0 {
+			entry.Status = gametx.StatusSettled
+		}
+		if err := gametx.Record(tx, entry); err != nil {
+			return balance, err
+		}
+		if err := gameround.Apply(tx, gameround.Event{
+			User:     user,
+			Provider: "TELO",
+			RoundID:  entry.RoundID,
+			GameID:   entry.GameID,
+			Bet:      entry.Bet,
+			Win:      entry.Win,
+			Close:    txn.Slot.IsRoundFinished,
+		}); err != nil {
+			return balance, err
+		}
+	}
+	return balance, nil
+}
+
+// isTeloBusinessError menandai error yang jawabannya boleh disimpan dan
+// di-replay; error lain (DB) membatalkan transaction.
+func isTeloBusinessError(err error) bool {
+	return errors.Is(err, wallet.ErrUserNotFound) || errors.Is(err, wallet.ErrUserInactive) ||
+		errors.Is(err, wallet.ErrInsufficientFunds) || errors.Is(err, errInvalidBetAmount) ||
+		errors.Is(err, errInvalidWinAmount) || errors.Is(err, errInvalidTxnType)
+}
+
+func teloWalletError(err error) string {
+	switch {
+	case errors.Is(err, wallet.ErrUserNotFound), errors.Is(err, wallet.ErrUserInactive):
+		return "USER_NOT_FOUND"
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return "INSUFFICIENT_USER_FUNDS"
+	case errors.Is(err, errInvalidBetAmount), errors.Is(err, errInvalidWinAmount), errors.Is(err, errInvalidTxnType):
+		return err.Error()
+	default:
+		return "FAILED_TO_SAVE_TRANSACTION"
+	}
 }
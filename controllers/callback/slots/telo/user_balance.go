@@ -1,9 +1,11 @@
 package telo
 
 import (
+	"log"
 	"telo/database"
 	"telo/helpers"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -24,5 +26,9 @@ func CheckUserBalance(c *fiber.Ctx) error {
 		return helpers.TeloError(c, "INVALID_USER")
 	}
 
-	return helpers.TeloSuccess(c, int64(user.Balance))
+	if err := wallet.RefreshBalance(database.DB, &user); err != nil {
+		log.Printf("[TELO] ⚠️ Failed to refresh seamless balance user=%s: %v", user.UserCode, err)
+	}
+
+	return helpers.TeloSuccess(c, user.Balance.IntPart())
 }
@@ -0,0 +1,140 @@
+// Package transfergateway adalah wallet seamless transfer-style yang dipakai
+// FastSpin dan SpadeGaming: satu endpoint, API dipilih lewat header "Api".
+// Perbedaan antar merchant ada di Merchant.
+package transfergateway
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"telo/database"
+	"telo/models"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ===== DTOs =====
+type TransferRequest struct {
+	TransferID   string          `json:"transferId"`
+	MerchantCode string          `json:"merchantCode"`
+	MerchantTxID string          `json:"merchantTxId,omitempty"`
+	AcctID       string          `json:"acctId"`
+	Currency     string          `json:"currency"`
+	Amount       decimal.Decimal `json:"amount"`
+	Type         int             `json:"type"`
+	TicketID     string          `json:"ticketId,omitempty"`
+	Channel      string          `json:"channel"`
+	GameCode     string          `json:"gameCode"`
+	ReferenceID  string          `json:"referenceId,omitempty"`
+	PlayerIP     string          `json:"playerIp,omitempty"`
+	GameFeature  string          `json:"gameFeature,omitempty"`
+	TransferTime string          `json:"transferTime,omitempty"`
+	SerialNo     string          `json:"serialNo"`
+	SpecialGame  *struct {
+		Type     string `json:"type,omitempty"`
+		Count    int    `json:"count,omitempty"`
+		Sequence int    `json:"sequence,omitempty"`
+	} `json:"specialGame,omitempty"`
+	RefTicketIds []string `json:"refTicketIds,omitempty"`
+}
+
+type TransferResponse struct {
+	TransferID   string          `json:"transferId"`
+	MerchantTxID string          `json:"merchantTxId,omitempty"`
+	AcctID       string          `json:"acctId"`
+	Balance      decimal.Decimal `json:"balance"`
+	Code         int             `json:"code"`
+	Msg          string          `json:"msg"`
+	SerialNo     string          `json:"serialNo"`
+}
+
+type GetBalanceRequest struct {
+	SerialNo     string  `json:"serialNo"`
+	MerchantCode string  `json:"merchantCode"`
+	AcctId       string  `json:"acctId"`
+	GameCode     *string `json:"gameCode,omitempty"`
+}
+
+type AcctInfo struct {
+	UserName string          `json:"userName,omitempty"`
+	Currency string          `json:"currency"`
+	AcctId   string          `json:"acctId"`
+	Balance  decimal.Decimal `json:"balance"`
+	SiteId   string          `json:"siteId,omitempty"`
+}
+
+type GetBalanceResponse struct {
+	AcctInfo     AcctInfo `json:"acctInfo"`
+	MerchantCode string   `json:"merchantCode"`
+	Msg          string   `json:"msg"`
+	Code         int      `json:"code"`
+	SerialNo     string   `json:"serialNo"`
+}
+
+// ===== Dispatcher =====
+func (m Merchant) GatewayHandler(c *fiber.Ctx) error {
+	api := strings.ToLower(c.Get("Api"))
+	log.Printf("[%s] Routing API=%s\n", m.Provider, api)
+
+	switch api {
+	case "getbalance":
+		return m.GetBalanceHandler(c)
+	case "transfer":
+		return m.TransferHandler(c)
+	default:
+		log.Printf("[%s] Unknown API header: %s\n", m.Provider, api)
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"code": -99,
+			"msg":  "Unknown API",
+		})
+	}
+}
+
+// ===== Handlers =====
+func (m Merchant) GetBalanceHandler(c *fiber.Ctx) error {
+	var req GetBalanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"code": -1, "msg": "Invalid request format"})
+	}
+
+	req.AcctId = strings.ToLower(strings.TrimSpace(req.AcctId))
+	if req.AcctId == "" || req.MerchantCode == "" || req.SerialNo == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"code": -2, "msg": "Missing required fields"})
+	}
+
+	var user models.User
+	if err := database.DB.Where("user_code = ?", req.AcctId).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(fiber.Map{"code": 1001, "msg": "User not found", "serialNo": req.SerialNo})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"code": 500, "msg": "DB error", "error": err.Error()})
+	}
+
+	if err := wallet.RefreshBalance(database.DB, &user); err != nil {
+		log.Printf("[%s] ⚠️ Failed to refresh seamless balance user=%s: %v", m.Provider, user.UserCode, err)
+	}
+
+	if err := wallet.CheckActive(database.DB, user); err != nil {
+		return c.JSON(fiber.Map{"code": 50103, "msg": "Account suspended", "serialNo": req.SerialNo})
+	}
+
+	displayBalance := user.Balance.Div(m.BalanceRatio)
+
+	resp := GetBalanceResponse{
+		AcctInfo: AcctInfo{
+			UserName: user.UserCode,
+			Currency: user.Currency,
+			AcctId:   user.UserCode,
+			Balance:  displayBalance,
+		},
+		MerchantCode: req.MerchantCode,
+		Msg:          "success",
+		Code:         0,
+		SerialNo:     req.SerialNo,
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,26 @@
+package transfergateway
+
+import "github.com/shopspring/decimal"
+
+// Merchant adalah konfigurasi satu provider di gateway ini. Digest dan
+// merchantCode divalidasi di middleware (FastSpinAuth/SpadeGamingAuth).
+type Merchant struct {
+	// Provider: nama provider di wallet, gametx dan gameround.
+	Provider string
+	// Table: tabel transaksi provider (kolom models.TransferTransaction).
+	Table string
+	// BalanceRatio: saldo internal = saldo provider * BalanceRatio.
+	BalanceRatio decimal.Decimal
+}
+
+var FastSpin = Merchant{
+	Provider:     "FASTSPIN",
+	Table:        "fast_spin_transactions",
+	BalanceRatio: decimal.NewFromInt(1000),
+}
+
+var SpadeGaming = Merchant{
+	Provider:     "SPADEGAMING",
+	Table:        "spade_gaming_transactions",
+	BalanceRatio: decimal.NewFromInt(1000),
+}
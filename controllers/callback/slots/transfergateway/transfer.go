@@ -0,0 +1,313 @@
+package transfergateway
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"telo/database"
+	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipe transfer.
+const (
+	TypeBet     = 1
+	TypeCancel  = 2
+	TypePayout  = 4
+	TypeBonus   = 7
+	TypeJackpot = 8
+)
+
+// statusCancelled menandai baris bet yang sudah di-cancel supaya cancel
+// berikutnya (transferId lain, mis. lewat refTicketIds) tidak refund lagi.
+const statusCancelled = "Cancelled"
+
+func (m Merchant) TransferHandler(c *fiber.Ctx) error {
+	var req TransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("[%s] ❌ Body parse failed: %v\n", m.Provider, err)
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"code": -1, "msg": "Invalid request format"})
+	}
+
+	req.AcctID = strings.ToLower(strings.TrimSpace(req.AcctID))
+	if req.TransferID == "" || req.AcctID == "" || req.Currency == "" || req.SerialNo == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"code": -2, "msg": "Missing required fields"})
+	}
+
+	switch req.Type {
+	case TypeBet, TypePayout, TypeBonus, TypeJackpot:
+	case TypeCancel:
+		if req.ReferenceID == "" && len(req.RefTicketIds) == 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"code": -2, "msg": "Missing required fields"})
+		}
+	default:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"code": -3, "msg": "Invalid transfer type"})
+	}
+
+	var user models.User
+	if err := database.DB.Where("user_code = ?", req.AcctID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.JSON(fiber.Map{"code": 1001, "msg": "User not found", "serialNo": req.SerialNo})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"code": 500, "msg": "DB error", "error": err.Error()})
+	}
+
+	// Saldo, baris transaksi provider, bet yang di-cancel, gametx dan round
+	// ditulis dalam satu DB transaction, sekali per transferId.
+	key := idempotency.Key{Provider: m.Provider, Operation: "TRANSFER", ExternalID: req.TransferID}
+	resp, err := idempotency.Do(database.DB, key, func(dbTx *gorm.DB) (int, any, error) {
+		// Transfer yang sudah tercatat sebelum tabel idempotency ada
+		var existing models.TransferTransaction
+		if err := dbTx.Table(m.Table).Where("transfer_id = ?", req.TransferID).First(&existing).Error; err == nil {
+			return http.StatusOK, m.transferResponse(existing), nil
+		}
+
+		return m.transfer(dbTx, req, user)
+	})
+	if err != nil {
+		log.Printf("[%s] ❌ Transfer failed transferId=%s: %v", m.Provider, req.TransferID, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"code": 500, "msg": "DB error", "error": err.Error()})
+	}
+
+	return resp.Send(c)
+}
+
+func (m Merchant) transfer(dbTx *gorm.DB, req TransferRequest, user models.User) (int, any, error) {
+	amount := req.Amount.Mul(m.BalanceRatio)
+
+	op := wallet.Credit
+	var bets []models.TransferTransaction
+	switch req.Type {
+	case TypeBet:
+		op = wallet.Debit
+	case TypeCancel:
+		found, open, err := m.lockBets(dbTx, req)
+		if err != nil {
+			return 0, nil, err
+		}
+		if found == 0 {
+			return http.StatusOK, fiber.Map{"code": 109, "msg": "Reference bet not found", "serialNo": req.SerialNo}, nil
+		}
+		op = wallet.Cancel
+		bets = open
+
+		// Refund hanya stake bet yang belum di-cancel. Kalau nominal cancel
+		// tidak sama dengan stake yang masih terbuka, salah satu sisi
+		// mencatat bet yang tidak diketahui sisi lain: ditolak, bukan
+		// di-refund sebagian. Bet yang semuanya sudah di-cancel dijawab
+		// sukses tanpa refund.
+		amount = decimal.Zero
+		for _, b := range bets {
+			amount = amount.Add(b.Amount.Mul(m.BalanceRatio))
+		}
+		if len(bets) > 0 && !amount.Equal(req.Amount.Mul(m.BalanceRatio)) {
+			log.Printf("[%s] ❌ Cancel amount mismatch transferId=%s: request=%s open bets=%s",
+				m.Provider, req.TransferID, req.Amount.Mul(m.BalanceRatio), amount)
+			return http.StatusOK, fiber.Map{"code": 106, "msg": "Cancel amount does not match open bets", "serialNo": req.SerialNo}, nil
+		}
+	}
+
+	res, err := op(dbTx, wallet.Request{
+		UserCode:   user.UserCode,
+		Provider:   m.Provider,
+		ExternalID: req.TransferID,
+		Amount:     amount,
+		RefID:      req.ReferenceID,
+		Note:       m.Provider + " transfer " + req.GameCode,
+	})
+	if errors.Is(err, wallet.ErrInsufficientFunds) {
+		return http.StatusOK, fiber.Map{"code": 1002, "msg": "Insufficient balance", "serialNo": req.SerialNo}, nil
+	}
+	if errors.Is(err, wallet.ErrUserInactive) {
+		return http.StatusOK, fiber.Map{"code": 50103, "msg": "Account suspended", "serialNo": req.SerialNo}, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	row := newRow(req)
+	row.BalanceBefore = res.BalanceBefore
+	row.BalanceAfter = res.BalanceAfter
+	if err := dbTx.Table(m.Table).Create(&row).Error; err != nil {
+		return 0, nil, err
+	}
+
+	if len(bets) > 0 {
+		ids := make([]uint, len(bets))
+		for i, b := range bets {
+			ids[i] = b.ID
+		}
+		if err := dbTx.Table(m.Table).Where("id IN ?", ids).Update("status", statusCancelled).Error; err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := m.record(dbTx, req, res, amount, bets); err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, m.transferResponse(row), nil
+}
+
+// lockBets mengunci bet yang dirujuk cancel, lewat referenceId (transferId
+// bet) atau refTicketIds. found = jumlah bet yang cocok, open = yang belum
+// di-cancel.
+func (m Merchant) lockBets(dbTx *gorm.DB, req TransferRequest) (int, []models.TransferTransaction, error) {
+	q := dbTx.Table(m.Table).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("type = ? AND acct_id = ?", TypeBet, req.AcctID)
+	if req.ReferenceID != "" {
+		q = q.Where("transfer_id = ?", req.ReferenceID)
+	} else {
+		q = q.Where("ticket_id IN ?", req.RefTicketIds)
+	}
+
+	var bets []models.TransferTransaction
+	if err := q.Order("id").Find(&bets).Error; err != nil {
+		return 0, nil, err
+	}
+
+	var open []models.TransferTransaction
+	for _, b := range bets {
+		if b.Status != statusCancelled {
+			open = append(open, b)
+		}
+	}
+	return len(bets), open, nil
+}
+
+// record memperbarui UserGameTransaction dan GameRound. Round = transferId
+// bet; cancel dan payout merujuk ke bet lewat referenceId/refTicketIds.
+func (m Merchant) record(dbTx *gorm.DB, req TransferRequest, res *wallet.Result, amount decimal.Decimal, bets []models.TransferTransaction) error {
+	if res.Duplicate {
+		return nil
+	}
+
+	if req.Type == TypeCancel {
+		for _, b := range bets {
+			stake := b.Amount.Mul(m.BalanceRatio)
+			if err := gametx.Record(dbTx, gametx.Entry{
+				User:         res.User,
+				Provider:     m.Provider,
+				ProviderTx:   b.TransferID,
+				RoundID:      b.TicketID,
+				GameID:       b.GameCode,
+				Refund:       stake,
+				Status:       gametx.StatusRefund,
+				BalanceAfter: res.BalanceAfter,
+				RefID:        req.TransferID,
+			}); err != nil {
+				return err
+			}
+			if err := gameround.Apply(dbTx, gameround.Event{
+				User:     res.User,
+				Provider: m.Provider,
+				RoundID:  b.TransferID,
+				GameID:   b.GameCode,
+				Refund:   stake,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	entry := gametx.Entry{
+		User:         res.User,
+		Provider:     m.Provider,
+		ProviderTx:   req.TransferID,
+		RoundID:      req.TicketID,
+		GameID:       req.GameCode,
+		BalanceAfter: res.BalanceAfter,
+		RefID:        req.TransferID,
+	}
+	event := gameround.Event{
+		User:     res.User,
+		Provider: m.Provider,
+		GameID:   req.GameCode,
+		Close:    req.Type != TypeBet,
+	}
+	switch req.Type {
+	case TypeBet:
+		entry.Bet = amount
+		entry.Status = gametx.StatusRunning
+		event.Bet = amount
+	case TypePayout:
+		if req.ReferenceID != "" {
+			entry.ProviderTx = req.ReferenceID
+		}
+		entry.Win = amount
+		entry.Status = gametx.StatusSettled
+		event.Win = amount
+	case TypeBonus:
+		entry.Bonus = amount
+		entry.Status = gametx.StatusSettled
+		event.Promo = amount
+	case TypeJackpot:
+		if req.ReferenceID != "" {
+			entry.ProviderTx = req.ReferenceID
+		}
+		entry.Win = amount
+		entry.Note = "jackpot"
+		entry.Status = gametx.StatusSettled
+		event.Jackpot = amount
+	}
+	if err := gametx.Record(dbTx, entry); err != nil {
+		return err
+	}
+	event.RoundID = entry.ProviderTx
+	return gameround.Apply(dbTx, event)
+}
+
+func newRow(req TransferRequest) models.TransferTransaction {
+	row := models.TransferTransaction{
+		TransferID:   req.TransferID,
+		MerchantCode: req.MerchantCode,
+		MerchantTxID: req.MerchantTxID,
+		AcctID:       req.AcctID,
+		Currency:     req.Currency,
+		Amount:       req.Amount,
+		Type:         req.Type,
+		TicketID:     req.TicketID,
+		Channel:      req.Channel,
+		GameCode:     req.GameCode,
+		ReferenceID:  req.ReferenceID,
+		PlayerIP:     req.PlayerIP,
+		GameFeature:  req.GameFeature,
+		TransferTime: req.TransferTime,
+		RefTicketIds: strings.Join(req.RefTicketIds, ","),
+		Status:       "Success",
+		Msg:          "success",
+		Code:         0,
+		SerialNo:     req.SerialNo,
+	}
+	if req.SpecialGame != nil {
+		row.SpecialType = req.SpecialGame.Type
+		row.SpecialCount = req.SpecialGame.Count
+		row.SpecialSeq = req.SpecialGame.Sequence
+	}
+	row.CreatedAt = time.Now()
+	return row
+}
+
+func (m Merchant) transferResponse(row models.TransferTransaction) TransferResponse {
+	return TransferResponse{
+		TransferID:   row.TransferID,
+		MerchantTxID: row.MerchantTxID,
+		AcctID:       row.AcctID,
+		Balance:      row.BalanceAfter.Div(m.BalanceRatio),
+		Code:         row.Code,
+		Msg:          row.Msg,
+		SerialNo:     row.SerialNo,
+	}
+}
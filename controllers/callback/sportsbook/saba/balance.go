@@ -0,0 +1,46 @@
+package saba
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"telo/database"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type GetBalanceMessage struct {
+	Action string `json:"action"`
+	UserID string `json:"userId"`
+}
+
+func GetBalanceHandler(c *fiber.Ctx) error {
+	var msg GetBalanceMessage
+	if err := parse(c, &msg); err != nil || msg.UserID == "" {
+		return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+	}
+
+	user, err := findUser(database.DB, msg.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(fail(codeAccountNotFound, "Account Is Not Exist"))
+	}
+	if err != nil {
+		log.Printf("[SABA] ❌ GetBalance DB error user=%s: %v", msg.UserID, err)
+		return c.JSON(fail(codeSystemError, "System Error"))
+	}
+
+	if err := wallet.RefreshBalance(database.DB, &user); err != nil {
+		log.Printf("[SABA] ⚠️ Failed to refresh seamless balance user=%s: %v", user.UserCode, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":    codeSuccess,
+		"msg":       nil,
+		"userId":    msg.UserID,
+		"balance":   displayBalance(user),
+		"balanceTs": time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,87 @@
+package saba
+
+import (
+	"errors"
+	"log"
+
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type CancelTxn struct {
+	RefID        string          `json:"refId"`
+	CreditAmount decimal.Decimal `json:"creditAmount"`
+	DebitAmount  decimal.Decimal `json:"debitAmount"`
+}
+
+type CancelBetMessage struct {
+	Action      string      `json:"action"`
+	OperationID string      `json:"operationId"`
+	UserID      string      `json:"userId"`
+	UpdateTime  string      `json:"updateTime"`
+	Txns        []CancelTxn `json:"txns"`
+}
+
+// CancelBetHandler membatalkan bet yang belum di-settle dan mengembalikan
+// stake sebesar creditAmount.
+func CancelBetHandler(c *fiber.Ctx) error {
+	var msg CancelBetMessage
+	if err := parse(c, &msg); err != nil || msg.UserID == "" || len(msg.Txns) == 0 {
+		return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+	}
+
+	return run(c, "CANCELBET", msg.OperationID, func(tx *gorm.DB) (int, any, error) {
+		user, err := findUser(tx, msg.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.StatusOK, fail(codeAccountNotFound, "Account Is Not Exist"), nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, t := range msg.Txns {
+			bet, err := lockBet(tx, user, t.RefID)
+			if err != nil {
+				return 0, nil, err
+			}
+			if bet == nil {
+				log.Printf("[SABA] username=%s ❌ CancelBet ticket not found refId=%s", user.UserCode, t.RefID)
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fail(codeNoSuchTicket, "No Such Ticket")}
+			}
+			if bet.Status != statusBet && bet.Status != statusConfirm {
+				log.Printf("[SABA] username=%s ⚠️ CancelBet skipped refId=%s status=%s", user.UserCode, t.RefID, bet.Status)
+				continue
+			}
+
+			if err := move(tx, &user, wallet.Cancel, t.CreditAmount, msg.OperationID, t.RefID, "SABA CancelBet "+t.RefID); err != nil {
+				return 0, nil, err
+			}
+			if err := move(tx, &user, wallet.Rollback, t.DebitAmount, msg.OperationID, t.RefID, "SABA CancelBet "+t.RefID); err != nil {
+				return 0, nil, err
+			}
+
+			bet.RefundAmount = toInternal(user, t.CreditAmount)
+			bet.OperationID = msg.OperationID
+			bet.BalanceAfter = user.Balance
+			bet.Status = statusCancel
+			if err := tx.Save(bet).Error; err != nil {
+				return 0, nil, err
+			}
+			if err := syncBet(tx, user, *bet); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		log.Printf("[SABA] username=%s ✅ CancelBet tickets=%d balance=%s", user.UserCode, len(msg.Txns), user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":  codeSuccess,
+			"msg":     nil,
+			"balance": displayBalance(user),
+		}, nil
+	})
+}
@@ -0,0 +1,101 @@
+package saba
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ConfirmTxn struct {
+	RefID         string          `json:"refId"`
+	TxID          json.Number     `json:"txId"`
+	Odds          decimal.Decimal `json:"odds"`
+	OddsType      json.Number     `json:"oddsType"`
+	ActualAmount  decimal.Decimal `json:"actualAmount"`
+	IsOddsChanged bool            `json:"isOddsChanged"`
+	CreditAmount  decimal.Decimal `json:"creditAmount"`
+	DebitAmount   decimal.Decimal `json:"debitAmount"`
+}
+
+type ConfirmBetMessage struct {
+	Action          string       `json:"action"`
+	OperationID     string       `json:"operationId"`
+	UserID          string       `json:"userId"`
+	UpdateTime      string       `json:"updateTime"`
+	TransactionTime string       `json:"transactionTime"`
+	Txns            []ConfirmTxn `json:"txns"`
+}
+
+// ConfirmBetHandler mengonfirmasi bet yang sudah di-debit. Kalau odds
+// berubah, SABA mengirim selisih stake lewat creditAmount/debitAmount.
+func ConfirmBetHandler(c *fiber.Ctx) error {
+	var msg ConfirmBetMessage
+	if err := parse(c, &msg); err != nil || msg.UserID == "" || len(msg.Txns) == 0 {
+		return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+	}
+
+	return run(c, "CONFIRMBET", msg.OperationID, func(tx *gorm.DB) (int, any, error) {
+		user, err := findUser(tx, msg.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.StatusOK, fail(codeAccountNotFound, "Account Is Not Exist"), nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, t := range msg.Txns {
+			bet, err := lockBet(tx, user, t.RefID)
+			if err != nil {
+				return 0, nil, err
+			}
+			if bet == nil {
+				log.Printf("[SABA] username=%s ❌ ConfirmBet ticket not found refId=%s", user.UserCode, t.RefID)
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fail(codeNoSuchTicket, "No Such Ticket")}
+			}
+			if bet.Status != statusBet {
+				log.Printf("[SABA] username=%s ⚠️ ConfirmBet skipped refId=%s status=%s", user.UserCode, t.RefID, bet.Status)
+				continue
+			}
+
+			if err := move(tx, &user, wallet.Debit, t.DebitAmount, msg.OperationID, t.RefID, "SABA ConfirmBet "+t.RefID); err != nil {
+				if resp, ok := walletFailure(err); ok {
+					return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: resp}
+				}
+				return 0, nil, err
+			}
+			if err := move(tx, &user, wallet.Cancel, t.CreditAmount, msg.OperationID, t.RefID, "SABA ConfirmBet "+t.RefID); err != nil {
+				return 0, nil, err
+			}
+
+			bet.BetAmount = bet.BetAmount.Add(toInternal(user, t.DebitAmount)).Sub(toInternal(user, t.CreditAmount))
+			bet.TxID = t.TxID.String()
+			bet.OperationID = msg.OperationID
+			bet.BalanceAfter = user.Balance
+			bet.Status = statusConfirm
+			if t.IsOddsChanged {
+				bet.Note = "odds " + t.Odds.String()
+			}
+			if err := tx.Save(bet).Error; err != nil {
+				return 0, nil, err
+			}
+			if err := syncBet(tx, user, *bet); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		log.Printf("[SABA] username=%s ✅ ConfirmBet tickets=%d balance=%s", user.UserCode, len(msg.Txns), user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":  codeSuccess,
+			"msg":     nil,
+			"balance": displayBalance(user),
+		}, nil
+	})
+}
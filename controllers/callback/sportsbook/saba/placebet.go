@@ -0,0 +1,194 @@
+package saba
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+
+	"telo/models"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type PlaceBetMessage struct {
+	Action       string          `json:"action"`
+	OperationID  string          `json:"operationId"`
+	UserID       string          `json:"userId"`
+	MatchID      json.Number     `json:"matchId"`
+	SportType    json.Number     `json:"sportType"`
+	BetType      json.Number     `json:"betType"`
+	OddsType     json.Number     `json:"oddsType"`
+	Odds         decimal.Decimal `json:"odds"`
+	BetAmount    decimal.Decimal `json:"betAmount"`
+	ActualAmount decimal.Decimal `json:"actualAmount"`
+	DebitAmount  decimal.Decimal `json:"debitAmount"`
+	CreditAmount decimal.Decimal `json:"creditAmount"`
+	RefID        string          `json:"refId"`
+	BetTime      string          `json:"betTime"`
+	IP           string          `json:"IP"`
+}
+
+type ParlayTxn struct {
+	RefID        string          `json:"refId"`
+	ParlayType   string          `json:"parlayType"`
+	BetAmount    decimal.Decimal `json:"betAmount"`
+	DebitAmount  decimal.Decimal `json:"debitAmount"`
+	CreditAmount decimal.Decimal `json:"creditAmount"`
+}
+
+type PlaceBetParlayMessage struct {
+	Action         string          `json:"action"`
+	OperationID    string          `json:"operationId"`
+	UserID         string          `json:"userId"`
+	BetTime        string          `json:"betTime"`
+	TotalBetAmount decimal.Decimal `json:"totalBetAmount"`
+	Txns           []ParlayTxn     `json:"txns"`
+	IP             string          `json:"IP"`
+}
+
+func PlaceBetHandler(c *fiber.Ctx) error {
+	var msg PlaceBetMessage
+	if err := parse(c, &msg); err != nil || msg.UserID == "" || msg.RefID == "" || msg.DebitAmount.IsNegative() {
+		return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+	}
+
+	return run(c, "PLACEBET", msg.OperationID, func(tx *gorm.DB) (int, any, error) {
+		user, err := findUser(tx, msg.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.StatusOK, fail(codeAccountNotFound, "Account Is Not Exist"), nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		existing, err := lockBet(tx, user, msg.RefID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if existing != nil {
+			log.Printf("[SABA] username=%s ⚠️ PlaceBet duplicate refId=%s", user.UserCode, msg.RefID)
+			return fiber.StatusOK, fail(codeDuplicate, "Duplicate Transaction"), nil
+		}
+
+		if err := move(tx, &user, wallet.Debit, msg.DebitAmount, msg.OperationID, msg.RefID, "SABA PlaceBet "+msg.RefID); err != nil {
+			if resp, ok := walletFailure(err); ok {
+				log.Printf("[SABA] username=%s ❌ PlaceBet rejected refId=%s: %v", user.UserCode, msg.RefID, err)
+				return fiber.StatusOK, resp, nil
+			}
+			return 0, nil, err
+		}
+
+		bet := newBet(user, msg.OperationID, msg.RefID, toInternal(user, msg.DebitAmount))
+		bet.GameID = msg.MatchID.String()
+		bet.BetType = msg.BetType.String()
+		bet.Market = msg.SportType.String()
+		bet.OddsType = msg.OddsType.String()
+		bet.Note = "odds " + msg.Odds.String()
+		if err := tx.Create(&bet).Error; err != nil {
+			return 0, nil, err
+		}
+		if err := syncBet(tx, user, bet); err != nil {
+			return 0, nil, err
+		}
+
+		log.Printf("[SABA] username=%s ✅ PlaceBet refId=%s debit=%s balance=%s",
+			user.UserCode, msg.RefID, msg.DebitAmount, user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status":       codeSuccess,
+			"msg":          nil,
+			"refId":        msg.RefID,
+			"licenseeTxId": strconv.FormatUint(uint64(bet.ID), 10),
+		}, nil
+	})
+}
+
+func PlaceBetParlayHandler(c *fiber.Ctx) error {
+	var msg PlaceBetParlayMessage
+	if err := parse(c, &msg); err != nil || msg.UserID == "" || len(msg.Txns) == 0 {
+		return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+	}
+	for _, t := range msg.Txns {
+		if t.RefID == "" || t.DebitAmount.IsNegative() {
+			return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+		}
+	}
+
+	return run(c, "PLACEBETPARLAY", msg.OperationID, func(tx *gorm.DB) (int, any, error) {
+		user, err := findUser(tx, msg.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.StatusOK, fail(codeAccountNotFound, "Account Is Not Exist"), nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// Semua ticket parlay diterima atau ditolak bersama.
+		txns := make([]fiber.Map, 0, len(msg.Txns))
+		for _, t := range msg.Txns {
+			existing, err := lockBet(tx, user, t.RefID)
+			if err != nil {
+				return 0, nil, err
+			}
+			if existing != nil {
+				log.Printf("[SABA] username=%s ⚠️ PlaceBetParlay duplicate refId=%s", user.UserCode, t.RefID)
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fail(codeDuplicate, "Duplicate Transaction")}
+			}
+
+			if err := move(tx, &user, wallet.Debit, t.DebitAmount, msg.OperationID, t.RefID, "SABA PlaceBetParlay "+t.RefID); err != nil {
+				if resp, ok := walletFailure(err); ok {
+					log.Printf("[SABA] username=%s ❌ PlaceBetParlay rejected refId=%s: %v", user.UserCode, t.RefID, err)
+					return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: resp}
+				}
+				return 0, nil, err
+			}
+
+			bet := newBet(user, msg.OperationID, t.RefID, toInternal(user, t.DebitAmount))
+			bet.BetType = "Parlay"
+			bet.Market = t.ParlayType
+			if err := tx.Create(&bet).Error; err != nil {
+				return 0, nil, err
+			}
+			if err := syncBet(tx, user, bet); err != nil {
+				return 0, nil, err
+			}
+
+			txns = append(txns, fiber.Map{
+				"refId":        t.RefID,
+				"licenseeTxId": strconv.FormatUint(uint64(bet.ID), 10),
+			})
+		}
+
+		log.Printf("[SABA] username=%s ✅ PlaceBetParlay tickets=%d total=%s balance=%s",
+			user.UserCode, len(msg.Txns), msg.TotalBetAmount, user.Balance)
+
+		return fiber.StatusOK, fiber.Map{
+			"status": codeSuccess,
+			"msg":    nil,
+			"txns":   txns,
+		}, nil
+	})
+}
+
+// newBet membuat baris bet baru; user.Balance sudah saldo setelah debit.
+func newBet(user models.User, operationID, refID string, stake decimal.Decimal) models.SabaTransaction {
+	return models.SabaTransaction{
+		UserID:        user.ID,
+		UserCode:      user.UserCode,
+		AgentCode:     user.AgentCode,
+		OperationID:   operationID,
+		Currency:      user.Currency,
+		BetAmount:     stake,
+		WinAmount:     decimal.Zero,
+		RefundAmount:  decimal.Zero,
+		BalanceBefore: user.Balance.Add(stake),
+		BalanceAfter:  user.Balance,
+		Status:        statusBet,
+		RefID:         refID,
+	}
+}
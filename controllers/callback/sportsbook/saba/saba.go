@@ -0,0 +1,188 @@
+// Package saba adalah seamless wallet SABA sportsbook. Semua request memakai
+// envelope {"key": vendorId, "message": {...}}; key divalidasi di
+// middlewares.SabaAuth. Setiap operasi yang mengubah saldo diproses sekali
+// per operationId lewat services/idempotency.
+package saba
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"telo/database"
+	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const provider = "SABA"
+
+// Kode status response SABA (string).
+const (
+	codeSuccess         = "0"
+	codeDuplicate       = "1"
+	codeBadParams       = "101"
+	codeAccountNotFound = "203"
+	codeAccountLocked   = "305"
+	codeInsufficient    = "502"
+	codeNoSuchTicket    = "504"
+	codeSystemError     = "999"
+)
+
+// Status SabaTransaction.
+const (
+	statusBet      = "BET"
+	statusConfirm  = "CONFIRM"
+	statusCancel   = "CANCEL"
+	statusSettle   = "SETTLE"
+	statusResettle = "RESETTLE"
+	statusUnsettle = "UNSETTLE"
+)
+
+type walletOp func(*gorm.DB, wallet.Request) (*wallet.Result, error)
+
+func fail(code, msg string) fiber.Map {
+	return fiber.Map{"status": code, "msg": msg}
+}
+
+// parse membaca isi "message" dari envelope SABA.
+func parse(c *fiber.Ctx, msg any) error {
+	var env struct {
+		Message json.RawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(c.Body(), &env); err != nil {
+		return err
+	}
+	if len(env.Message) == 0 {
+		return errors.New("message is required")
+	}
+	return json.Unmarshal(env.Message, msg)
+}
+
+// run memproses satu operasi SABA sekali per operationId. Penolakan per
+// ticket di operasi multi-ticket dikembalikan sebagai idempotency.Abort
+// supaya ticket lain di operasi yang sama ikut di-rollback.
+func run(c *fiber.Ctx, action, operationID string, fn func(tx *gorm.DB) (int, any, error)) error {
+	operationID = strings.TrimSpace(operationID)
+	if operationID == "" {
+		return c.JSON(fail(codeBadParams, "operationId is required"))
+	}
+
+	key := idempotency.Key{Provider: provider, Operation: action, ExternalID: operationID}
+	resp, err := idempotency.Do(database.DB, key, fn)
+	if err != nil {
+		log.Printf("[SABA] ❌ %s failed operationId=%s: %v", action, operationID, err)
+		return c.JSON(fail(codeSystemError, "System Error"))
+	}
+	if resp.Replayed {
+		log.Printf("[SABA] 🔁 %s replayed operationId=%s", action, operationID)
+	}
+	return resp.Send(c)
+}
+
+// getRate: saldo internal = nominal SABA * rate. SABA dibuka lewat 568Win,
+// jadi satuan tampilannya sama dengan SBO.
+func getRate(currency string) decimal.Decimal {
+	return gametx.SBORate(currency)
+}
+
+func toInternal(user models.User, amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(getRate(user.Currency))
+}
+
+func displayBalance(user models.User) decimal.Decimal {
+	return user.Balance.Div(getRate(user.Currency))
+}
+
+func findUser(tx *gorm.DB, userID string) (models.User, error) {
+	var user models.User
+	err := tx.Where("user_code = ?", strings.TrimSpace(userID)).First(&user).Error
+	return user, err
+}
+
+// lockBet mengunci bet user berdasarkan refId. Mengembalikan nil kalau
+// bet tidak ada.
+func lockBet(tx *gorm.DB, user models.User, refID string) (*models.SabaTransaction, error) {
+	var bet models.SabaTransaction
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_code = ? AND ref_id = ?", user.UserCode, refID).
+		First(&bet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bet, nil
+}
+
+// move menjalankan satu pergerakan saldo dengan nominal SABA. Nominal 0
+// dilewati. ExternalID operationId:refId, jadi debit/credit yang sama tidak
+// diproses dua kali walau satu operasi berisi beberapa ticket.
+func move(tx *gorm.DB, user *models.User, op walletOp, amount decimal.Decimal, operationID, refID, note string) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	res, err := op(tx, wallet.Request{
+		UserCode:   user.UserCode,
+		Provider:   provider,
+		ExternalID: operationID + ":" + refID,
+		Amount:     toInternal(*user, amount),
+		RefID:      refID,
+		Note:       note,
+	})
+	if err != nil {
+		return err
+	}
+	user.Balance = res.BalanceAfter
+	return nil
+}
+
+// walletFailure memetakan error wallet ke response SABA. ok = false kalau
+// err bukan penolakan bisnis.
+func walletFailure(err error) (fiber.Map, bool) {
+	switch {
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return fail(codeInsufficient, "Player Has Insufficient Funds"), true
+	case errors.Is(err, wallet.ErrUserInactive):
+		return fail(codeAccountLocked, "Account Is Locked"), true
+	case errors.Is(err, wallet.ErrUserNotFound):
+		return fail(codeAccountNotFound, "Account Is Not Exist"), true
+	}
+	return nil, false
+}
+
+// syncBet menyalin state bet ke UserGameTransaction dan GameRound. Satu
+// refId = satu round.
+func syncBet(tx *gorm.DB, user models.User, bet models.SabaTransaction) error {
+	row := gametx.FromSaba(user, bet)
+	if err := gametx.Sync(tx, row, false); err != nil {
+		return err
+	}
+
+	status := models.RoundStatusOpen
+	switch row.Status {
+	case gametx.StatusSettled:
+		status = models.RoundStatusSettled
+	case gametx.StatusRefund:
+		status = models.RoundStatusCancelled
+	}
+	return gameround.Sync(tx, gameround.Event{
+		User:     user,
+		Provider: provider,
+		RoundID:  bet.RefID,
+		GameID:   bet.GameID,
+		Bet:      row.BetAmount,
+		Win:      row.WinAmount,
+		Promo:    decimal.Zero,
+		Refund:   row.RefundAmount,
+	}, status)
+}
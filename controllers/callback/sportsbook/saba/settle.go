@@ -0,0 +1,151 @@
+package saba
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+
+	"telo/models"
+	"telo/services/idempotency"
+	"telo/services/wallet"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type SettleTxn struct {
+	UserID       string          `json:"userId"`
+	RefID        string          `json:"refId"`
+	TxID         json.Number     `json:"txId"`
+	UpdateTime   string          `json:"updateTime"`
+	WinlostDate  string          `json:"winlostDate"`
+	Status       string          `json:"status"`
+	ExtraStatus  string          `json:"extraStatus"`
+	Payout       decimal.Decimal `json:"payout"`
+	CreditAmount decimal.Decimal `json:"creditAmount"`
+	DebitAmount  decimal.Decimal `json:"debitAmount"`
+}
+
+// SettleMessage dipakai Settle, Resettle dan Unsettle. Satu operasi bisa
+// berisi ticket dari beberapa user.
+type SettleMessage struct {
+	Action      string      `json:"action"`
+	OperationID string      `json:"operationId"`
+	Txns        []SettleTxn `json:"txns"`
+}
+
+// settleRule: status bet yang boleh diproses, status hasilnya, dan apakah
+// payout dicatat sebagai win.
+type settleRule struct {
+	action string
+	from   []string
+	to     string
+	payout bool
+	note   string
+}
+
+var (
+	ruleSettle = settleRule{
+		action: "SETTLE",
+		from:   []string{statusBet, statusConfirm, statusUnsettle},
+		to:     statusSettle,
+		payout: true,
+		note:   "SABA Settle ",
+	}
+	ruleResettle = settleRule{
+		action: "RESETTLE",
+		from:   []string{statusSettle, statusResettle},
+		to:     statusResettle,
+		payout: true,
+		note:   "SABA Resettle ",
+	}
+	ruleUnsettle = settleRule{
+		action: "UNSETTLE",
+		from:   []string{statusSettle, statusResettle},
+		to:     statusUnsettle,
+		note:   "SABA Unsettle ",
+	}
+)
+
+func SettleHandler(c *fiber.Ctx) error   { return settle(c, ruleSettle) }
+func ResettleHandler(c *fiber.Ctx) error { return settle(c, ruleResettle) }
+func UnsettleHandler(c *fiber.Ctx) error { return settle(c, ruleUnsettle) }
+
+// settle menerapkan creditAmount (credit) dan debitAmount (rollback) tiap
+// ticket, lalu memindahkan status bet sesuai rule. Ticket yang statusnya
+// tidak cocok dilewati tanpa pergerakan saldo.
+func settle(c *fiber.Ctx, rule settleRule) error {
+	var msg SettleMessage
+	if err := parse(c, &msg); err != nil || len(msg.Txns) == 0 {
+		return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+	}
+	for _, t := range msg.Txns {
+		if t.UserID == "" || t.RefID == "" || t.CreditAmount.IsNegative() || t.DebitAmount.IsNegative() {
+			return c.JSON(fail(codeBadParams, "Parameter(s) Incorrect"))
+		}
+	}
+
+	return run(c, rule.action, msg.OperationID, func(tx *gorm.DB) (int, any, error) {
+		users := map[string]*models.User{}
+		for _, t := range msg.Txns {
+			user, ok := users[t.UserID]
+			if !ok {
+				u, err := findUser(tx, t.UserID)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fail(codeAccountNotFound, "Account Is Not Exist")}
+				}
+				if err != nil {
+					return 0, nil, err
+				}
+				user = &u
+				users[t.UserID] = user
+			}
+
+			bet, err := lockBet(tx, *user, t.RefID)
+			if err != nil {
+				return 0, nil, err
+			}
+			if bet == nil {
+				log.Printf("[SABA] username=%s ❌ %s ticket not found refId=%s", user.UserCode, rule.action, t.RefID)
+				return 0, nil, &idempotency.Abort{Status: fiber.StatusOK, Body: fail(codeNoSuchTicket, "No Such Ticket")}
+			}
+			if !slices.Contains(rule.from, bet.Status) {
+				log.Printf("[SABA] username=%s ⚠️ %s skipped refId=%s status=%s", user.UserCode, rule.action, t.RefID, bet.Status)
+				continue
+			}
+
+			if err := move(tx, user, wallet.Credit, t.CreditAmount, msg.OperationID, t.RefID, rule.note+t.RefID); err != nil {
+				return 0, nil, err
+			}
+			if err := move(tx, user, wallet.Rollback, t.DebitAmount, msg.OperationID, t.RefID, rule.note+t.RefID); err != nil {
+				return 0, nil, err
+			}
+
+			bet.WinAmount = decimal.Zero
+			if rule.payout {
+				bet.WinAmount = toInternal(*user, t.Payout)
+			}
+			if t.TxID != "" {
+				bet.TxID = t.TxID.String()
+			}
+			bet.OperationID = msg.OperationID
+			bet.BalanceAfter = user.Balance
+			bet.Status = rule.to
+			if t.Status != "" {
+				bet.Note = t.Status
+			}
+			if err := tx.Save(bet).Error; err != nil {
+				return 0, nil, err
+			}
+			if err := syncBet(tx, *user, *bet); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		log.Printf("[SABA] ✅ %s operationId=%s tickets=%d", rule.action, msg.OperationID, len(msg.Txns))
+
+		return fiber.StatusOK, fiber.Map{"status": codeSuccess, "msg": nil}, nil
+	})
+}
@@ -2,12 +2,14 @@ package sbo
 
 import (
 	"errors"
-	"math"
+	"log"
 	"strings"
 	"telo/database"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -53,8 +55,8 @@ func GetMemberBalanceHandler(c *fiber.Ctx) error {
 			return err
 		}
 
-		if err := normalizeAndPersist(tx, &user); err != nil {
-			return err
+		if err := wallet.RefreshBalance(tx, &user); err != nil {
+			log.Printf("[SBO] ⚠️ Failed to refresh seamless balance user=%s: %v", user.UserCode, err)
 		}
 
 		resp = fiber.Map{
@@ -80,50 +82,37 @@ func normalizeCurrency(s string) string {
 	return strings.ToUpper(strings.TrimSpace(s))
 }
 
-// Jumlah decimal tampilan berdasarkan mata uang
-func decimalsForCurrency(currency string) int {
-	switch normalizeCurrency(currency) {
-	case "IDR", "VND":
-		return 0
-	default:
-		return 2
-	}
-}
-
-func getRate(currency string) float64 {
+// getRate: saldo internal = saldo tampilan SBO * rate.
+func getRate(currency string) decimal.Decimal {
 	switch normalizeCurrency(currency) {
 	case "IDR", "VND":
-		return 1000
+		return decimal.NewFromInt(1000)
 	default:
-		return 1
+		return decimal.NewFromInt(1)
 	}
 }
 
-func roundTo(v float64, places int) float64 {
-	p := math.Pow(10, float64(places))
-	return math.Round(v*p) / p
+func displayBalanceWithCurrency(currency string, internalBalance decimal.Decimal) decimal.Decimal {
+	return internalBalance.Div(getRate(currency))
 }
 
-func roundInternalBalance(currency string, v float64) float64 {
-	return roundTo(v, decimalsForCurrency(currency))
+func convertToInternalValueWithCurrency(currency string, displayValue decimal.Decimal) decimal.Decimal {
+	return displayValue.Mul(getRate(currency))
 }
 
-func normalizeAndPersist(tx *gorm.DB, u *models.User) error {
-	before := u.Balance
-	u.Balance = roundInternalBalance(u.Currency, u.Balance)
-	if u.Balance > -1e-9 && u.Balance < 0 {
-		u.Balance = 0
-	}
-	if math.Abs(u.Balance-before) < 1e-9 {
-		return nil
+// moveBalance menjalankan perubahan saldo lewat services/wallet di dalam tx
+// handler, lalu menyalin saldo terbaru ke user yang sudah di-lock.
+func moveBalance(tx *gorm.DB, u *models.User, op func(*gorm.DB, wallet.Request) (*wallet.Result, error), amount decimal.Decimal, externalID, note string) error {
+	res, err := op(tx, wallet.Request{
+		UserCode:   u.UserCode,
+		Provider:   "SBO",
+		ExternalID: externalID,
+		Amount:     amount,
+		Note:       note,
+	})
+	if err != nil {
+		return err
 	}
-	return tx.Model(u).Where("id = ?", u.ID).Update("balance", u.Balance).Error
-}
-
-func displayBalanceWithCurrency(currency string, internalBalance float64) float64 {
-	return internalBalance / getRate(currency)
-}
-
-func convertToInternalValueWithCurrency(currency string, displayValue float64) float64 {
-	return displayValue * getRate(currency)
+	u.Balance = res.BalanceAfter
+	return nil
 }
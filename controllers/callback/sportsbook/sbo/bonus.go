@@ -8,27 +8,29 @@ import (
 
 	"telo/database"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 // FIX: Expanded request to include all fields from the CSV for complete data capture.
 type BonusCreditRequest struct {
-	CompanyKey              string         `json:"CompanyKey"`
-	Username                string         `json:"Username"`
-	TransferCode            string         `json:"TransferCode"`
-	TransactionId           string         `json:"TransactionId"` // Capture this for consistency
-	Amount                  float64        `json:"Amount"`
-	BonusTime               string         `json:"BonusTime"`
-	ProductType             int            `json:"ProductType"`
-	GameType                int            `json:"GameType"`
-	Gpid                    int            `json:"Gpid"`
-	GameId                  int            `json:"GameId"`
-	IsGameProviderPromotion bool           `json:"IsGameProviderPromotion"`
-	BonusProvider           string         `json:"BonusProvider"`
-	ExtraInfo               map[string]any `json:"ExtraInfo"`
+	CompanyKey              string          `json:"CompanyKey"`
+	Username                string          `json:"Username"`
+	TransferCode            string          `json:"TransferCode"`
+	TransactionId           string          `json:"TransactionId"` // Capture this for consistency
+	Amount                  decimal.Decimal `json:"Amount"`
+	BonusTime               string          `json:"BonusTime"`
+	ProductType             int             `json:"ProductType"`
+	GameType                int             `json:"GameType"`
+	Gpid                    int             `json:"Gpid"`
+	GameId                  int             `json:"GameId"`
+	IsGameProviderPromotion bool            `json:"IsGameProviderPromotion"`
+	BonusProvider           string          `json:"BonusProvider"`
+	ExtraInfo               map[string]any  `json:"ExtraInfo"`
 }
 
 // FIX: Added a dedicated request struct for canceling bonuses.
@@ -49,7 +51,7 @@ func BonusCreditHandler(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ErrorCode": 422, "ErrorMessage": "Invalid request format"})
 	}
-	if req.Username == "" || req.TransferCode == "" || req.Amount <= 0 {
+	if req.Username == "" || req.TransferCode == "" || !req.Amount.IsPositive() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"ErrorCode": 422, "ErrorMessage": "Username, TransferCode, and positive Amount are required"})
 	}
 	// Default ProductType for bonus if not provided
@@ -77,10 +79,8 @@ func BonusCreditHandler(c *fiber.Ctx) error {
 		}
 
 		// Apply bonus credit to the user's balance
-		rate := getRate(user.Currency)
-		credit := roundInternalBalance(user.Currency, req.Amount*rate)
-		user.Balance = roundInternalBalance(user.Currency, user.Balance+credit)
-		if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+		credit := convertToInternalValueWithCurrency(user.Currency, req.Amount)
+		if err := moveBalance(tx, &user, wallet.Credit, credit, req.TransferCode, "SBO Bonus "+req.TransferCode); err != nil {
 			return err
 		}
 
@@ -101,7 +101,7 @@ func BonusCreditHandler(c *fiber.Ctx) error {
 		trx := models.X568WinTransaction{
 			CompanyKey:    req.CompanyKey,
 			Username:      req.Username,
-			Amount:        0, // A bonus is not a stake, so Amount is 0.
+			Amount:        decimal.Zero, // A bonus is not a stake, so Amount is 0.
 			TransferCode:  req.TransferCode,
 			TransactionId: req.TransactionId,
 			ProductType:   req.ProductType,
@@ -117,6 +117,9 @@ func BonusCreditHandler(c *fiber.Ctx) error {
 			return err
 		}
 
+		if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+			return err
+		}
 		resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 		return nil
 	})
@@ -164,11 +167,9 @@ func CancelBonusHandler(c *fiber.Ctx) error {
 			return nil
 		case "Settled":
 			// This is the main path: reverse the bonus.
-			rate := getRate(user.Currency)
 			// The original bonus was stored in WinLoss, so we subtract it.
-			debit := roundInternalBalance(user.Currency, trx.WinLoss*rate)
-			user.Balance = roundInternalBalance(user.Currency, user.Balance-debit)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			debit := convertToInternalValueWithCurrency(user.Currency, trx.WinLoss)
+			if err := moveBalance(tx, &user, wallet.Rollback, debit, "", "SBO Cancel bonus "+req.TransferCode); err != nil {
 				return err
 			}
 
@@ -188,6 +189,9 @@ func CancelBonusHandler(c *fiber.Ctx) error {
 			return nil
 		}
 
+		if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+			return err
+		}
 		resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 		return nil
 	})
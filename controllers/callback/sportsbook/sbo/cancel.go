@@ -8,8 +8,10 @@ import (
 
 	"telo/database"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -54,10 +56,6 @@ func CancelBetHandler(c *fiber.Ctx) error {
 			return err
 		}
 
-		if err := normalizeAndPersist(tx, &user); err != nil {
-			return err
-		}
-
 		// Handle WM (ProductType 9) cancel for sub-bets
 		if req.ProductType == 9 {
 			// --- Validate input ---
@@ -101,7 +99,7 @@ func CancelBetHandler(c *fiber.Ctx) error {
 				// Check if it's a bonus-like transaction
 				var meta map[string]any
 				_ = json.Unmarshal(btrx.ExtraInfo, &meta)
-				isBonus := (btrx.Amount == 0 && btrx.WinLoss > 0) || (meta != nil && meta["bonus"] == true)
+				isBonus := (btrx.Amount.IsZero() && btrx.WinLoss.IsPositive()) || (meta != nil && meta["bonus"] == true)
 				if !isBonus {
 					resp = fiber.Map{"ErrorCode": 6, "ErrorMessage": "Bet Not Found",
 						"AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
@@ -115,10 +113,8 @@ func CancelBetHandler(c *fiber.Ctx) error {
 					return nil
 				case "Settled":
 					// Reverse credited bonus
-					rate := getRate(user.Currency)
-					debit := roundInternalBalance(user.Currency, btrx.WinLoss*rate)
-					user.Balance = roundInternalBalance(user.Currency, user.Balance-debit)
-					if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+					debit := convertToInternalValueWithCurrency(user.Currency, btrx.WinLoss)
+					if err := moveBalance(tx, &user, wallet.Rollback, debit, "", "SBO Cancel bonus "+req.TransferCode); err != nil {
 						return err
 					}
 					res := tx.Model(&btrx).Where("id = ? AND status = ?", btrx.ID, "Settled").Update("status", "Void")
@@ -130,6 +126,9 @@ func CancelBetHandler(c *fiber.Ctx) error {
 							"AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 						return nil
 					}
+					if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+						return err
+					}
 					resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 					return nil
 				default:
@@ -140,15 +139,15 @@ func CancelBetHandler(c *fiber.Ctx) error {
 			}
 
 			// Compute changes without mutating state
-			totalChange := 0.0
+			totalChange := decimal.Zero
 			for i := range bets {
 				b := &bets[i]
 				switch b.Status {
 				case "Running":
-					totalChange += b.Amount
+					totalChange = totalChange.Add(b.Amount)
 				case "Settled":
 					if req.IsCancelAll {
-						totalChange += (b.Amount - b.WinLoss)
+						totalChange = totalChange.Add(b.Amount.Sub(b.WinLoss))
 					} else {
 						resp = fiber.Map{"ErrorCode": 2001, "ErrorMessage": "Bet Already Settled",
 							"AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
@@ -181,13 +180,19 @@ func CancelBetHandler(c *fiber.Ctx) error {
 				}
 			}
 
-			if totalChange != 0 {
-				user.Balance = roundInternalBalance(user.Currency, user.Balance+totalChange)
-				if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			if !totalChange.IsZero() {
+				op := wallet.Cancel
+				if totalChange.IsNegative() {
+					op = wallet.Rollback
+				}
+				if err := moveBalance(tx, &user, op, totalChange.Abs(), "", "SBO WM Cancel "+req.TransferCode); err != nil {
 					return err
 				}
 			}
 
+			if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+				return err
+			}
 			resp = fiber.Map{
 				"ErrorCode":   0,
 				"AccountName": req.Username,
@@ -214,8 +219,7 @@ func CancelBetHandler(c *fiber.Ctx) error {
 		}
 
 		var balanceUpdated bool
-		var balanceChange float64
-		rate := getRate(user.Currency)
+		var balanceChange decimal.Decimal
 
 		switch trx.Status {
 		case "Void":
@@ -227,7 +231,7 @@ func CancelBetHandler(c *fiber.Ctx) error {
 			balanceUpdated = true
 
 		case "Settled":
-			balanceChange = trx.Amount - trx.WinLoss
+			balanceChange = trx.Amount.Sub(trx.WinLoss)
 			balanceUpdated = true
 
 		default:
@@ -236,9 +240,12 @@ func CancelBetHandler(c *fiber.Ctx) error {
 		}
 
 		if balanceUpdated {
-			refund := roundInternalBalance(user.Currency, balanceChange*rate)
-			user.Balance = roundInternalBalance(user.Currency, user.Balance+refund)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			refund := convertToInternalValueWithCurrency(user.Currency, balanceChange)
+			op := wallet.Cancel
+			if refund.IsNegative() {
+				op = wallet.Rollback
+			}
+			if err := moveBalance(tx, &user, op, refund.Abs(), "", "SBO Cancel "+req.TransferCode); err != nil {
 				return err
 			}
 		}
@@ -256,6 +263,9 @@ func CancelBetHandler(c *fiber.Ctx) error {
 			return nil
 		}
 
+		if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+			return err
+		}
 		resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 		return nil
 	})
@@ -4,46 +4,45 @@ package sbo
 import (
 	"encoding/json"
 	"errors"
-	"math"
 	"strings"
 	"time"
 
 	"telo/database"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type DeductRequest struct {
-	CompanyKey      string         `json:"CompanyKey"`
-	Username        string         `json:"Username"`
-	Amount          float64        `json:"Amount"`
-	TransferCode    string         `json:"TransferCode"`
-	TransactionId   string         `json:"TransactionId"`
-	BetTime         string         `json:"BetTime"`
-	ProductType     int            `json:"ProductType"`
-	GameType        int            `json:"GameType"`
-	GameRoundId     *string        `json:"GameRoundId"`
-	GamePeriodId    *string        `json:"GamePeriodId"`
-	OrderDetail     string         `json:"OrderDetail"`
-	PlayerIp        *string        `json:"PlayerIp"`
-	GameTypeName    *string        `json:"GameTypeName"`
-	Gpid            int            `json:"Gpid"`
-	GameId          int            `json:"GameId"`
-	ExtraInfo       map[string]any `json:"ExtraInfo"`
-	CommissionStake float64        `json:"CommissionStake"`
+	CompanyKey      string          `json:"CompanyKey"`
+	Username        string          `json:"Username"`
+	Amount          decimal.Decimal `json:"Amount"`
+	TransferCode    string          `json:"TransferCode"`
+	TransactionId   string          `json:"TransactionId"`
+	BetTime         string          `json:"BetTime"`
+	ProductType     int             `json:"ProductType"`
+	GameType        int             `json:"GameType"`
+	GameRoundId     *string         `json:"GameRoundId"`
+	GamePeriodId    *string         `json:"GamePeriodId"`
+	OrderDetail     string          `json:"OrderDetail"`
+	PlayerIp        *string         `json:"PlayerIp"`
+	GameTypeName    *string         `json:"GameTypeName"`
+	Gpid            int             `json:"Gpid"`
+	GameId          int             `json:"GameId"`
+	ExtraInfo       map[string]any  `json:"ExtraInfo"`
+	CommissionStake decimal.Decimal `json:"CommissionStake"`
 }
 
-const eps = 1e-6
-
 func isIncremental(pt int) bool {
 	return pt == 3 || pt == 7 || pt == 9
 }
 
-func betAmountForResponse(req DeductRequest) float64 {
-	if req.CommissionStake > 0 {
+func betAmountForResponse(req DeductRequest) decimal.Decimal {
+	if req.CommissionStake.IsPositive() {
 		return req.CommissionStake
 	}
 	return req.Amount
@@ -56,8 +55,8 @@ func parsedBetTime(s string) time.Time {
 	return time.Now()
 }
 
-func attachCommissionStake(info map[string]any, stake float64) map[string]any {
-	if stake <= 0 {
+func attachCommissionStake(info map[string]any, stake decimal.Decimal) map[string]any {
+	if !stake.IsPositive() {
 		return info
 	}
 	if info == nil {
@@ -67,14 +66,13 @@ func attachCommissionStake(info map[string]any, stake float64) map[string]any {
 	return info
 }
 
-func createNewTransaction(tx *gorm.DB, user *models.User, req *DeductRequest, rate float64) (fiber.Map, error) {
-	neededBalance := roundInternalBalance(user.Currency, req.Amount*rate)
-	if neededBalance > user.Balance+eps {
+func createNewTransaction(tx *gorm.DB, user *models.User, req *DeductRequest) (fiber.Map, error) {
+	neededBalance := convertToInternalValueWithCurrency(user.Currency, req.Amount)
+	if neededBalance.GreaterThan(user.Balance) {
 		return fiber.Map{"ErrorCode": 5, "ErrorMessage": "Insufficient balance", "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}, nil
 	}
 
-	user.Balance = roundInternalBalance(user.Currency, user.Balance-neededBalance)
-	if err := tx.Model(user).Update("balance", user.Balance).Error; err != nil {
+	if err := moveBalance(tx, user, wallet.Debit, neededBalance, req.TransferCode, "SBO Deduct "+req.TransferCode); err != nil {
 		return nil, err
 	}
 
@@ -98,13 +96,17 @@ func createNewTransaction(tx *gorm.DB, user *models.User, req *DeductRequest, ra
 		BetTime:       betTime,
 		ExtraInfo:     extraInfoJSON,
 		Status:        "Running",
-		WinLoss:       0,
+		WinLoss:       decimal.Zero,
 	}
 
 	if err := tx.Create(&newTx).Error; err != nil {
 		return nil, err
 	}
 
+	if err := syncGameTx(tx, *user, req.TransferCode); err != nil {
+		return nil, err
+	}
+
 	return fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "BetAmount": betAmountForResponse(*req), "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}, nil
 }
 
@@ -119,7 +121,7 @@ func DeductHandler(c *fiber.Ctx) error {
 
 	req.Username = strings.TrimSpace(req.Username)
 	req.TransferCode = strings.TrimSpace(req.TransferCode)
-	if req.Username == "" || req.TransferCode == "" || req.Amount <= 0 {
+	if req.Username == "" || req.TransferCode == "" || !req.Amount.IsPositive() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"ErrorCode":    422,
 			"ErrorMessage": "Username, TransferCode, and Amount are required",
@@ -137,13 +139,18 @@ func DeductHandler(c *fiber.Ctx) error {
 			return err
 		}
 
-		if err := normalizeAndPersist(tx, &user); err != nil {
-			return err
+		// Member yang di-suspend tidak boleh pasang bet baru.
+		if err := wallet.CheckActive(tx, user); err != nil {
+			if !errors.Is(err, wallet.ErrUserInactive) {
+				return err
+			}
+			resp = fiber.Map{"ErrorCode": 7, "ErrorMessage": "Member is suspended", "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
+			return nil
 		}
 
 		if req.ProductType == 9 {
 			// --- Validate input ---
-			if len(req.Username) == 0 || len(req.TransferCode) == 0 || len(req.TransactionId) == 0 || req.Amount <= 0 {
+			if len(req.Username) == 0 || len(req.TransferCode) == 0 || len(req.TransactionId) == 0 || !req.Amount.IsPositive() {
 				resp = fiber.Map{
 					"ErrorCode":    3,
 					"ErrorMessage": "Invalid request format",
@@ -183,7 +190,7 @@ func DeductHandler(c *fiber.Ctx) error {
 			// Convert amount to internal value and check balance
 			internalAmount := convertToInternalValueWithCurrency(user.Currency, req.Amount)
 
-			if user.Balance < internalAmount {
+			if user.Balance.LessThan(internalAmount) {
 				resp = fiber.Map{
 					"ErrorCode":    5,
 					"ErrorMessage": "Insufficient Balance",
@@ -212,11 +219,13 @@ func DeductHandler(c *fiber.Ctx) error {
 			}
 
 			// Update user balance
-			user.Balance = roundInternalBalance(user.Currency, user.Balance-internalAmount)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			if err := moveBalance(tx, &user, wallet.Debit, internalAmount, req.TransactionId, "SBO WM Deduct "+req.TransferCode); err != nil {
 				return err
 			}
 
+			if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+				return err
+			}
 			resp = fiber.Map{
 				"ErrorCode":   0,
 				"AccountName": req.Username,
@@ -232,8 +241,7 @@ func DeductHandler(c *fiber.Ctx) error {
 			First(&trx).Error
 
 		if errors.Is(findErr, gorm.ErrRecordNotFound) {
-			rate := getRate(user.Currency)
-			newResp, err := createNewTransaction(tx, &user, &req, rate)
+			newResp, err := createNewTransaction(tx, &user, &req)
 			if err != nil {
 				return err
 			}
@@ -253,7 +261,7 @@ func DeductHandler(c *fiber.Ctx) error {
 		if isIncremental(req.ProductType) {
 
 			if isIncremental(req.ProductType) {
-				if math.Abs(req.Amount-trx.Amount) <= eps {
+				if req.Amount.Equal(trx.Amount) {
 					resp = fiber.Map{
 						"ErrorCode":   0,
 						"AccountName": req.Username,
@@ -263,7 +271,7 @@ func DeductHandler(c *fiber.Ctx) error {
 					return nil
 				}
 
-				if req.Amount < trx.Amount-eps {
+				if req.Amount.LessThan(trx.Amount) {
 					resp = fiber.Map{
 						"ErrorCode":    7,
 						"ErrorMessage": "Amount lower than previous for same TransferCode",
@@ -272,10 +280,9 @@ func DeductHandler(c *fiber.Ctx) error {
 					return nil
 				}
 
-				rate := getRate(user.Currency)
-				diff := roundInternalBalance(user.Currency, (req.Amount-trx.Amount)*rate)
+				diff := convertToInternalValueWithCurrency(user.Currency, req.Amount.Sub(trx.Amount))
 
-				if diff > user.Balance+eps {
+				if diff.GreaterThan(user.Balance) {
 					resp = fiber.Map{
 						"ErrorCode":    5,
 						"ErrorMessage": "Insufficient balance",
@@ -284,14 +291,17 @@ func DeductHandler(c *fiber.Ctx) error {
 					return nil
 				}
 
-				user.Balance = roundInternalBalance(user.Currency, user.Balance-diff)
-				if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+				incrementID := req.TransferCode + ":" + req.Amount.String()
+				if err := moveBalance(tx, &user, wallet.Debit, diff, incrementID, "SBO Deduct increment "+req.TransferCode); err != nil {
 					return err
 				}
 				if err := tx.Model(&trx).Update("amount", req.Amount).Error; err != nil {
 					return err
 				}
 
+				if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+					return err
+				}
 				resp = fiber.Map{
 					"ErrorCode":   0,
 					"AccountName": req.Username,
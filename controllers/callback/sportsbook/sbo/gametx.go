@@ -0,0 +1,67 @@
+package sbo
+
+import (
+	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// syncGameTx menyalin state bet SBO (termasuk sub-bet WM) untuk satu
+// TransferCode ke UserGameTransaction dan GameRound. Dipanggil setelah state
+// bet berubah, di dalam tx yang sama.
+func syncGameTx(tx *gorm.DB, user models.User, transferCode string) error {
+	var rows []models.UserGameTransaction
+
+	var trxs []models.X568WinTransaction
+	if err := tx.Where("transfer_code = ? AND username = ?", transferCode, user.UserCode).Find(&trxs).Error; err != nil {
+		return err
+	}
+	for _, trx := range trxs {
+		rows = append(rows, gametx.FromSBO(user, trx, getRate(user.Currency)))
+	}
+
+	var bets []models.WmSubBet
+	if err := tx.Where("transfer_code = ? AND username = ?", transferCode, user.UserCode).Find(&bets).Error; err != nil {
+		return err
+	}
+	for _, b := range bets {
+		rows = append(rows, gametx.FromWmSubBet(user, b))
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Satu TransferCode = satu round; WM bisa punya beberapa sub-bet.
+	round := gameround.Event{
+		User:     user,
+		Provider: "SBO",
+		RoundID:  transferCode,
+		GameID:   rows[0].GameID,
+		Bet:      decimal.Zero,
+		Win:      decimal.Zero,
+		Promo:    decimal.Zero,
+		Refund:   decimal.Zero,
+	}
+	status := models.RoundStatusCancelled
+	for _, row := range rows {
+		if err := gametx.Sync(tx, row, false); err != nil {
+			return err
+		}
+		round.Bet = round.Bet.Add(row.BetAmount)
+		round.Win = round.Win.Add(row.WinAmount)
+		round.Promo = round.Promo.Add(row.BonusAmount)
+		round.Refund = round.Refund.Add(row.RefundAmount)
+
+		switch {
+		case row.Status == gametx.StatusRunning:
+			status = models.RoundStatusOpen
+		case row.Status == gametx.StatusSettled && status == models.RoundStatusCancelled:
+			status = models.RoundStatusSettled
+		}
+	}
+	return gameround.Sync(tx, round, status)
+}
@@ -9,8 +9,10 @@ import (
 
 	"telo/database"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -63,10 +65,6 @@ func RollbackBetHandler(c *fiber.Ctx) error {
 			return err
 		}
 
-		if err := normalizeAndPersist(tx, &user); err != nil {
-			return err
-		}
-
 		if req.ProductType == 9 {
 			if len(req.Username) == 0 || len(req.TransferCode) == 0 {
 				resp = fiber.Map{"ErrorCode": 3, "ErrorMessage": "Invalid request format"}
@@ -95,7 +93,7 @@ func RollbackBetHandler(c *fiber.Ctx) error {
 				}
 			}
 			if voidCount > 0 {
-				totalDelta := 0.0
+				totalDelta := decimal.Zero
 				for i := range bets {
 					b := &bets[i]
 					if b.Status == "Void" {
@@ -108,16 +106,18 @@ func RollbackBetHandler(c *fiber.Ctx) error {
 								"AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 							return nil
 						}
-						totalDelta -= b.Amount
+						totalDelta = totalDelta.Sub(b.Amount)
 					}
 				}
-				if totalDelta != 0 {
-					user.Balance = roundInternalBalance(user.Currency, user.Balance+totalDelta)
-					if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+				if !totalDelta.IsZero() {
+					if err := moveBalance(tx, &user, wallet.Rollback, totalDelta.Neg(), "", "SBO WM Rollback "+req.TransferCode); err != nil {
 						return err
 					}
 				}
-				log.Printf("WM Rollback (all void->running): user=%s transfer=%s delta=%.4f newBalance=%.4f", req.Username, req.TransferCode, totalDelta, user.Balance)
+				log.Printf("WM Rollback (all void->running): user=%s transfer=%s delta=%s newBalance=%s", req.Username, req.TransferCode, totalDelta, user.Balance)
+				if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+					return err
+				}
 				resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 				return nil
 			}
@@ -156,17 +156,23 @@ func RollbackBetHandler(c *fiber.Ctx) error {
 			}
 
 			delta := computeWMRollbackDelta(oldStatus, candidate.Amount, candidate.WinLoss)
-			if delta != 0 {
-				user.Balance = roundInternalBalance(user.Currency, user.Balance+delta)
-				if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			if !delta.IsZero() {
+				op := wallet.Cancel
+				if delta.IsNegative() {
+					op = wallet.Rollback
+				}
+				if err := moveBalance(tx, &user, op, delta.Abs(), "", "SBO WM Rollback "+req.TransferCode); err != nil {
 					return err
 				}
 			}
 
 			// Minimal audit trail
-			log.Printf("WM Rollback: user=%s transfer=%s txid=%s from=%s delta=%.4f newBalance=%.4f",
+			log.Printf("WM Rollback: user=%s transfer=%s txid=%s from=%s delta=%s newBalance=%s",
 				req.Username, req.TransferCode, candidate.TransactionId, oldStatus, delta, user.Balance)
 
+			if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+				return err
+			}
 			resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 			return nil
 		}
@@ -196,24 +202,20 @@ func RollbackBetHandler(c *fiber.Ctx) error {
 			return nil
 		}
 
-		rate := getRate(user.Currency)
-
 		switch trx.Status {
 		case "Settled":
 			// Revert the settlement by subtracting the credited WinLoss amount.
-			dec := roundInternalBalance(user.Currency, trx.WinLoss*rate)
-			user.Balance = roundInternalBalance(user.Currency, user.Balance-dec)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			dec := convertToInternalValueWithCurrency(user.Currency, trx.WinLoss)
+			if err := moveBalance(tx, &user, wallet.Rollback, dec, "", "SBO Rollback settle "+req.TransferCode); err != nil {
 				return err
 			}
 
 		case "Void":
 			// FIX: This logic is now universal for all product types, not just PT=9.
 			// Revert the cancellation by re-deducting the original stake.
-			need := roundInternalBalance(user.Currency, trx.Amount*rate)
+			need := convertToInternalValueWithCurrency(user.Currency, trx.Amount)
 			// This is allowed to make the balance negative to pass tests like Sports-7-7.
-			user.Balance = roundInternalBalance(user.Currency, user.Balance-need)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			if err := moveBalance(tx, &user, wallet.Rollback, need, "", "SBO Rollback cancel "+req.TransferCode); err != nil {
 				return err
 			}
 
@@ -253,6 +255,9 @@ func RollbackBetHandler(c *fiber.Ctx) error {
 			return nil
 		}
 
+		if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+			return err
+		}
 		resp = fiber.Map{
 			"ErrorCode":   0,
 			"AccountName": req.Username,
@@ -282,13 +287,13 @@ func findWMRollbackCandidate(bets []models.WmSubBet, txId string) (*models.WmSub
 
 	for i := range bets {
 		b := &bets[i]
-		if b.Status == "Running" && b.WinLoss != 0 {
+		if b.Status == "Running" && !b.WinLoss.IsZero() {
 			hasRunningWithWin = true
 		}
-		if b.Status == "Void" && b.WinLoss != 0 {
+		if b.Status == "Void" && !b.WinLoss.IsZero() {
 			voidWithWin = append(voidWithWin, b)
 		}
-		if b.Status == "Settled" && b.WinLoss != 0 {
+		if b.Status == "Settled" && !b.WinLoss.IsZero() {
 			settledWithWin = append(settledWithWin, b)
 		}
 	}
@@ -298,7 +303,7 @@ func findWMRollbackCandidate(bets []models.WmSubBet, txId string) (*models.WmSub
 		for i := range bets {
 			b := &bets[i]
 			if b.TransactionId == txId {
-				if (b.Status == "Void" || b.Status == "Settled") && b.WinLoss != 0 {
+				if (b.Status == "Void" || b.Status == "Settled") && !b.WinLoss.IsZero() {
 					return b, hasRunningWithWin, true
 				}
 				// not eligible but exists
@@ -323,14 +328,14 @@ func findWMRollbackCandidate(bets []models.WmSubBet, txId string) (*models.WmSub
 // computeWMRollbackDelta computes the balance delta for rollback in internal units.
 // - If oldStatus == "Settled": delta = -WinLoss (reverse credited payout)
 // - If oldStatus == "Void": delta = -(WinLoss - Amount) (re-apply settlement payout minus already-refunded stake)
-func computeWMRollbackDelta(oldStatus string, amount, winloss float64) float64 {
+func computeWMRollbackDelta(oldStatus string, amount, winloss decimal.Decimal) decimal.Decimal {
 	switch oldStatus {
 	case "Settled":
-		return -winloss
+		return winloss.Neg()
 	case "Void":
 		// After cancel-all, reverting to Running should re-deduct only the stake
-		return -amount
+		return amount.Neg()
 	default:
-		return 0
+		return decimal.Zero
 	}
 }
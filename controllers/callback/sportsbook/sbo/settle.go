@@ -9,27 +9,29 @@ import (
 
 	"telo/database"
 	"telo/models"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type SettleRequest struct {
-	CompanyKey      string         `json:"CompanyKey"`
-	Username        string         `json:"Username"`
-	TransferCode    string         `json:"TransferCode"`
-	TransactionId   string         `json:"TransactionId"` // 🔑 khusus ProductType=9 (WM)
-	WinLoss         float64        `json:"WinLoss"`
-	ResultType      int            `json:"ResultType"` // 0: Win, 1: Lose, 2: Draw/Refund
-	ResultTime      string         `json:"ResultTime"`
-	ProductType     int            `json:"ProductType"`
-	GameType        int            `json:"GameType"`
-	GameResult      *string        `json:"GameResult"`
-	CommissionStake float64        `json:"CommissionStake"`
-	Gpid            int            `json:"Gpid"`
-	IsCashOut       bool           `json:"IsCashOut"`
-	ExtraInfo       map[string]any `json:"ExtraInfo"`
+	CompanyKey      string          `json:"CompanyKey"`
+	Username        string          `json:"Username"`
+	TransferCode    string          `json:"TransferCode"`
+	TransactionId   string          `json:"TransactionId"` // 🔑 khusus ProductType=9 (WM)
+	WinLoss         decimal.Decimal `json:"WinLoss"`
+	ResultType      int             `json:"ResultType"` // 0: Win, 1: Lose, 2: Draw/Refund
+	ResultTime      string          `json:"ResultTime"`
+	ProductType     int             `json:"ProductType"`
+	GameType        int             `json:"GameType"`
+	GameResult      *string         `json:"GameResult"`
+	CommissionStake decimal.Decimal `json:"CommissionStake"`
+	Gpid            int             `json:"Gpid"`
+	IsCashOut       bool            `json:"IsCashOut"`
+	ExtraInfo       map[string]any  `json:"ExtraInfo"`
 }
 
 func normalizeRFC3339(s string) string {
@@ -75,10 +77,6 @@ func SettleHandler(c *fiber.Ctx) error {
 			return err
 		}
 
-		if err := normalizeAndPersist(tx, &user); err != nil {
-			return err
-		}
-
 		if req.ProductType == 9 {
 			if len(req.Username) == 0 || len(req.TransferCode) == 0 {
 				resp = fiber.Map{"ErrorCode": 3, "ErrorMessage": "Invalid request format"}
@@ -181,11 +179,17 @@ func SettleHandler(c *fiber.Ctx) error {
 				return nil
 			}
 
-			user.Balance = roundInternalBalance(user.Currency, user.Balance+newWinLoss)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			op := wallet.Credit
+			if newWinLoss.IsNegative() {
+				op = wallet.Rollback
+			}
+			if err := moveBalance(tx, &user, op, newWinLoss.Abs(), "", "SBO WM Settle "+req.TransferCode); err != nil {
 				return err
 			}
 
+			if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+				return err
+			}
 			resp = fiber.Map{
 				"ErrorCode":    0,
 				"AccountName":  req.Username,
@@ -219,7 +223,7 @@ func SettleHandler(c *fiber.Ctx) error {
 			return nil
 		}
 
-		var creditAmount float64
+		var creditAmount decimal.Decimal
 		if req.IsCashOut {
 			creditAmount = req.WinLoss
 		} else {
@@ -227,20 +231,18 @@ func SettleHandler(c *fiber.Ctx) error {
 			case 0: // Win: Credit the total payout (WinLoss)
 				creditAmount = req.WinLoss
 			case 1: // Lose: No credit is given (stake is lost).
-				creditAmount = 0
+				creditAmount = decimal.Zero
 			case 2: // Draw/Refund: Credit the original stake back to the player.
 				creditAmount = trx.Amount
 			default:
 				// Handle other potential result types if they exist.
-				creditAmount = 0
+				creditAmount = decimal.Zero
 			}
 		}
 
-		if creditAmount > 0 {
-			rate := getRate(user.Currency)
-			inc := roundInternalBalance(user.Currency, creditAmount*rate)
-			user.Balance = roundInternalBalance(user.Currency, user.Balance+inc)
-			if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+		if creditAmount.IsPositive() {
+			inc := convertToInternalValueWithCurrency(user.Currency, creditAmount)
+			if err := moveBalance(tx, &user, wallet.Credit, inc, "", "SBO Settle "+req.TransferCode); err != nil {
 				return err
 			}
 		}
@@ -281,6 +283,9 @@ func SettleHandler(c *fiber.Ctx) error {
 			return nil
 		}
 
+		if err := syncGameTx(tx, user, req.TransferCode); err != nil {
+			return err
+		}
 		resp = fiber.Map{"ErrorCode": 0, "AccountName": req.Username, "Balance": displayBalanceWithCurrency(user.Currency, user.Balance)}
 		return nil
 	})
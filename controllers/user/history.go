@@ -0,0 +1,201 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ListUserTransactionsRequest struct {
+	helpers.PageRequest
+	UserCode string `json:"user_code"`
+	Type     string `json:"type"`
+	RefID    string `json:"ref_id"`
+}
+
+type GameHistoryRequest struct {
+	helpers.PageRequest
+	UserCode string `json:"user_code"`
+	Provider string `json:"provider"`
+	GameID   string `json:"game_id"`
+	Status   string `json:"status"`
+}
+
+type GameRoundsRequest struct {
+	helpers.PageRequest
+	UserCode string `json:"user_code"`
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+}
+
+func paginationError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, helpers.ErrInvalidCursor) {
+		return helpers.JSONError(c, "INVALID_CURSOR")
+	}
+	return helpers.JSONError(c, "INVALID_DATE_RANGE")
+}
+
+// ListUserTransactions: deposit/withdraw user milik agent yang login.
+func ListUserTransactions(c *fiber.Ctx) error {
+	var req ListUserTransactionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	q := database.DB.Model(&models.UserTransaction{}).Where("agent_code = ?", agent.AgentCode)
+	if req.UserCode != "" {
+		q = q.Where("user_code = ?", req.UserCode)
+	}
+	if req.Type != "" {
+		q = q.Where("trx_type = ?", req.Type)
+	}
+	if req.RefID != "" {
+		q = q.Where("ref_id = ?", req.RefID)
+	}
+
+	q, limit, err := helpers.Paginate(q, req.PageRequest)
+	if err != nil {
+		return paginationError(c, err)
+	}
+
+	var rows []models.UserTransaction
+	if err := q.Find(&rows).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_TRANSACTIONS")
+	}
+	rows, next := helpers.PageResult(rows, limit, func(t models.UserTransaction) uint { return t.ID })
+
+	items := make([]fiber.Map, 0, len(rows))
+	for _, t := range rows {
+		items = append(items, fiber.Map{
+			"id":             t.ID,
+			"user_code":      t.UserCode,
+			"trx_type":       t.TrxType,
+			"amount":         t.Amount,
+			"balance_before": t.BalanceBefore,
+			"balance_after":  t.BalanceAfter,
+			"currency":       t.Currency,
+			"note":           t.Note,
+			"ref_id":         t.RefID,
+			"created_at":     t.CreatedAt,
+		})
+	}
+
+	return helpers.JSONSuccess(c, "Transactions retrieved successfully", fiber.Map{
+		"items":       items,
+		"next_cursor": next,
+	})
+}
+
+// GameHistory: riwayat bet/win per round untuk user milik agent yang login.
+func GameHistory(c *fiber.Ctx) error {
+	var req GameHistoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	q := database.DB.Model(&models.UserGameTransaction{}).Where("agent_code = ?", agent.AgentCode)
+	if req.UserCode != "" {
+		q = q.Where("user_code = ?", req.UserCode)
+	}
+	if req.Provider != "" {
+		q = q.Where("provider = ?", strings.ToUpper(req.Provider))
+	}
+	if req.GameID != "" {
+		q = q.Where("game_id = ?", req.GameID)
+	}
+	if req.Status != "" {
+		q = q.Where("status = ?", req.Status)
+	}
+
+	q, limit, err := helpers.Paginate(q, req.PageRequest)
+	if err != nil {
+		return paginationError(c, err)
+	}
+
+	var rows []models.UserGameTransaction
+	if err := q.Find(&rows).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_GAME_HISTORY")
+	}
+	rows, next := helpers.PageResult(rows, limit, func(t models.UserGameTransaction) uint { return t.ID })
+
+	items := make([]fiber.Map, 0, len(rows))
+	for _, t := range rows {
+		items = append(items, fiber.Map{
+			"id":             t.ID,
+			"user_code":      t.UserCode,
+			"provider":       t.Provider,
+			"game_id":        t.GameID,
+			"provider_tx":    t.ProviderTx,
+			"bet_amount":     t.BetAmount,
+			"win_amount":     t.WinAmount,
+			"bonus_amount":   t.BonusAmount,
+			"currency":       t.Currency,
+			"balance_before": t.BalanceBefore,
+			"balance_after":  t.BalanceAfter,
+			"status":         t.Status,
+			"ref_id":         t.RefID,
+			"created_at":     t.CreatedAt,
+			"updated_at":     t.UpdatedAt,
+		})
+	}
+
+	return helpers.JSONSuccess(c, "Game history retrieved successfully", fiber.Map{
+		"items":       items,
+		"next_cursor": next,
+	})
+}
+
+// GameRounds: ringkasan per round (bet/win/jackpot/promo/refund) untuk user
+// milik agent yang login.
+func GameRounds(c *fiber.Ctx) error {
+	var req GameRoundsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	q := database.DB.Model(&models.GameRound{}).Where("agent_code = ?", agent.AgentCode)
+	if req.UserCode != "" {
+		q = q.Where("user_code = ?", req.UserCode)
+	}
+	if req.Provider != "" {
+		q = q.Where("provider = ?", strings.ToUpper(req.Provider))
+	}
+	if req.Status != "" {
+		q = q.Where("status = ?", req.Status)
+	}
+
+	q, limit, err := helpers.Paginate(q, req.PageRequest)
+	if err != nil {
+		return paginationError(c, err)
+	}
+
+	var rows []models.GameRound
+	if err := q.Find(&rows).Error; err != nil {
+		return helpers.JSONError(c, "FAILED_TO_FETCH_GAME_ROUNDS")
+	}
+	rows, next := helpers.PageResult(rows, limit, func(r models.GameRound) uint { return r.ID })
+
+	return helpers.JSONSuccess(c, "Game rounds retrieved successfully", fiber.Map{
+		"items":       rows,
+		"next_cursor": next,
+	})
+}
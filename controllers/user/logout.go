@@ -0,0 +1,100 @@
+package user
+
+import (
+	"strings"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"telo/services/evosession"
+	"telo/services/launchtoken"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LogoutRequest struct {
+	UserCode string `json:"user_code"`
+}
+
+// LogoutUser: agent memberi tahu bahwa player logout. Semua launch token dan
+// session Evolution user dicabut, jadi callback provider dengan token lama
+// ditolak.
+func LogoutUser(c *fiber.Ctx) error {
+	var req LogoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+	if req.UserCode == "" {
+		return helpers.JSONError(c, "USER_CODE_REQUIRED")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	var user models.User
+	if err := database.DB.Where("user_code = ? AND agent_code = ?", req.UserCode, agent.AgentCode).
+		First(&user).Error; err != nil {
+		return helpers.JSONError(c, "USER_NOT_FOUND_OR_UNAUTHORIZED")
+	}
+
+	revoked, err := launchtoken.RevokeUser(database.DB, user.ID)
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_REVOKE_TOKENS")
+	}
+	sessions, err := evosession.RevokeUser(database.DB, user.ID, "")
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_REVOKE_SESSIONS")
+	}
+
+	return helpers.JSONSuccess(c, "User logged out successfully", fiber.Map{
+		"user_code":        user.UserCode,
+		"revoked_tokens":   revoked,
+		"revoked_sessions": sessions,
+	})
+}
+
+type RevokeSessionsRequest struct {
+	UserCode string `json:"user_code"`
+	// Provider: evolution_slot, evolution_live, atau kosong untuk semua.
+	Provider string `json:"provider"`
+}
+
+// RevokeSessions mencabut session Evolution user yang masih aktif. Callback
+// check/balance/debit dengan SID tersebut langsung ditolak.
+func RevokeSessions(c *fiber.Ctx) error {
+	var req RevokeSessionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+	if req.UserCode == "" {
+		return helpers.JSONError(c, "USER_CODE_REQUIRED")
+	}
+
+	provider := strings.ToUpper(req.Provider)
+	if provider != "" && provider != evosession.ProviderSlot && provider != evosession.ProviderLive {
+		return helpers.JSONError(c, "INVALID_PROVIDER")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	var user models.User
+	if err := database.DB.Where("user_code = ? AND agent_code = ?", req.UserCode, agent.AgentCode).
+		First(&user).Error; err != nil {
+		return helpers.JSONError(c, "USER_NOT_FOUND_OR_UNAUTHORIZED")
+	}
+
+	revoked, err := evosession.RevokeUser(database.DB, user.ID, provider)
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_REVOKE_SESSIONS")
+	}
+
+	return helpers.JSONSuccess(c, "Sessions revoked successfully", fiber.Map{
+		"user_code":        user.UserCode,
+		"provider":         provider,
+		"revoked_sessions": revoked,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"telo/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 )
 
 type RegisterUserRequest struct {
@@ -70,7 +71,7 @@ func RegisterUser(c *fiber.Ctx) error {
 		AgentCode: agent.AgentCode,
 		Country:   countryKey,
 		Currency:  currency,
-		Balance:   0,
+		Balance:   decimal.Zero,
 		IsActive:  true,
 	}
 
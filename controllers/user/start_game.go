@@ -1,9 +1,13 @@
 package user
 
 import (
+	"log"
 	"strings"
+	"telo/database"
 	"telo/helpers"
+	"telo/models"
 	"telo/providers"
+	"telo/services/agentsettings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,14 +19,65 @@ func LaunchGameHandler(c *fiber.Ctx) error {
 		return helpers.JSONError(c, "INVALID_JSON")
 	}
 
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	ip := req.IP
+	if ip == "" {
+		ip = c.IP()
+	}
+	audit := models.LaunchAudit{
+		AgentID:      agent.ID,
+		AgentCode:    agent.AgentCode,
+		UserCode:     req.UserCode,
+		ProviderCode: req.ProviderCode,
+		GameCode:     req.GameCode,
+		IP:           ip,
+		Platform:     req.Platform,
+	}
+	// Detail error (URL upstream, error DB, payload provider) hanya disimpan
+	// di audit; agent cukup menerima kodenya.
+	fail := func(result, detail string) error {
+		audit.Result = result
+		audit.Error = detail
+		saveLaunchAudit(&audit)
+		return helpers.JSONError(c, result)
+	}
+
+	if !agent.IsActive {
+		return fail("AGENT_INACTIVE", "")
+	}
+
 	launcher := providers.GetProvider(req.ProviderCode)
 	if launcher == nil {
-		return helpers.JSONError(c, "UNSUPPORTED_PROVIDER")
+		return fail("UNSUPPORTED_PROVIDER", "")
+	}
+
+	// User harus milik agent yang login dan masih aktif.
+	var user models.User
+	if err := database.DB.Where("user_code = ?", req.UserCode).First(&user).Error; err != nil {
+		return fail("USER_NOT_FOUND", "")
+	}
+	if user.AgentCode != agent.AgentCode {
+		return fail("USER_NOT_OWNED_BY_AGENT", "")
+	}
+	if !user.IsActive {
+		return fail("USER_INACTIVE", "")
+	}
+
+	enabled, err := agentsettings.IsProviderEnabled(database.DB, agent.ID, req.ProviderCode)
+	if err != nil {
+		return fail("FAILED_TO_CHECK_PROVIDER", err.Error())
+	}
+	if !enabled {
+		return fail("PROVIDER_DISABLED_FOR_AGENT", "")
 	}
 
 	launchURL, err := launcher.StartGame(req)
 	if err != nil {
-		return helpers.JSONError(c, "FAILED_TO_START_GAME: "+err.Error())
+		return fail("FAILED_TO_START_GAME", err.Error())
 	}
 
 	// 🔧 Normalisasi supaya selalu pakai https://
@@ -30,7 +85,17 @@ func LaunchGameHandler(c *fiber.Ctx) error {
 		launchURL = "https:" + launchURL
 	}
 
+	audit.Result = "SUCCESS"
+	saveLaunchAudit(&audit)
+
 	return helpers.JSONSuccess(c, "Game launched successfully", fiber.Map{
 		"launch_url": launchURL,
 	})
 }
+
+// saveLaunchAudit tidak menggagalkan launch kalau audit gagal disimpan.
+func saveLaunchAudit(audit *models.LaunchAudit) {
+	if err := database.DB.Create(audit).Error; err != nil {
+		log.Printf("[LAUNCH] ❌ Failed to save launch audit user=%s provider=%s: %v", audit.UserCode, audit.ProviderCode, err)
+	}
+}
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"errors"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"telo/services/agentwallet"
+	"telo/services/evosession"
+	"telo/services/launchtoken"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserStatusRequest struct {
+	UserCode string `json:"user_code"`
+	Reason   string `json:"reason"`
+}
+
+var (
+	errUserNotFound   = errors.New("user not found")
+	errUserClosed     = errors.New("user closed")
+	errUserHasBalance = errors.New("user has balance")
+	errUserStatusNoop = errors.New("status unchanged")
+)
+
+// SuspendUser: agent menonaktifkan user-nya. User tidak bisa launch game atau
+// pasang bet baru; settle/refund round yang sudah berjalan tetap diterima.
+func SuspendUser(c *fiber.Ctx) error {
+	return setUserStatus(c, models.AccountActionSuspend)
+}
+
+// ReactivateUser: agent mengaktifkan kembali user yang di-suspend.
+func ReactivateUser(c *fiber.Ctx) error {
+	return setUserStatus(c, models.AccountActionReactivate)
+}
+
+// CloseUser: agent menutup user secara permanen. Untuk agent mode transfer
+// saldo user harus sudah ditarik (nol) lebih dulu lewat /user/transfer, yang
+// tetap menerima withdraw dari user yang di-suspend.
+func CloseUser(c *fiber.Ctx) error {
+	return setUserStatus(c, models.AccountActionClose)
+}
+
+func setUserStatus(c *fiber.Ctx, action string) error {
+	var req UserStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.UserCode == "" || req.Reason == "" {
+		return helpers.JSONError(c, "USER_CODE_AND_REASON_REQUIRED")
+	}
+	if len(req.Reason) > 255 {
+		return helpers.JSONError(c, "REASON_TOO_LONG")
+	}
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	active := action == models.AccountActionReactivate
+
+	var user models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_code = ? AND agent_code = ?", req.UserCode, agent.AgentCode).
+			First(&user).Error; err != nil {
+			return errUserNotFound
+		}
+		if user.ClosedAt != nil {
+			return errUserClosed
+		}
+
+		updates := map[string]any{"is_active": active}
+		switch action {
+		case models.AccountActionClose:
+			if agent.WalletMode != agentwallet.ModeSeamless && !user.Balance.IsZero() {
+				return errUserHasBalance
+			}
+			now := time.Now()
+			updates["closed_at"] = &now
+			user.ClosedAt = &now
+		default:
+			if user.IsActive == active {
+				return errUserStatusNoop
+			}
+		}
+
+		wasActive := user.IsActive
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !active {
+			if _, err := launchtoken.RevokeUser(tx, user.ID); err != nil {
+				return err
+			}
+			if _, err := evosession.RevokeUser(tx, user.ID, ""); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.AccountStatusChange{
+			SubjectType: models.AccountSubjectUser,
+			SubjectID:   user.ID,
+			SubjectCode: user.UserCode,
+			Action:      action,
+			WasActive:   wasActive,
+			IsActive:    active,
+			Reason:      req.Reason,
+			ChangedBy:   agent.AgentCode,
+			IP:          c.IP(),
+		}).Error
+	})
+	switch {
+	case errors.Is(err, errUserNotFound):
+		return helpers.JSONError(c, "USER_NOT_FOUND_OR_UNAUTHORIZED")
+	case errors.Is(err, errUserClosed):
+		return helpers.JSONError(c, "USER_CLOSED")
+	case errors.Is(err, errUserHasBalance):
+		return helpers.JSONError(c, "USER_HAS_BALANCE")
+	case errors.Is(err, errUserStatusNoop):
+		if active {
+			return helpers.JSONError(c, "USER_ALREADY_ACTIVE")
+		}
+		return helpers.JSONError(c, "USER_ALREADY_SUSPENDED")
+	case err != nil:
+		return helpers.JSONError(c, "FAILED_TO_UPDATE_USER_STATUS")
+	}
+
+	return helpers.JSONSuccess(c, "User status updated successfully", fiber.Map{
+		"user_code": user.UserCode,
+		"is_active": active,
+		"action":    action,
+		"closed_at": user.ClosedAt,
+	})
+}
@@ -1,18 +1,32 @@
 package user
 
 import (
+	"errors"
 	"telo/database"
 	"telo/helpers"
 	"telo/models"
+	"telo/services/agentwallet"
+	"telo/services/idempotency"
+	"telo/services/ledger"
+	"telo/services/wallet"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransferRequest struct {
-	UserCode string `json:"user_code"`
-	Amount   int64  `json:"amount"`
-	Note     string `json:"note"`
+	UserCode string          `json:"user_code"`
+	Amount   decimal.Decimal `json:"amount"`
+	Note     string          `json:"note"`
+	// RefID dari agent; retry dengan RefID yang sama tidak memindahkan saldo lagi.
+	RefID string `json:"ref_id"`
+}
+
+type TransferStatusRequest struct {
+	RefID string `json:"ref_id"`
 }
 
 func TransferBalance(c *fiber.Ctx) error {
@@ -21,97 +35,187 @@ func TransferBalance(c *fiber.Ctx) error {
 		return helpers.JSONError(c, "INVALID_JSON")
 	}
 
-	if req.UserCode == "" || req.Amount == 0 {
+	if req.UserCode == "" || req.Amount.IsZero() {
 		return helpers.JSONError(c, "USER_CODE_AND_AMOUNT_REQUIRED")
 	}
 
+	if len(req.RefID) > 64 {
+		return helpers.JSONError(c, "REF_ID_TOO_LONG")
+	}
+
 	agent, ok := c.Locals("agent").(models.Agent)
 	if !ok {
 		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
 	}
 
-	var user models.User
-	if err := database.DB.
-		Where("user_code = ? AND agent_code = ? AND is_active = true", req.UserCode, agent.AgentCode).
-		First(&user).Error; err != nil {
-		return helpers.JSONError(c, "USER_NOT_FOUND_OR_UNAUTHORIZED")
+	// Agent mode seamless memegang saldo user di wallet-nya sendiri.
+	if agent.WalletMode == agentwallet.ModeSeamless {
+		return helpers.JSONError(c, "TRANSFER_DISABLED_FOR_SEAMLESS_AGENT")
 	}
 
-	amountAbs := abs(req.Amount)
+	refID := req.RefID
+	if refID == "" {
+		refID = uuid.New().String()
+	}
 
-	if req.Amount < 0 && user.Balance < float64(amountAbs) {
-		return helpers.JSONError(c, "INSUFFICIENT_USER_BALANCE")
+	// Transfer yang gagal tidak disimpan (Abort), jadi ref_id yang sama bisa
+	// dicoba lagi; hanya transfer yang sukses yang di-replay.
+	fail := func(message string) (int, any, error) {
+		return 0, nil, &idempotency.Abort{Status: fiber.StatusBadRequest, Body: helpers.JSONErrorBody(message)}
 	}
 
-	if req.Amount > 0 && int64(agent.Balance) < amountAbs {
-		return helpers.JSONError(c, "INSUFFICIENT_AGENT_BALANCE")
+	direction := "deposit"
+	if req.Amount.IsNegative() {
+		direction = "withdraw"
 	}
 
-	oldUserBalance := user.Balance
-	oldAgentBalance := agent.Balance
+	// Retry dengan ref_id yang sama harus berisi transfer yang sama; kalau
+	// tidak, agent mendapat REF_ID_CONFLICT, bukan replay transfer pertama.
+	key := idempotency.Key{
+		Provider:    "AGENT",
+		Operation:   "TRANSFER",
+		ExternalID:  agent.AgentCode + ":" + refID,
+		Fingerprint: idempotency.Fingerprint(req.UserCode, req.Amount.Abs().String(), direction),
+	}
+	resp, err := idempotency.Do(database.DB, key, func(tx *gorm.DB) (int, any, error) {
+		// Lock agent dulu, lalu user (lewat wallet), supaya transfer bersamaan
+		// tidak bisa membuat saldo agent minus.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&agent, agent.ID).Error; err != nil {
+			return fail("INVALID_AGENT_SESSION")
+		}
 
-	user.Balance += float64(req.Amount)
-	agent.Balance -= req.Amount
+		// User yang di-suspend masih boleh ditarik saldonya (supaya bisa
+		// ditutup), tapi tidak boleh menerima deposit.
+		var user models.User
+		if err := tx.
+			Where("user_code = ? AND agent_code = ? AND closed_at IS NULL", req.UserCode, agent.AgentCode).
+			First(&user).Error; err != nil {
+			return fail("USER_NOT_FOUND_OR_UNAUTHORIZED")
+		}
+		if !user.IsActive && req.Amount.IsPositive() {
+			return fail("USER_INACTIVE")
+		}
 
-	if err := database.DB.Save(&user).Error; err != nil {
-		return helpers.JSONError(c, "FAILED_TO_UPDATE_USER_BALANCE")
-	}
+		amountAbs := req.Amount.Abs()
 
-	if err := database.DB.Save(&agent).Error; err != nil {
-		return helpers.JSONError(c, "FAILED_TO_UPDATE_AGENT_BALANCE")
-	}
+		if req.Amount.IsPositive() && agent.Balance.LessThan(amountAbs) {
+			return fail("INSUFFICIENT_AGENT_BALANCE")
+		}
 
-	trxType := "deposit"
-	if req.Amount < 0 {
-		trxType = "withdraw"
-	}
+		op := wallet.Credit
+		if req.Amount.IsNegative() {
+			op = wallet.Withdraw
+		}
+		res, err := op(tx, wallet.Request{
+			UserCode:       user.UserCode,
+			Provider:       "AGENT",
+			Amount:         amountAbs,
+			RefID:          refID,
+			Note:           req.Note,
+			CounterAccount: ledger.AgentAccount(agent.AgentCode),
+			RequireActive:  req.Amount.IsPositive(),
+		})
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			return fail("INSUFFICIENT_USER_BALANCE")
+		}
+		if err != nil {
+			return fail("FAILED_TO_UPDATE_USER_BALANCE")
+		}
+		oldUserBalance := res.BalanceBefore
+		user.Balance = res.BalanceAfter
+
+		oldAgentBalance := agent.Balance
+		agent.Balance = agent.Balance.Sub(req.Amount)
 
-	note := req.Note
-	if note == "" {
-		if trxType == "deposit" {
-			note = "System deposit via API"
-		} else {
-			note = "System withdraw via API"
+		if err := tx.Model(&agent).Update("balance", agent.Balance).Error; err != nil {
+			return fail("FAILED_TO_UPDATE_AGENT_BALANCE")
 		}
-	}
 
-	refID := uuid.New().String()
-
-	_ = database.DB.Create(&models.UserTransaction{
-		UserID:        user.ID,
-		AgentCode:     user.AgentCode,
-		UserCode:      user.UserCode,
-		TrxType:       trxType,
-		Amount:        amountAbs,
-		BalanceBefore: oldUserBalance,
-		BalanceAfter:  user.Balance,
-		Currency:      user.Currency,
-		Note:          note,
-		RefID:         refID,
-	})
+		note := req.Note
+		if note == "" {
+			if direction == "deposit" {
+				note = "System deposit via API"
+			} else {
+				note = "System withdraw via API"
+			}
+		}
 
-	_ = database.DB.Create(&models.AgentTransaction{
-		AgentID:       agent.ID,
-		AgentCode:     agent.AgentCode,
-		TrxType:       trxType,
-		Amount:        amountAbs,
-		BalanceBefore: int64(oldAgentBalance),
-		BalanceAfter:  int64(agent.Balance),
-		Currency:      agent.Currency,
-		Note:          note + " (user: " + user.UserCode + ")",
-		RefID:         refID,
-	})
+		if err := tx.Create(&models.UserTransaction{
+			UserID:        user.ID,
+			AgentCode:     user.AgentCode,
+			UserCode:      user.UserCode,
+			TrxType:       direction,
+			Amount:        amountAbs,
+			BalanceBefore: oldUserBalance,
+			BalanceAfter:  user.Balance,
+			Currency:      user.Currency,
+			Note:          note,
+			RefID:         refID,
+		}).Error; err != nil {
+			return fail("FAILED_TO_SAVE_TRANSACTION")
+		}
+
+		if err := tx.Create(&models.AgentTransaction{
+			AgentID:       agent.ID,
+			AgentCode:     agent.AgentCode,
+			TrxType:       direction,
+			Amount:        amountAbs,
+			BalanceBefore: oldAgentBalance,
+			BalanceAfter:  agent.Balance,
+			Currency:      agent.Currency,
+			Note:          note + " (user: " + user.UserCode + ")",
+			RefID:         refID,
+		}).Error; err != nil {
+			return fail("FAILED_TO_SAVE_TRANSACTION")
+		}
 
-	return helpers.JSONSuccess(c, "Balance updated successfully", fiber.Map{
-		"user_code": user.UserCode,
-		"balance":   user.Balance,
-		"ref_id":    refID,
+		return fiber.StatusOK, helpers.JSONSuccessBody("Balance updated successfully", fiber.Map{
+			"user_code": user.UserCode,
+			"balance":   user.Balance,
+			"ref_id":    refID,
+		}), nil
 	})
+	if errors.Is(err, idempotency.ErrConflict) {
+		return helpers.JSONError(c, "REF_ID_CONFLICT")
+	}
+	if err != nil {
+		return helpers.JSONError(c, "FAILED_TO_SAVE_TRANSACTION")
+	}
+
+	return resp.Send(c)
 }
 
-func abs(x int64) int64 {
-	if x < 0 {
-		return -x
+func TransferStatus(c *fiber.Ctx) error {
+	var req TransferStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return helpers.JSONError(c, "INVALID_JSON")
+	}
+
+	if req.RefID == "" {
+		return helpers.JSONError(c, "REF_ID_REQUIRED")
 	}
-	return x
+
+	agent, ok := c.Locals("agent").(models.Agent)
+	if !ok {
+		return helpers.JSONError(c, "INVALID_AGENT_SESSION")
+	}
+
+	var trx models.UserTransaction
+	if err := database.DB.
+		Where("ref_id = ? AND agent_code = ?", req.RefID, agent.AgentCode).
+		First(&trx).Error; err != nil {
+		return helpers.JSONError(c, "TRANSFER_NOT_FOUND")
+	}
+
+	return helpers.JSONSuccess(c, "Transfer found", fiber.Map{
+		"ref_id":         trx.RefID,
+		"status":         "SUCCESS",
+		"user_code":      trx.UserCode,
+		"trx_type":       trx.TrxType,
+		"amount":         trx.Amount,
+		"balance_before": trx.BalanceBefore,
+		"balance_after":  trx.BalanceAfter,
+		"currency":       trx.Currency,
+		"created_at":     trx.CreatedAt,
+	})
 }
@@ -43,6 +43,10 @@ func Connect() {
 	if autoMigrate {
 		log.Println("🟡 Starting auto-migration...")
 
+		if err := MigrateMoneyColumns(DB); err != nil {
+			log.Fatal("❌ Failed to migrate money columns:", err)
+		}
+
 		if err := DB.AutoMigrate(
 			&models.Agent{},
 			&models.User{},
@@ -56,13 +60,49 @@ func Connect() {
 			&models.WmSubBet{},
 			&models.FastSpinTransaction{},
 			&models.SpadeGamingTransaction{},
+			&models.SabaTransaction{},
 			&models.Win568Bet{},
 			&models.Win568SubBet{},
 			&models.UserGameTransaction{},
+			&models.WalletTransaction{},
+			&models.LedgerEntry{},
+			&models.LedgerMismatch{},
+			&models.IdempotencyKey{},
+			&models.AgentSecretRotation{},
+			&models.AgentSettlement{},
+			&models.AgentCategorySetting{},
+			&models.AgentProviderSetting{},
+			&models.LaunchAudit{},
+			&models.AgentWalletCall{},
+			&models.AccountStatusChange{},
+			&models.GameRound{},
+			&models.StaleRoundAlert{},
+			&models.LaunchToken{},
+			&models.UsedNonce{},
 		); err != nil {
 			log.Fatal("❌ Failed to auto-migrate database:", err)
 		}
 
+		if err := BackfillAgentPaths(DB); err != nil {
+			log.Fatal("❌ Failed to backfill agent paths:", err)
+		}
+
+		if err := UppercaseGameProviders(DB); err != nil {
+			log.Fatal("❌ Failed to uppercase game providers:", err)
+		}
+
+		if err := SplitEvolutionProviders(DB); err != nil {
+			log.Fatal("❌ Failed to split Evolution providers:", err)
+		}
+
+		if err := RelabelWmGameTransactions(DB); err != nil {
+			log.Fatal("❌ Failed to relabel WM game transactions:", err)
+		}
+
+		if err := EncryptAgentSecrets(DB); err != nil {
+			log.Fatal("❌ Failed to encrypt agent secrets:", err)
+		}
+
 		log.Println("✅ Auto migration completed")
 	}
 }
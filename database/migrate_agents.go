@@ -0,0 +1,138 @@
+package database
+
+import (
+	"log"
+
+	"telo/helpers"
+	"telo/models"
+
+	"gorm.io/gorm"
+)
+
+// BackfillAgentPaths mengisi path untuk agent lama yang dibuat sebelum ada
+// hierarki. Agent tanpa parent menjadi root pohonnya sendiri.
+func BackfillAgentPaths(db *gorm.DB) error {
+	res := db.Exec(`UPDATE agents SET path = '/' || id || '/'
+		WHERE (path IS NULL OR path = '') AND parent_id IS NULL`)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("🌳 Backfilled path for %d root agents", res.RowsAffected)
+	}
+	return nil
+}
+
+// SplitEvolutionProviders memindahkan baris wallet, game transaction dan
+// round Evolution dari label gabungan EVOLUTION ke EVOLUTION_SLOT /
+// EVOLUTION_LIVE menurut EvolutionTransaction.Provider pada ref_id yang sama.
+// Transaksi dari sebelum produk dipisah berlabel "Evolution Live" dan ikut
+// menjadi EVOLUTION_LIVE.
+func SplitEvolutionProviders(db *gorm.DB) error {
+	const labels = `(SELECT ref_id, CASE MAX(provider) WHEN 'Evolution Slot' THEN 'EVOLUTION_SLOT' ELSE 'EVOLUTION_LIVE' END AS provider
+		FROM evolution_transactions WHERE ref_id <> '' GROUP BY ref_id) e`
+	for _, sql := range []string{
+		`UPDATE wallet_transactions t SET provider = e.provider FROM ` + labels + `
+			WHERE t.provider = 'EVOLUTION' AND t.ref_id = e.ref_id`,
+		`UPDATE user_game_transactions t SET provider = e.provider FROM ` + labels + `
+			WHERE t.provider = 'EVOLUTION' AND t.provider_tx = e.ref_id`,
+		`UPDATE game_rounds t SET provider = e.provider FROM ` + labels + `
+			WHERE t.provider = 'EVOLUTION' AND t.round_id = e.ref_id`,
+	} {
+		res := db.Exec(sql)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			log.Printf("🎲 Split %d Evolution rows into slot/live", res.RowsAffected)
+		}
+	}
+	return nil
+}
+
+// UppercaseGameProviders menyeragamkan kolom provider di
+// user_game_transactions dan game_rounds ke huruf besar (Playstar dulu menulis
+// "Playstar"), supaya filter riwayat cukup "provider = ?" dan memakai index.
+func UppercaseGameProviders(db *gorm.DB) error {
+	for _, sql := range []string{
+		`UPDATE user_game_transactions g SET provider = UPPER(g.provider)
+			WHERE g.provider <> UPPER(g.provider) AND NOT EXISTS (
+				SELECT 1 FROM user_game_transactions o
+				WHERE o.provider = UPPER(g.provider) AND o.provider_tx = g.provider_tx)`,
+		`UPDATE game_rounds r SET provider = UPPER(r.provider)
+			WHERE r.provider <> UPPER(r.provider) AND NOT EXISTS (
+				SELECT 1 FROM game_rounds o
+				WHERE o.provider = UPPER(r.provider) AND o.round_id = r.round_id AND o.user_id = r.user_id)`,
+	} {
+		res := db.Exec(sql)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			log.Printf("🔠 Uppercased provider on %d game rows", res.RowsAffected)
+		}
+	}
+	return nil
+}
+
+// RelabelWmGameTransactions memindahkan baris sub-bet WM lama dari provider
+// SBO ke SBO_WM. Baris WM dikenali dari ref_id dan round_id yang sama-sama
+// TransferCode, sedangkan baris SBO memakai TransferCode sebagai provider_tx.
+func RelabelWmGameTransactions(db *gorm.DB) error {
+	res := db.Exec(`UPDATE user_game_transactions g SET provider = 'SBO_WM'
+		FROM wm_sub_bets w
+		WHERE g.provider = 'SBO' AND g.provider_tx = w.transaction_id
+			AND g.ref_id = w.transfer_code AND g.round_id = w.transfer_code
+			AND g.provider_tx <> w.transfer_code`)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("🎲 Relabeled %d WM game transactions to SBO_WM", res.RowsAffected)
+	}
+	return nil
+}
+
+// EncryptAgentSecrets mengenkripsi key HMAC agent lama (secret plaintext di
+// secret_key atau key plaintext di secret_hash/previous_secret_hash) ke
+// signing_key_enc/previous_signing_key_enc lalu mengosongkan kolom lamanya.
+func EncryptAgentSecrets(db *gorm.DB) error {
+	var agents []models.Agent
+	if err := db.Where("(secret_key IS NOT NULL AND secret_key <> '') OR " +
+		"(secret_hash IS NOT NULL AND secret_hash <> '') OR " +
+		"(previous_secret_hash IS NOT NULL AND previous_secret_hash <> '')").
+		Find(&agents).Error; err != nil {
+		return err
+	}
+
+	for _, a := range agents {
+		updates := map[string]any{"secret_key": "", "secret_hash": "", "previous_secret_hash": ""}
+
+		current := a.SecretHash
+		if current == "" && a.SecretKey != "" {
+			current = helpers.SigningKey(a.SecretKey)
+		}
+		if current != "" && a.SigningKeyEnc == "" {
+			sealed, err := helpers.SealSecret(current, a.AgentCode)
+			if err != nil {
+				return err
+			}
+			updates["signing_key_enc"] = sealed
+		}
+		if a.PreviousSecretHash != "" && a.PreviousSigningKeyEnc == "" {
+			sealed, err := helpers.SealSecret(a.PreviousSecretHash, a.AgentCode)
+			if err != nil {
+				return err
+			}
+			updates["previous_signing_key_enc"] = sealed
+		}
+
+		if err := db.Model(&models.Agent{}).Where("id = ?", a.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if len(agents) > 0 {
+		log.Printf("🔐 Encrypted signing key for %d agents", len(agents))
+	}
+	return nil
+}
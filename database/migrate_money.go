@@ -0,0 +1,86 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type moneyColumn struct {
+	Table  string
+	Column string
+	Type   string
+	// Using adalah ekspresi konversi nilai lama, default "<column>::<type>".
+	Using string
+}
+
+// cents → satuan saldo untuk data lama Pragmatic; provider lain (Playstar)
+// sudah menyimpan amount dalam satuan saldo.
+func pragmaticCents(column string) string {
+	return fmt.Sprintf("CASE WHEN provider = 'PRAGMATIC' THEN %s::numeric / 100 ELSE %s::numeric END", column, column)
+}
+
+var moneyColumns = []moneyColumn{
+	{Table: "users", Column: "balance", Type: "numeric(24,4)"},
+	{Table: "agents", Column: "balance", Type: "numeric(24,4)"},
+	{Table: "user_transactions", Column: "amount", Type: "numeric(24,4)"},
+	{Table: "user_transactions", Column: "balance_before", Type: "numeric(24,4)"},
+	{Table: "user_transactions", Column: "balance_after", Type: "numeric(24,4)"},
+	{Table: "agent_transactions", Column: "amount", Type: "numeric(24,4)"},
+	{Table: "agent_transactions", Column: "balance_before", Type: "numeric(24,4)"},
+	{Table: "agent_transactions", Column: "balance_after", Type: "numeric(24,4)"},
+	{Table: "user_game_transactions", Column: "bet_amount", Type: "numeric(24,4)", Using: pragmaticCents("bet_amount")},
+	{Table: "user_game_transactions", Column: "win_amount", Type: "numeric(24,4)", Using: pragmaticCents("win_amount")},
+	{Table: "user_game_transactions", Column: "bonus_amount", Type: "numeric(24,4)", Using: pragmaticCents("bonus_amount")},
+	{Table: "user_game_transactions", Column: "jp_contrib", Type: "numeric(24,6)"},
+	{Table: "user_game_transactions", Column: "balance_before", Type: "numeric(24,4)"},
+	{Table: "user_game_transactions", Column: "balance_after", Type: "numeric(24,4)"},
+	{Table: "wallet_transactions", Column: "amount", Type: "numeric(24,4)"},
+	{Table: "wallet_transactions", Column: "balance_before", Type: "numeric(24,4)"},
+	{Table: "wallet_transactions", Column: "balance_after", Type: "numeric(24,4)"},
+	{Table: "evolution_transactions", Column: "amount", Type: "numeric(24,4)"},
+	{Table: "wm_sub_bets", Column: "amount", Type: "numeric(24,4)"},
+	{Table: "wm_sub_bets", Column: "win_loss", Type: "numeric(24,4)"},
+	{Table: "x568_win_transactions", Column: "amount", Type: "numeric(24,4)"},
+	{Table: "x568_win_transactions", Column: "win_loss", Type: "numeric(24,4)"},
+	{Table: "saba_transactions", Column: "bet_amount", Type: "numeric(24,4)"},
+	{Table: "saba_transactions", Column: "win_amount", Type: "numeric(24,4)"},
+	{Table: "saba_transactions", Column: "refund_amount", Type: "numeric(24,4)"},
+	{Table: "saba_transactions", Column: "balance_before", Type: "numeric(24,4)"},
+	{Table: "saba_transactions", Column: "balance_after", Type: "numeric(24,4)"},
+}
+
+// MigrateMoneyColumns mengubah kolom uang lama (double precision / bigint)
+// menjadi numeric. Harus jalan sebelum AutoMigrate supaya konversi cents
+// Pragmatic tidak dilewati. Kolom yang sudah numeric atau tabel yang belum
+// ada dilewati, jadi aman dijalankan berulang kali.
+func MigrateMoneyColumns(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, mc := range moneyColumns {
+			var dataType string
+			err := tx.Raw(`SELECT data_type FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+				mc.Table, mc.Column).Scan(&dataType).Error
+			if err != nil {
+				return err
+			}
+			if dataType == "" || dataType == "numeric" {
+				continue
+			}
+
+			using := mc.Using
+			if using == "" {
+				using = fmt.Sprintf("%s::numeric", mc.Column)
+			}
+
+			sql := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE %s USING (%s)::%s`,
+				mc.Table, mc.Column, mc.Type, using, mc.Type)
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("migrate %s.%s: %w", mc.Table, mc.Column, err)
+			}
+			log.Printf("💱 Migrated %s.%s from %s to %s", mc.Table, mc.Column, dataType, mc.Type)
+		}
+		return nil
+	})
+}
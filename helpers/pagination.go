@@ -0,0 +1,112 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidDate   = errors.New("invalid date")
+)
+
+// PageRequest adalah field paginasi + rentang tanggal yang dipakai semua
+// endpoint history. From/To boleh YYYY-MM-DD (UTC, To inklusif satu hari
+// penuh) atau RFC3339.
+type PageRequest struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// EncodeCursor membuat cursor opaque dari ID baris terakhir.
+func EncodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("id:" + strconv.FormatUint(uint64(id), 10)))
+}
+
+func DecodeCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), "id:") {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(raw), "id:"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
+
+// Paginate menerapkan rentang created_at, cursor (id menurun) dan limit+1 ke
+// query. Caller memotong hasil dengan PageResult.
+func Paginate(q *gorm.DB, page PageRequest) (*gorm.DB, int, error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	if page.From != "" {
+		from, err := parseBound(page.From, false)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = q.Where("created_at >= ?", from)
+	}
+	if page.To != "" {
+		to, err := parseBound(page.To, true)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = q.Where("created_at < ?", to)
+	}
+	if page.Cursor != "" {
+		id, err := DecodeCursor(page.Cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = q.Where("id < ?", id)
+	}
+
+	return q.Order("id DESC").Limit(limit + 1), limit, nil
+}
+
+// PageResult memotong hasil limit+1 dan mengembalikan next_cursor ("" kalau
+// sudah halaman terakhir).
+func PageResult[T any](rows []T, limit int, id func(T) uint) ([]T, string) {
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, EncodeCursor(id(rows[len(rows)-1]))
+}
+
+func parseBound(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	if end {
+		// RFC3339 inklusif sampai detik tersebut
+		t = t.Add(time.Nanosecond)
+	}
+	return t, nil
+}
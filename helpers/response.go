@@ -7,19 +7,27 @@ import (
 )
 
 func JSONSuccess(c *fiber.Ctx, message string, data any) error {
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+	return c.Status(fiber.StatusOK).JSON(JSONSuccessBody(message, data))
+}
+
+func JSONError(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(JSONErrorBody(message))
+}
+
+func JSONSuccessBody(message string, data any) fiber.Map {
+	return fiber.Map{
 		"success": true,
 		"message": message,
 		"data":    data,
-	})
+	}
 }
 
-func JSONError(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+func JSONErrorBody(message string) fiber.Map {
+	return fiber.Map{
 		"success": false,
 		"message": message,
 		"data":    nil,
-	})
+	}
 }
 
 func FormatFloat(num float64, precision int) float64 {
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Signing key agent disimpan terenkripsi AES-256-GCM dengan key server dari
+// AGENT_SECRET_ENC_KEY (base64, 32 byte). Isi tabel agents saja tidak cukup
+// untuk membuat signature; key server tidak pernah disimpan di DB.
+
+const sealedPrefix = "v1:"
+
+var (
+	ErrSecretKeyMissing = errors.New("AGENT_SECRET_ENC_KEY must be a base64 encoded 32 byte key")
+	ErrSealedSecret     = errors.New("invalid sealed secret")
+)
+
+func secretCipher() (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(os.Getenv("AGENT_SECRET_ENC_KEY"))
+	if err != nil || len(raw) != 32 {
+		return nil, ErrSecretKeyMissing
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealSecret mengenkripsi plain. aad (agent code) ikut diautentikasi, jadi
+// ciphertext tidak bisa dipindah ke baris agent lain.
+func SealSecret(plain, aad string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(aad))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret membalik SealSecret.
+func OpenSecret(sealed, aad string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil || !strings.HasPrefix(sealed, sealedPrefix) || len(raw) < aead.NonceSize() {
+		return "", ErrSealedSecret
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", ErrSealedSecret
+	}
+	return string(plain), nil
+}
+
+// SealSigningKey menurunkan signing key dari secret agent lalu
+// mengenkripsinya untuk disimpan di Agent.SigningKeyEnc.
+func SealSigningKey(secret, agentCode string) (string, error) {
+	return SealSecret(SigningKey(secret), agentCode)
+}
@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// SigningKey menurunkan key HMAC dari secret agent. Nilai ini setara dengan
+// secret (siapa pun yang memegangnya bisa membuat signature), jadi hanya
+// disimpan terenkripsi, lihat SealSigningKey.
+func SigningKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CanonicalRequest adalah string yang ditandatangani:
+//
+//	METHOD \n URI \n TIMESTAMP \n NONCE \n hex(sha256(body))
+func CanonicalRequest(method, uri, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		uri,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+func SignRequest(signingKey, canonical string) string {
+	h := hmac.New(sha256.New, []byte(signingKey))
+	h.Write([]byte(canonical))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func VerifyRequestSignature(signingKey, canonical, signature string) bool {
+	expected := SignRequest(signingKey, canonical)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
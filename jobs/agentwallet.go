@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"telo/database"
+	"telo/services/agentwallet"
+	"time"
+)
+
+// StartAgentWalletRetryScheduler mengirim ulang settle/rollback ke wallet
+// agent mode seamless yang belum diterima. Interval lewat
+// AGENT_WALLET_RETRY_INTERVAL (format time.ParseDuration), default 30 detik.
+func StartAgentWalletRetryScheduler() {
+	interval := 30 * time.Second
+	if v := os.Getenv("AGENT_WALLET_RETRY_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("⚠️  Invalid value for AGENT_WALLET_RETRY_INTERVAL: %s\n", v)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			<-ticker.C
+			delivered, err := agentwallet.RetryPending(database.DB, time.Now())
+			if err != nil {
+				log.Printf("❌ error retry agent wallet: %v", err)
+				continue
+			}
+			if delivered > 0 {
+				log.Printf("✅ [AGENTWALLET] %d pending call delivered", delivered)
+			}
+		}
+	}()
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"telo/database"
+	"telo/services/ledger"
+	"time"
+)
+
+// StartLedgerReconcileScheduler menjalankan rekonsiliasi saldo user vs ledger
+// secara berkala. Interval bisa diatur lewat LEDGER_RECONCILE_INTERVAL
+// (format time.ParseDuration), default 1 jam.
+func StartLedgerReconcileScheduler() {
+	interval := time.Hour
+	if v := os.Getenv("LEDGER_RECONCILE_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("⚠️  Invalid value for LEDGER_RECONCILE_INTERVAL: %s\n", v)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			<-ticker.C
+			runID, drift, err := ledger.Reconcile(database.DB)
+			if err != nil {
+				log.Printf("❌ error reconcile ledger: %v", err)
+				continue
+			}
+			if drift > 0 {
+				log.Printf("⚠️ [LEDGER] reconcile %s: %d user mismatch", runID, drift)
+			}
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"telo/database"
+	"telo/models"
+	"time"
+)
+
+// StartNonceCleanupScheduler menghapus nonce signature yang sudah kedaluwarsa.
+// Interval lewat NONCE_CLEANUP_INTERVAL (format time.ParseDuration), default
+// 10 menit.
+func StartNonceCleanupScheduler() {
+	interval := 10 * time.Minute
+	if v := os.Getenv("NONCE_CLEANUP_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("⚠️  Invalid value for NONCE_CLEANUP_INTERVAL: %s\n", v)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			<-ticker.C
+			res := database.DB.Where("expires_at < ?", time.Now()).Delete(&models.UsedNonce{})
+			if res.Error != nil {
+				log.Printf("❌ error cleanup nonce: %v", res.Error)
+			}
+		}
+	}()
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"log"
+	"telo/database"
+	"telo/services/settlement"
+	"time"
+)
+
+// StartSettlementScheduler men-settle GGR agent untuk periode yang sudah
+// selesai. Dicek tiap jam; periode yang sudah di-settle dilewati.
+func StartSettlementScheduler() {
+	ticker := time.NewTicker(time.Hour)
+	go func() {
+		for {
+			<-ticker.C
+			if _, err := settlement.SettlePending(database.DB, time.Now()); err != nil {
+				log.Printf("❌ error settle GGR: %v", err)
+			}
+		}
+	}()
+}
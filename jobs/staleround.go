@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"telo/database"
+	"telo/services/staleround"
+	"time"
+)
+
+// StartStaleRoundScheduler mencari round yang tidak pernah ditutup provider
+// dan menjalankan policy-nya (lihat services/staleround). Interval lewat
+// STALE_ROUND_INTERVAL (format time.ParseDuration), default 5 menit.
+func StartStaleRoundScheduler() {
+	interval := 5 * time.Minute
+	if v := os.Getenv("STALE_ROUND_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("⚠️  Invalid value for STALE_ROUND_INTERVAL: %s\n", v)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			<-ticker.C
+			n, err := staleround.Scan(database.DB, time.Now())
+			if err != nil {
+				log.Printf("❌ error stale round scan: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("⚠️ [STALEROUND] %d stale round(s) processed", n)
+			}
+		}
+	}()
+}
@@ -37,6 +37,11 @@ func main() {
 	app := fiber.New()
 	routes.Setup(app)
 	jobs.StartWin568Scheduler()
+	jobs.StartLedgerReconcileScheduler()
+	jobs.StartSettlementScheduler()
+	jobs.StartAgentWalletRetryScheduler()
+	jobs.StartStaleRoundScheduler()
+	jobs.StartNonceCleanupScheduler()
 
 	addr := fmt.Sprintf("%s:%s", host, port)
 	log.Println("Server running at", addr)
@@ -1,40 +1,31 @@
 package middlewares
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"os"
+	"telo/helpers"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// AgentAuth melindungi endpoint master (/agent/register, /agent/topup).
+// Request harus ditandatangani dengan MASTER_AGENT_SECRET, lihat
+// verifySignedRequest.
 func AgentAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var body struct {
-			Signature string `json:"signature"`
-		}
+		masterCode := os.Getenv("MASTER_AGENT_CODE")
+		masterSecret := os.Getenv("MASTER_AGENT_SECRET")
 
-		if err := c.BodyParser(&body); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		if masterCode == "" || masterSecret == "" || c.Get(HeaderAgentCode) != masterCode {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"status": 0,
-				"msg":    "INVALID_JSON",
+				"msg":    "INVALID_AGENT_CREDENTIALS",
 			})
 		}
 
-		masterCode := os.Getenv("MASTER_AGENT_CODE")
-		masterSecret := os.Getenv("MASTER_AGENT_SECRET")
-
-		data := masterCode + masterSecret
-
-		h := hmac.New(sha256.New, []byte(masterSecret))
-		h.Write([]byte(data))
-		expectedSignature := hex.EncodeToString(h.Sum(nil))
-
-		if body.Signature != expectedSignature {
+		if msg := verifySignedRequest(c, masterCode, helpers.SigningKey(masterSecret)); msg != "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"status": 0,
-				"msg":    "INVALID_SIGNATURE",
+				"msg":    msg,
 			})
 		}
 
@@ -7,11 +7,13 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func CheckEvolutionTokenLive() fiber.Handler {
-	expected := os.Getenv("EVOLUTION_AUTH_TOKEN_LIVE")
+// CheckEvolutionToken memvalidasi authToken Evolution di query. Slot dan live
+// memakai token berbeda, tokenEnv adalah nama env-nya (lihat evolution.Product).
+func CheckEvolutionToken(tokenEnv string) fiber.Handler {
+	expected := os.Getenv(tokenEnv)
 
 	return func(c *fiber.Ctx) error {
-		if c.Query("authToken") != expected {
+		if expected == "" || c.Query("authToken") != expected {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"status":  "INVALID_TOKEN_ID",
 				"message": "Unauthorized: Invalid Evolution token",
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Kode error FastSpin/SpadeGaming (dokumen seamless wallet keduanya sama).
+const (
+	fsCodeMerchantKeyError = 108
+	fsCodeMerchantNotFound = 10113
+)
+
+// FastSpinAuth memvalidasi header Digest dan merchantCode callback FastSpin.
+func FastSpinAuth() fiber.Handler {
+	return digestAuth("FASTSPIN", os.Getenv("FASTSPIN_MERCHANT_CODE"), os.Getenv("FASTSPIN_SECRET_KEY"))
+}
+
+// SpadeGamingAuth memvalidasi header Digest dan merchantCode callback SpadeGaming.
+func SpadeGamingAuth() fiber.Handler {
+	return digestAuth("SPADEGAMING", os.Getenv("SPADE_GAMING_MERCHANT_CODE"), os.Getenv("SPADE_GAMING_SECRET_KEY"))
+}
+
+// digestAuth: Digest = MD5(raw body + secret key), sama dengan yang dikirim
+// launcher saat authorize. merchantCode di body harus milik kita.
+func digestAuth(provider, merchantCode, secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		body := c.Body()
+
+		var req struct {
+			MerchantCode string `json:"merchantCode"`
+			SerialNo     string `json:"serialNo"`
+		}
+		_ = json.Unmarshal(body, &req)
+
+		sum := md5.Sum(append(append([]byte{}, body...), secret...))
+		expected := hex.EncodeToString(sum[:])
+		digest := strings.ToLower(strings.TrimSpace(c.Get("Digest")))
+
+		if secret == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(digest)) != 1 {
+			log.Printf("[%s] ❌ Invalid digest | serialNo=%s | ip=%s", provider, req.SerialNo, c.IP())
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"code":     fsCodeMerchantKeyError,
+				"msg":      "Invalid digest",
+				"serialNo": req.SerialNo,
+			})
+		}
+
+		if merchantCode == "" || req.MerchantCode != merchantCode {
+			log.Printf("[%s] ❌ Invalid merchantCode=%s | serialNo=%s", provider, req.MerchantCode, req.SerialNo)
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"code":     fsCodeMerchantNotFound,
+				"msg":      "Merchant not found",
+				"serialNo": req.SerialNo,
+			})
+		}
+
+		return c.Next()
+	}
+}
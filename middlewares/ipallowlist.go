@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IPAllowlist membatasi callback provider ke IP sumber yang terdaftar di
+// <PROVIDER>_IP_ALLOWLIST (dipisah koma, boleh IP atau CIDR). Kalau env
+// kosong semua IP diterima; kalau env diisi tapi tidak ada entry yang valid,
+// server menolak start supaya salah ketik tidak membuka callback ke semua IP.
+// denied adalah body error dalam format provider.
+func IPAllowlist(provider string, denied fiber.Map) fiber.Handler {
+	key := strings.ToUpper(provider) + "_IP_ALLOWLIST"
+	raw := os.Getenv(key)
+	nets := parseAllowlist(key, raw)
+	if len(nets) == 0 && strings.Trim(raw, " ,") != "" {
+		log.Fatalf("❌ %s is set but contains no valid IP or CIDR: %q", key, raw)
+	}
+
+	return func(c *fiber.Ctx) error {
+		if len(nets) == 0 {
+			return c.Next()
+		}
+
+		ip := net.ParseIP(c.IP())
+		for _, n := range nets {
+			if ip != nil && n.Contains(ip) {
+				return c.Next()
+			}
+		}
+
+		log.Printf("[%s] ❌ Source IP not allowed | ip=%s | path=%s", strings.ToUpper(provider), c.IP(), c.Path())
+		return c.Status(fiber.StatusOK).JSON(denied)
+	}
+}
+
+func parseAllowlist(key, raw string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("⚠️  Invalid entry in %s: %s\n", key, entry)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
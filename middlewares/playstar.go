@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"telo/database"
+	"telo/models"
+	"telo/services/launchtoken"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlaystarAuth memvalidasi access_token Playstar terhadap launch token yang
+// dibuat launcher Playstar dan mencocokkan member_id dengan pemilik token.
+//
+// Untuk bet/getbalance (active = true) token harus masih aktif dan user
+// disimpan di c.Locals("user"). Result/refund/bonus boleh datang setelah
+// sesi expired atau logout, jadi cukup token-nya dikenal dan milik member.
+func PlaystarAuth(active bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := strings.TrimSpace(c.Query("access_token"))
+		memberID := strings.TrimSpace(c.Query("member_id"))
+		if accessToken == "" || memberID == "" {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status_code": 1})
+		}
+
+		var (
+			user  models.User
+			token models.LaunchToken
+			err   error
+		)
+		if active {
+			user, token, err = launchtoken.ResolveOrLegacy(database.DB, accessToken, "PLAYSTAR", memberID)
+		} else {
+			token, err = launchtoken.FindOrLegacy(database.DB, accessToken, "PLAYSTAR", memberID)
+		}
+		switch {
+		case errors.Is(err, launchtoken.ErrNotFound), errors.Is(err, launchtoken.ErrExpired),
+			errors.Is(err, launchtoken.ErrRevoked):
+			log.Printf("[PLAYSTAR] ❌ Invalid access_token | member=%s | err=%v", memberID, err)
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status_code": 1})
+		case err != nil:
+			log.Printf("[PLAYSTAR] ❌ Failed to resolve access_token | member=%s | err=%v", memberID, err)
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status_code": 5})
+		}
+
+		if token.UserCode != memberID {
+			log.Printf("[PLAYSTAR] ❌ member_id mismatch | member=%s | token_user=%s", memberID, token.UserCode)
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status_code": 1})
+		}
+
+		if active {
+			c.Locals("user", user)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PragmaticHash memvalidasi parameter hash di semua callback Pragmatic Play:
+// MD5 dari seluruh parameter (kecuali hash) yang diurutkan per nama dalam
+// format key=value&key=value, lalu ditambah secret key. Secret diambil dari
+// PRAGMATIC_SECRET_KEY, beda per environment.
+func PragmaticHash() fiber.Handler {
+	secret := os.Getenv("PRAGMATIC_SECRET_KEY")
+
+	return func(c *fiber.Ctx) error {
+		params := map[string]string{}
+		c.Request().PostArgs().VisitAll(func(k, v []byte) {
+			params[string(k)] = string(v)
+		})
+
+		hash := params["hash"]
+		delete(params, "hash")
+
+		if secret == "" || hash == "" || !validPragmaticHash(params, secret, hash) {
+			log.Printf("[PRAGMATIC] ❌ Invalid hash | path=%s | ip=%s", c.Path(), c.IP())
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"error":       5,
+				"description": "Invalid hash",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+func validPragmaticHash(params map[string]string, secret, hash string) bool {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+params[k])
+	}
+
+	sum := md5.Sum([]byte(strings.Join(pairs, "&") + secret))
+	expected := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(hash))) == 1
+}
@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SabaAuth memvalidasi "key" di envelope request SABA ({"key": ..., "message":
+// {...}}) terhadap SABA_VENDOR_ID.
+func SabaAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body struct {
+			Key string `json:"key"`
+		}
+
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"status": "101",
+				"msg":    "Parameter(s) Incorrect",
+			})
+		}
+
+		vendorID := os.Getenv("SABA_VENDOR_ID")
+		if vendorID == "" || body.Key != vendorID {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"status": "311",
+				"msg":    "Invalid Authentication Key",
+			})
+		}
+
+		return c.Next()
+	}
+}
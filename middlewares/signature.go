@@ -0,0 +1,98 @@
+package middlewares
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	HeaderAgentCode = "X-Agent-Code"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+const defaultSignatureSkew = 5 * time.Minute
+
+// signatureSkew dibaca dari SIGNATURE_MAX_SKEW (detik), default 5 menit.
+func signatureSkew() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("SIGNATURE_MAX_SKEW")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return defaultSignatureSkew
+}
+
+// useNonce mencatat nonce di tabel used_nonces (dipakai bersama semua
+// instance) dan mengembalikan false kalau nonce masih tercatat. Request dengan
+// timestamp di luar window sudah ditolak, jadi baris yang kedaluwarsa boleh
+// ditimpa sebelum dihapus job.
+func useNonce(agentCode, nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "agent_code"}, {Name: "nonce"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "used_nonces.expires_at < ?", Vars: []any{now}},
+		}},
+	}).Create(&models.UsedNonce{AgentCode: agentCode, Nonce: nonce, ExpiresAt: now.Add(ttl)})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// verifySignedRequest memeriksa header signature untuk agentCode dengan key
+// yang diberikan. Mengembalikan kode error kosong kalau valid.
+func verifySignedRequest(c *fiber.Ctx, agentCode string, signingKeys ...string) string {
+	timestamp := c.Get(HeaderTimestamp)
+	nonce := c.Get(HeaderNonce)
+	signature := c.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return "SIGNATURE_HEADERS_REQUIRED"
+	}
+	if len(nonce) < 8 || len(nonce) > 64 {
+		return "INVALID_NONCE"
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "INVALID_TIMESTAMP"
+	}
+	skew := signatureSkew()
+	diff := time.Since(time.Unix(ts, 0))
+	if diff > skew || diff < -skew {
+		return "TIMESTAMP_OUT_OF_WINDOW"
+	}
+
+	canonical := helpers.CanonicalRequest(c.Method(), c.OriginalURL(), timestamp, nonce, c.Body())
+	valid := false
+	for _, key := range signingKeys {
+		if key != "" && helpers.VerifyRequestSignature(key, canonical, signature) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "INVALID_SIGNATURE"
+	}
+
+	// Nonce dicatat setelah signature valid supaya pihak lain tidak bisa
+	// "membakar" nonce milik agent.
+	ok, err := useNonce(agentCode, nonce, 2*skew)
+	if err != nil {
+		log.Printf("❌ Failed to record nonce for agent %s: %v", agentCode, err)
+		return "NONCE_CHECK_FAILED"
+	}
+	if !ok {
+		return "NONCE_ALREADY_USED"
+	}
+	return ""
+}
@@ -1,26 +1,52 @@
 package middlewares
 
 import (
+	"log"
 	"telo/database"
 	"telo/helpers"
 	"telo/models"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func UserAuthMiddleware(c *fiber.Ctx) error {
-	agentCode := c.Get("X-Agent-Code")
-	secretKey := c.Get("X-Secret-Key")
+	return userAuth(c, false)
+}
+
+// UserAuthAllowInactive sama dengan UserAuthMiddleware tapi meneruskan agent
+// yang tidak aktif ke handler, untuk endpoint yang perlu mencatat percobaan
+// (mis. audit launch) sebelum menolaknya.
+func UserAuthAllowInactive(c *fiber.Ctx) error {
+	return userAuth(c, true)
+}
 
-	if agentCode == "" || secretKey == "" {
-		return helpers.JSONError(c, "AGENT_CODE_AND_SECRET_REQUIRED")
+func userAuth(c *fiber.Ctx, allowInactive bool) error {
+	agentCode := c.Get(HeaderAgentCode)
+	if agentCode == "" {
+		return helpers.JSONError(c, "AGENT_CODE_REQUIRED")
 	}
 
 	var agent models.Agent
-	if err := database.DB.Where("agent_code = ? AND secret_key = ? AND is_active = true", agentCode, secretKey).First(&agent).Error; err != nil {
+	if err := database.DB.Where("agent_code = ?", agentCode).First(&agent).Error; err != nil {
+		return helpers.JSONError(c, "INVALID_AGENT_CREDENTIALS")
+	}
+
+	keys, err := agent.SigningKeys(time.Now())
+	if err != nil {
+		log.Printf("❌ Failed to load signing key for agent %s: %v", agent.AgentCode, err)
 		return helpers.JSONError(c, "INVALID_AGENT_CREDENTIALS")
 	}
 
+	if msg := verifySignedRequest(c, agent.AgentCode, keys...); msg != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(helpers.JSONErrorBody(msg))
+	}
+
+	// Status agent baru dibuka setelah signature valid.
+	if !agent.IsActive && !allowInactive {
+		return helpers.JSONError(c, "AGENT_INACTIVE")
+	}
+
 	c.Locals("agent", agent)
 	return c.Next()
 }
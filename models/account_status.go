@@ -0,0 +1,29 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	AccountActionSuspend    = "SUSPEND"
+	AccountActionReactivate = "REACTIVATE"
+	AccountActionClose      = "CLOSE"
+
+	AccountSubjectAgent = "agent"
+	AccountSubjectUser  = "user"
+)
+
+// AccountStatusChange adalah audit trail suspend/reactivate/close agent dan
+// user.
+type AccountStatusChange struct {
+	gorm.Model
+
+	SubjectType string `gorm:"size:16;index:idx_account_status_subject" json:"subject_type"`
+	SubjectID   uint   `gorm:"index:idx_account_status_subject" json:"subject_id"`
+	SubjectCode string `gorm:"size:32;index" json:"subject_code"`
+	Action      string `gorm:"size:16" json:"action"`
+	WasActive   bool   `json:"was_active"`
+	IsActive    bool   `json:"is_active"`
+	Reason      string `gorm:"size:255" json:"reason"`
+	// ChangedBy: "MASTER" atau kode agent yang melakukan perubahan.
+	ChangedBy string `gorm:"size:32" json:"changed_by"`
+	IP        string `gorm:"size:64" json:"ip"`
+}
@@ -1,32 +1,146 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"telo/helpers"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
 
 type Agent struct {
 	gorm.Model
 
-	Username  string  `gorm:"uniqueIndex;size:32" json:"username"`
-	AgentCode string  `gorm:"uniqueIndex;size:32" json:"agent_code"`
-	SecretKey string  `gorm:"size:128" json:"secret_key"`
-	Balance   int64   `json:"balance"`
-	Currency  string  `gorm:"size:8" json:"currency"`
-	GGR       float64 `json:"ggr"`
-	IsActive  bool    `gorm:"default:true" json:"isactive"`
+	Username  string `gorm:"uniqueIndex;size:32" json:"username"`
+	AgentCode string `gorm:"uniqueIndex;size:32" json:"agent_code"`
+	// SecretKey hanya berisi data lama sebelum enkripsi; dikosongkan oleh
+	// migrasi EncryptAgentSecrets.
+	SecretKey string          `gorm:"size:128" json:"-"`
+	Balance   decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"balance"`
+	Currency  string          `gorm:"size:8" json:"currency"`
+	GGR       float64         `json:"ggr"`
+	IsActive  bool            `gorm:"default:true" json:"isactive"`
+
+	// SigningKeyEnc = helpers.SealSigningKey(secret): key HMAC untuk signature,
+	// terenkripsi dengan AGENT_SECRET_ENC_KEY. Selama grace period setelah
+	// rotasi, PreviousSigningKeyEnc masih diterima.
+	SigningKeyEnc           string     `gorm:"size:255" json:"-"`
+	PreviousSigningKeyEnc   string     `gorm:"size:255" json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"-"`
+
+	// SecretHash dan PreviousSecretHash: key HMAC plaintext dari versi lama,
+	// dikosongkan oleh migrasi EncryptAgentSecrets.
+	SecretHash         string `gorm:"size:64" json:"-"`
+	PreviousSecretHash string `gorm:"size:64" json:"-"`
+
+	// Hierarki master → senior → agent. Path berisi ID leluhur sampai diri
+	// sendiri ("/1/5/9/") supaya query downline cukup pakai LIKE.
+	ParentID   *uint  `gorm:"index" json:"parent_id"`
+	ParentCode string `gorm:"size:32;index" json:"parent_code"`
+	Tier       string `gorm:"size:16;default:master" json:"tier"`
+	Path       string `gorm:"size:255;index" json:"path"`
+
+	// WalletMode "transfer" (saldo user di users.balance, diisi lewat
+	// /user/transfer) atau "seamless" (saldo dipegang agent, setiap debit/credit
+	// diteruskan ke WalletURL).
+	WalletMode string `gorm:"size:16;default:transfer" json:"wallet_mode"`
+	WalletURL  string `gorm:"size:255" json:"wallet_url"`
+
+	Parent   *Agent  `gorm:"foreignKey:ParentID" json:"-"`
+	Children []Agent `gorm:"foreignKey:ParentID" json:"-"`
 
 	Users        []User             `gorm:"foreignKey:AgentCode;references:AgentCode"`
 	Transactions []AgentTransaction `gorm:"foreignKey:AgentID"`
 }
 
+const (
+	AgentTierMaster = "master"
+	AgentTierSenior = "senior"
+	AgentTierAgent  = "agent"
+)
+
+// ChildTier mengembalikan tier untuk sub-agent dari tier ini; false kalau
+// tier tersebut tidak boleh punya sub-agent.
+func ChildTier(tier string) (string, bool) {
+	switch tier {
+	case AgentTierMaster:
+		return AgentTierSenior, true
+	case AgentTierSenior:
+		return AgentTierAgent, true
+	default:
+		return "", false
+	}
+}
+
+// SubtreePattern adalah pola LIKE untuk agent ini beserta seluruh downline-nya.
+func (a Agent) SubtreePattern() string {
+	if a.Path == "" {
+		// root lama yang path-nya belum di-backfill
+		return fmt.Sprintf("/%d/%%", a.ID)
+	}
+	return a.Path + "%"
+}
+
+// SigningKeys mendekripsi key yang saat ini boleh dipakai untuk signature.
+// Key pertama adalah key aktif.
+func (a Agent) SigningKeys(now time.Time) ([]string, error) {
+	current, err := a.signingKey(a.SigningKeyEnc, a.SecretHash)
+	if err != nil {
+		return nil, err
+	}
+	if current == "" && a.SecretKey != "" {
+		// agent lama yang belum dimigrasi EncryptAgentSecrets
+		current = helpers.SigningKey(a.SecretKey)
+	}
+	if current == "" {
+		return nil, errors.New("agent has no signing key")
+	}
+
+	keys := []string{current}
+	if a.PreviousSecretExpiresAt != nil && now.Before(*a.PreviousSecretExpiresAt) {
+		previous, err := a.signingKey(a.PreviousSigningKeyEnc, a.PreviousSecretHash)
+		if err != nil {
+			return nil, err
+		}
+		if previous != "" {
+			keys = append(keys, previous)
+		}
+	}
+	return keys, nil
+}
+
+func (a Agent) signingKey(sealed, legacy string) (string, error) {
+	if sealed == "" {
+		return legacy, nil
+	}
+	return helpers.OpenSecret(sealed, a.AgentCode)
+}
+
+// AgentSecretRotation adalah audit trail setiap rotasi secret agent.
+type AgentSecretRotation struct {
+	gorm.Model
+
+	AgentID    uint      `gorm:"index"`
+	AgentCode  string    `gorm:"size:32;index"`
+	RotatedBy  string    `gorm:"size:32"`
+	GraceUntil time.Time `json:"grace_until"`
+	IP         string    `gorm:"size:64"`
+	Reason     string    `gorm:"size:255"`
+}
+
 type AgentTransaction struct {
 	gorm.Model
 
-	AgentID       uint   `gorm:"index"`
-	AgentCode     string `gorm:"index;size:32"`
-	TrxType       string `gorm:"size:16"`
-	Amount        int64  `json:"amount"`
-	BalanceBefore int64  `json:"balance_before"`
-	BalanceAfter  int64  `json:"balance_after"`
-	Currency      string `gorm:"size:8"`
-	Note          string `gorm:"size:255"`
-	RefID         string `gorm:"size:64"`
+	AgentID       uint            `gorm:"index"`
+	AgentCode     string          `gorm:"index;size:32"`
+	TrxType       string          `gorm:"size:16"`
+	Amount        decimal.Decimal `gorm:"type:numeric(24,4)" json:"amount"`
+	BalanceBefore decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_before"`
+	BalanceAfter  decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_after"`
+	Currency      string          `gorm:"size:8"`
+	Note          string          `gorm:"size:255"`
+	RefID         string          `gorm:"size:64;index"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// AgentCategorySetting mengatur satu kategori provider (slots, live_casino,
+// sportsbook) untuk satu agent. Tanpa baris = kategori aktif dengan rate
+// Agent.GGR.
+type AgentCategorySetting struct {
+	gorm.Model
+
+	AgentID  uint   `gorm:"uniqueIndex:idx_agent_category"`
+	Category string `gorm:"size:32;uniqueIndex:idx_agent_category" json:"category"`
+	Enabled  bool   `gorm:"default:true" json:"enabled"`
+	// GGRRate dalam persen; NULL = pakai Agent.GGR.
+	GGRRate decimal.NullDecimal `gorm:"type:numeric(10,4)" json:"ggr_rate"`
+}
+
+// AgentProviderSetting meng-override kategori untuk satu provider launcher.
+type AgentProviderSetting struct {
+	gorm.Model
+
+	AgentID      uint   `gorm:"uniqueIndex:idx_agent_provider"`
+	ProviderCode string `gorm:"size:32;uniqueIndex:idx_agent_provider" json:"provider_code"`
+	Enabled      bool   `json:"enabled"`
+}
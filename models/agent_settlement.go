@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// AgentSettlement adalah statement GGR satu agent untuk satu provider dalam
+// satu periode. GGR = TotalBet - TotalRefund - TotalWin (dari sisi operator),
+// TotalRefund adalah stake bet yang di-cancel; Amount =
+// GGR * Rate / 100 didebit dari Agent.Balance (GGR negatif → dikredit).
+type AgentSettlement struct {
+	gorm.Model
+
+	AgentID     uint      `gorm:"uniqueIndex:idx_agent_settlement_period"`
+	AgentCode   string    `gorm:"size:32;index"`
+	Provider    string    `gorm:"size:32;uniqueIndex:idx_agent_settlement_period"`
+	PeriodStart time.Time `gorm:"uniqueIndex:idx_agent_settlement_period" json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Currency    string    `gorm:"size:8"`
+
+	TxCount     int64           `json:"tx_count"`
+	TotalBet    decimal.Decimal `gorm:"type:numeric(24,4)" json:"total_bet"`
+	TotalRefund decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"total_refund"`
+	TotalWin    decimal.Decimal `gorm:"type:numeric(24,4)" json:"total_win"`
+	GGR         decimal.Decimal `gorm:"type:numeric(24,4)" json:"ggr"`
+	Rate        decimal.Decimal `gorm:"type:numeric(10,4)" json:"rate"`
+	Amount      decimal.Decimal `gorm:"type:numeric(24,4)" json:"amount"`
+
+	BalanceBefore decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_before"`
+	BalanceAfter  decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_after"`
+	RefID         string          `gorm:"size:64;index"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// AgentWalletCall mencatat setiap request ke wallet agent mode seamless. Baris
+// ditulis di luar transaction callback supaya tetap ada walaupun transaction
+// itu rollback; WalletTransactionID baru terisi kalau transaction commit.
+type AgentWalletCall struct {
+	gorm.Model
+
+	AgentID   uint   `gorm:"index"`
+	AgentCode string `gorm:"size:32;index"`
+	UserCode  string `gorm:"size:100;index"`
+	// Action: bet, settle, rollback.
+	Action string `gorm:"size:16;index"`
+	// TxID dikirim ke agent dan harus diperlakukan idempotent oleh agent.
+	TxID string `gorm:"size:64;uniqueIndex"`
+	// RefTxID: untuk rollback, TxID bet yang dibatalkan.
+	RefTxID    string          `gorm:"size:64;index"`
+	Provider   string          `gorm:"size:32"`
+	Operation  string          `gorm:"size:16"`
+	ExternalID string          `gorm:"size:128"`
+	Amount     decimal.Decimal `gorm:"type:numeric(24,4)"`
+	Currency   string          `gorm:"size:8"`
+
+	// Status: PENDING (belum/akan dicoba ulang), OK, REJECTED, FAILED.
+	Status        string              `gorm:"size:16;index"`
+	Attempts      int                 `gorm:"default:0"`
+	NextAttemptAt *time.Time          `gorm:"index"`
+	LastError     string              `gorm:"type:text"`
+	AgentBalance  decimal.NullDecimal `gorm:"type:numeric(24,4)"`
+
+	WalletTransactionID *uint `gorm:"index"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	RoundStatusOpen      = "Open"
+	RoundStatusSettled   = "Settled"
+	RoundStatusCancelled = "Cancelled"
+)
+
+// GameRound merangkum semua event (bet, result, bonus, jackpot, refund) dari
+// satu round provider untuk satu user. Amount dalam satuan saldo user.
+type GameRound struct {
+	gorm.Model
+
+	Provider  string `gorm:"size:32;index:idx_game_round,unique" json:"provider"`
+	RoundID   string `gorm:"size:128;index:idx_game_round,unique" json:"round_id"`
+	UserID    uint   `gorm:"index:idx_game_round,unique" json:"user_id"`
+	UserCode  string `gorm:"size:32;index" json:"user_code"`
+	AgentCode string `gorm:"size:32;index" json:"agent_code"`
+	GameID    string `gorm:"size:64" json:"game_id"`
+	Currency  string `gorm:"size:8" json:"currency"`
+
+	// Status: Open → Settled / Cancelled. Bet baru pada round yang sudah
+	// ditutup membukanya lagi.
+	Status string `gorm:"size:16;index" json:"status"`
+
+	BetAmount     decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"bet_amount"`
+	WinAmount     decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"win_amount"`
+	JackpotAmount decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"jackpot_amount"`
+	PromoAmount   decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"promo_amount"`
+	RefundAmount  decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"refund_amount"`
+	EventCount    int             `json:"event_count"`
+
+	OpenedAt    time.Time  `gorm:"index" json:"opened_at"`
+	LastEventAt time.Time  `json:"last_event_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+}
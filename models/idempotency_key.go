@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// IdempotencyKey menyimpan response pertama untuk satu callback provider.
+// Kombinasi Provider + Operation + ExternalID unik, jadi retry (termasuk yang
+// datang bersamaan) hanya diproses sekali dan sisanya mendapat replay
+// ResponseBody yang sama persis.
+type IdempotencyKey struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Provider   string `gorm:"size:32;uniqueIndex:idx_idempotency_key"`
+	Operation  string `gorm:"size:32;uniqueIndex:idx_idempotency_key"`
+	ExternalID string `gorm:"size:128;uniqueIndex:idx_idempotency_key"`
+
+	// Fingerprint: hash isi request pertama (opsional). Retry dengan key sama
+	// tapi isi berbeda ditolak, bukan di-replay.
+	Fingerprint string `gorm:"size:64"`
+
+	StatusCode   int
+	ResponseBody string `gorm:"type:text"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LaunchAudit mencatat setiap percobaan launch game, sukses maupun gagal.
+type LaunchAudit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	AgentID      uint   `gorm:"index" json:"agent_id"`
+	AgentCode    string `gorm:"size:50;index" json:"agent_code"`
+	UserCode     string `gorm:"size:100;index" json:"user_code"`
+	ProviderCode string `gorm:"size:32" json:"provider_code"`
+	GameCode     string `gorm:"size:100" json:"game_code"`
+	IP           string `gorm:"size:64" json:"ip"`
+	Platform     string `gorm:"size:32" json:"platform"`
+	// Result: SUCCESS atau kode error yang dikembalikan ke agent.
+	Result string `gorm:"size:64;index" json:"result"`
+	Error  string `gorm:"type:text" json:"error,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// LaunchToken adalah token opaque yang dikirim ke provider saat launch game
+// sebagai pengganti user_code. Callback provider me-resolve user dari token
+// ini; token terikat ke user + provider + game dan bisa di-revoke.
+type LaunchToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Token    string `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	UserID   uint   `gorm:"index;not null" json:"user_id"`
+	UserCode string `gorm:"size:100;index" json:"user_code"`
+	// Provider memakai nama provider di wallet (PRAGMATIC, PLAYSTAR, ...).
+	Provider string `gorm:"size:32;index" json:"provider"`
+	GameCode string `gorm:"size:100" json:"game_code"`
+
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+}
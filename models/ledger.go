@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrLedgerAppendOnly = errors.New("ledger entries are append-only")
+
+// LedgerEntry adalah satu sisi dari jurnal double-entry. Setiap pergerakan
+// saldo menulis minimal dua entry dengan JournalID yang sama dan total Amount
+// nol: sisi user (Account "USER:<user_code>") dan sisi lawan (provider, agent,
+// atau opening balance).
+//
+// Tabel ini append-only: koreksi dilakukan dengan jurnal baru, bukan update.
+type LedgerEntry struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	JournalID string `gorm:"size:64;index"`
+	Account   string `gorm:"size:96;index"`
+
+	// UserID hanya diisi untuk entry sisi user.
+	UserID   *uint           `gorm:"index"`
+	Amount   decimal.Decimal `gorm:"type:numeric(24,4);not null" json:"amount"`
+	Currency string          `gorm:"size:8"`
+
+	Provider            string `gorm:"size:32"`
+	Operation           string `gorm:"size:16"`
+	WalletTransactionID *uint  `gorm:"index"`
+	Note                string `gorm:"size:255"`
+}
+
+func (LedgerEntry) BeforeUpdate(*gorm.DB) error { return ErrLedgerAppendOnly }
+func (LedgerEntry) BeforeDelete(*gorm.DB) error { return ErrLedgerAppendOnly }
+
+// LedgerMismatch dicatat oleh job rekonsiliasi ketika saldo user di tabel
+// users berbeda dengan total ledger-nya.
+type LedgerMismatch struct {
+	gorm.Model
+
+	RunID    string `gorm:"size:64;index"`
+	UserID   uint   `gorm:"index"`
+	UserCode string `gorm:"size:32;index"`
+	Currency string `gorm:"size:8"`
+
+	UserBalance   decimal.Decimal `gorm:"type:numeric(24,4)" json:"user_balance"`
+	LedgerBalance decimal.Decimal `gorm:"type:numeric(24,4)" json:"ledger_balance"`
+	Drift         decimal.Decimal `gorm:"type:numeric(24,4)" json:"drift"`
+
+	CheckedAt time.Time `gorm:"index"`
+}
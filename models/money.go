@@ -0,0 +1,10 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Semua kolom uang (saldo, amount, before/after) memakai decimal.Decimal
+// dengan tipe kolom numeric(24,4) supaya tidak ada float drift. Untuk
+// kompatibilitas API, nilai decimal tetap dikirim sebagai angka di JSON.
+func init() {
+	decimal.MarshalJSONWithoutQuotes = true
+}
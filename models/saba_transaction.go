@@ -1,6 +1,9 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
 
 type SabaTransaction struct {
 	gorm.Model
@@ -9,21 +12,22 @@ type SabaTransaction struct {
 	UserCode  string `gorm:"size:32;index"` // kode user unik
 	AgentCode string `gorm:"size:32;index"` // kode agent
 
-	OperationID string `gorm:"size:64;index"` // unik dari SABA (idempotency check)
+	OperationID string `gorm:"size:64;index"` // operationId SABA terakhir yang mengubah bet
 	GameID      string `gorm:"size:64;index"` // pertandingan / event id
 	BetType     string `gorm:"size:32"`       // Single, Parlay, etc
 	Market      string `gorm:"size:32"`       // e.g. FT/HT
 	OddsType    string `gorm:"size:16"`
 	Currency    string `gorm:"size:8"`
 
-	BetAmount    float64 `json:"bet_amount"`    // jumlah bet
-	WinAmount    float64 `json:"win_amount"`    // jumlah kemenangan
-	RefundAmount float64 `json:"refund_amount"` // jumlah refund (jika cancel/unsettle)
+	BetAmount    decimal.Decimal `gorm:"type:numeric(24,4)" json:"bet_amount"`    // jumlah bet (satuan saldo user)
+	WinAmount    decimal.Decimal `gorm:"type:numeric(24,4)" json:"win_amount"`    // jumlah kemenangan
+	RefundAmount decimal.Decimal `gorm:"type:numeric(24,4)" json:"refund_amount"` // jumlah refund (jika cancel/unsettle)
 
-	BalanceBefore float64 `json:"balance_before"`
-	BalanceAfter  float64 `json:"balance_after"`
+	BalanceBefore decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_before"`
+	BalanceAfter  decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_after"`
 
 	Status string `gorm:"size:16;index"` // BET, CONFIRM, CANCEL, SETTLE, RESETTLE, UNSETTLE
 	Note   string `gorm:"size:255"`
-	RefID  string `gorm:"size:64;index"` // internal ref (misal SABABET-xxxx)
+	RefID  string `gorm:"size:64;index"` // refId SABA, kunci bet per user
+	TxID   string `gorm:"size:64;index"` // txId SABA, dikirim saat ConfirmBet
 }
@@ -8,12 +8,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// Session adalah SID Evolution: satu baris per launch, terpisah untuk
+// Evolution slot dan live (Provider).
 type Session struct {
 	gorm.Model
 	SID       string    `gorm:"size:36;uniqueIndex;not null"`
 	UserID    uint      `gorm:"index"`
 	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Provider  string    `gorm:"size:32;index"`
+	GameCode  string    `gorm:"size:100"`
 	ExpiresAt time.Time `gorm:"index"`
+	// RefreshedFrom: SID lama kalau session ini dibuat lewat endpoint /sid.
+	RefreshedFrom string     `gorm:"size:36"`
+	RevokedAt     *time.Time `gorm:"index"`
 }
 
 func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	StalePolicyAlert       = "alert"
+	StalePolicyRefund      = "refund"
+	StalePolicyCheckStatus = "check_status"
+
+	StaleAlertOpen     = "OPEN"
+	StaleAlertResolved = "RESOLVED"
+)
+
+// StaleRoundAlert: satu baris per bet/round yang masih terbuka melewati
+// batas waktu provider-nya. Ref memakai kunci yang sama dengan
+// UserGameTransaction.ProviderTx supaya satu bet tidak di-alert dua kali dari
+// tabel yang berbeda.
+type StaleRoundAlert struct {
+	gorm.Model
+
+	Provider  string          `gorm:"size:32;index:idx_stale_round_ref,unique" json:"provider"`
+	Ref       string          `gorm:"size:128;index:idx_stale_round_ref,unique" json:"ref"`
+	Source    string          `gorm:"size:32" json:"source"`
+	UserCode  string          `gorm:"size:32;index" json:"user_code"`
+	AgentCode string          `gorm:"size:32;index" json:"agent_code"`
+	Amount    decimal.Decimal `gorm:"type:numeric(24,4)" json:"amount"`
+	OpenedAt  time.Time       `json:"opened_at"`
+
+	Policy string `gorm:"size:16" json:"policy"`
+	// Action: hasil policy terakhir, mis. ALERTED, REFUNDED, REFUND_FAILED,
+	// PROVIDER_SETTLED, PROVIDER_RUNNING, PROVIDER_NOT_FOUND.
+	Action string `gorm:"size:32" json:"action"`
+	Detail string `gorm:"size:255" json:"detail"`
+	Status string `gorm:"size:16;index" json:"status"`
+
+	Checks        int        `json:"checks"`
+	LastCheckedAt time.Time  `json:"last_checked_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
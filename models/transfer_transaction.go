@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// TransferTransaction adalah satu transfer dari gateway transfer-style
+// (FastSpin, SpadeGaming). Tiap provider punya tabel sendiri dengan kolom
+// yang sama, lihat FastSpinTransaction dan SpadeGamingTransaction.
+type TransferTransaction struct {
+	gorm.Model
+	TransferID   string          `gorm:"uniqueIndex;size:50;not null" json:"transferId"` // unique transfer ID dari provider
+	MerchantCode string          `gorm:"size:50;not null" json:"merchantCode"`
+	MerchantTxID string          `gorm:"size:50" json:"merchantTxId"` // optional: ID internal merchant
+	AcctID       string          `gorm:"index;size:50;not null" json:"acctId"`
+	Currency     string          `gorm:"size:10;not null" json:"currency"`
+	Amount       decimal.Decimal `gorm:"type:decimal(20,9);not null" json:"amount"`
+	Type         int             `gorm:"not null" json:"type"` // 1=bet, 2=cancel, 4=payout, 7=bonus, 8=jackpot
+	TicketID     string          `gorm:"size:50;index" json:"ticketId"`
+	Channel      string          `gorm:"size:20" json:"channel"`
+	GameCode     string          `gorm:"size:20" json:"gameCode"`
+	ReferenceID  string          `gorm:"size:50;index" json:"referenceId"`
+	PlayerIP     string          `gorm:"size:50" json:"playerIp"`
+	GameFeature  string          `gorm:"size:50" json:"gameFeature"`
+	TransferTime string          `gorm:"size:20" json:"transferTime"`
+
+	// Extra fields for payout / special game
+	SpecialType  string `gorm:"size:20" json:"specialType"`
+	SpecialCount int    `json:"specialCount"`
+	SpecialSeq   int    `json:"specialSeq"`
+	RefTicketIds string `gorm:"type:text" json:"refTicketIds"` // bisa simpan JSON array string
+
+	// Status & balance info
+	BalanceBefore decimal.Decimal `gorm:"type:decimal(20,4)" json:"balanceBefore"`
+	BalanceAfter  decimal.Decimal `gorm:"type:decimal(20,4)" json:"balanceAfter"`
+	Status        string          `gorm:"size:20;default:'Success'" json:"status"` // Success, Failed, Pending, Cancelled (bet yang sudah di-cancel)
+	Msg           string          `gorm:"size:255" json:"msg"`
+	Code          int             `json:"code"`
+	SerialNo      string          `gorm:"size:50" json:"serialNo"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UsedNonce mencatat nonce signature agent yang sudah dipakai. AgentCode +
+// Nonce unik sehingga replay ditolak di semua instance; baris yang sudah lewat
+// ExpiresAt tidak berarti lagi (timestamp request-nya di luar window) dan
+// dihapus oleh job.
+type UsedNonce struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	AgentCode string    `gorm:"size:32;uniqueIndex:idx_used_nonce"`
+	Nonce     string    `gorm:"size:64;uniqueIndex:idx_used_nonce"`
+	ExpiresAt time.Time `gorm:"index"`
+}
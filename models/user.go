@@ -1,18 +1,24 @@
 package models
 
 import (
+	"time"
+
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
 
-	UserCode         string                `gorm:"uniqueIndex;size:32" json:"user_code"`
-	AgentCode        string                `gorm:"index;size:32" json:"agent_code"`
-	Balance          float64               `json:"balance"`
-	Country          string                `gorm:"size:64" json:"country"`
-	Currency         string                `gorm:"size:8" json:"currency"`
-	IsActive         bool                  `gorm:"default:true" json:"is_active"`
+	UserCode  string          `gorm:"uniqueIndex;size:32" json:"user_code"`
+	AgentCode string          `gorm:"index;size:32" json:"agent_code"`
+	Balance   decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"balance"`
+	Country   string          `gorm:"size:64" json:"country"`
+	Currency  string          `gorm:"size:8" json:"currency"`
+	IsActive  bool            `gorm:"default:true" json:"is_active"`
+	// ClosedAt terisi kalau akun ditutup permanen; akun tertutup tidak bisa
+	// diaktifkan lagi.
+	ClosedAt         *time.Time            `json:"closed_at,omitempty"`
 	Transactions     []UserTransaction     `gorm:"foreignKey:UserID"`
 	GameTransactions []UserGameTransaction `gorm:"foreignKey:UserID"`
 }
@@ -20,16 +26,16 @@ type User struct {
 type UserTransaction struct {
 	gorm.Model
 
-	UserID        uint    `gorm:"index"`
-	AgentCode     string  `gorm:"index;size:32"`
-	UserCode      string  `gorm:"size:32"`
-	TrxType       string  `gorm:"size:16"`
-	Amount        int64   `json:"amount"`
-	BalanceBefore float64 `json:"balance_before"`
-	BalanceAfter  float64 `json:"balance_after"`
-	Currency      string  `gorm:"size:8" json:"currency"`
-	Note          string  `gorm:"size:255"`
-	RefID         string  `gorm:"size:64"`
+	UserID        uint            `gorm:"index"`
+	AgentCode     string          `gorm:"index;size:32"`
+	UserCode      string          `gorm:"size:32"`
+	TrxType       string          `gorm:"size:16"`
+	Amount        decimal.Decimal `gorm:"type:numeric(24,4)" json:"amount"`
+	BalanceBefore decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_before"`
+	BalanceAfter  decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_after"`
+	Currency      string          `gorm:"size:8" json:"currency"`
+	Note          string          `gorm:"size:255"`
+	RefID         string          `gorm:"size:64;index"`
 }
 
 type UserGameTransaction struct {
@@ -41,19 +47,24 @@ type UserGameTransaction struct {
 
 	GameID     string `gorm:"size:64;index"`
 	SubGameID  uint16 `gorm:"index"`
-	ProviderTx string `gorm:"size:64;index:idx_provider_tx,unique"`
-	Provider   string `gorm:"size:32;index:idx_provider_tx,unique"`
+	ProviderTx string `gorm:"size:128;index:idx_provider_tx,unique"`
+	Provider   string `gorm:"size:32;index;index:idx_provider_tx,unique"`
 
-	BetAmount   int64   `json:"bet_amount"`
-	WinAmount   int64   `json:"win_amount"`
-	BonusAmount int64   `json:"bonus_amount"`
-	JPContrib   float64 `json:"jp_contrib"`
-	Currency    string  `gorm:"size:8"`
+	// Semua amount dalam satuan saldo user (bukan cents).
+	BetAmount   decimal.Decimal `gorm:"type:numeric(24,4)" json:"bet_amount"`
+	WinAmount   decimal.Decimal `gorm:"type:numeric(24,4)" json:"win_amount"`
+	BonusAmount decimal.Decimal `gorm:"type:numeric(24,4)" json:"bonus_amount"`
+	// RefundAmount: stake yang dikembalikan lewat cancel/refund.
+	RefundAmount decimal.Decimal `gorm:"type:numeric(24,4);default:0" json:"refund_amount"`
+	JPContrib    decimal.Decimal `gorm:"type:numeric(24,6)" json:"jp_contrib"`
+	Currency     string          `gorm:"size:8"`
 
-	BalanceBefore float64 `json:"balance_before"`
-	BalanceAfter  float64 `json:"balance_after"`
+	BalanceBefore decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_before"`
+	BalanceAfter  decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_after"`
 
 	Status string `gorm:"size:16;index"`
 	Note   string `gorm:"size:255"`
 	RefID  string `gorm:"size:64;index"`
+	// RoundID: id round/ticket dari provider (bisa sama untuk beberapa bet).
+	RoundID string `gorm:"size:128;index" json:"round_id"`
 }
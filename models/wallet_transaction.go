@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// WalletTransaction adalah satu baris per pergerakan saldo user yang
+// diproses lewat services/wallet.
+type WalletTransaction struct {
+	gorm.Model
+
+	UserID    uint   `gorm:"index;index:idx_wallet_external,unique,where:external_id <> ''"`
+	UserCode  string `gorm:"size:32;index"`
+	AgentCode string `gorm:"size:32;index"`
+
+	Provider   string `gorm:"size:32;index:idx_wallet_external"`
+	Operation  string `gorm:"size:16;index:idx_wallet_external"`
+	ExternalID string `gorm:"size:128;index:idx_wallet_external"`
+
+	Amount        decimal.Decimal `gorm:"type:numeric(24,4)" json:"amount"`
+	BalanceBefore decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_before"`
+	BalanceAfter  decimal.Decimal `gorm:"type:numeric(24,4)" json:"balance_after"`
+	Currency      string          `gorm:"size:8"`
+
+	RefID string `gorm:"size:64;index"`
+	Note  string `gorm:"size:255"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql/driver"
+	"github.com/shopspring/decimal"
 	"strings"
 	"time"
 
@@ -70,11 +71,11 @@ func (wt WinTime) Value() (driver.Value, error) {
 type X568WinTransaction struct {
 	gorm.Model
 
-	CompanyKey    string `gorm:"size:100;index"`
-	Username      string `gorm:"size:100;index;index:uk_sbo_transfer_user,unique"`
-	Amount        float64
-	TransferCode  string `gorm:"size:100;index;index:uk_sbo_transfer_user"`
-	TransactionId string `gorm:"size:100;index"`
+	CompanyKey    string          `gorm:"size:100;index"`
+	Username      string          `gorm:"size:100;index;index:uk_sbo_transfer_user,unique"`
+	Amount        decimal.Decimal `gorm:"type:numeric(24,4)"`
+	TransferCode  string          `gorm:"size:100;index;index:uk_sbo_transfer_user"`
+	TransactionId string          `gorm:"size:100;index"`
 	BetTime       time.Time
 	ProductType   int
 	GameType      int
@@ -86,11 +87,11 @@ type X568WinTransaction struct {
 	Gpid          int `gorm:"default:-1"`
 	GameId        int `gorm:"default:0"`
 
-	ExtraInfo datatypes.JSON `gorm:"type:jsonb"`
-	Status    string         `gorm:"size:50;index"`
-	WinLoss   float64        `gorm:"default:0"`
-	Rollback  bool           `gorm:"default:false"`
-	IsCashOut bool           `gorm:"default:false"`
+	ExtraInfo datatypes.JSON  `gorm:"type:jsonb"`
+	Status    string          `gorm:"size:50;index"`
+	WinLoss   decimal.Decimal `gorm:"type:numeric(24,4);default:0"`
+	Rollback  bool            `gorm:"default:false"`
+	IsCashOut bool            `gorm:"default:false"`
 
 	ResultType int `gorm:"default:0"`
 	ResultTime *time.Time
@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("AllBet", &Win568AllBet{
+	providers.RegisterProvider("AllBet", providers.CategoryLiveCasino, &Win568AllBet{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("PLAYACE", &Win568AsiaGaming{
+	providers.RegisterProvider("PLAYACE", providers.CategoryLiveCasino, &Win568AsiaGaming{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
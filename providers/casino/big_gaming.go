@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("BigGaming", &Win568BigGaming{
+	providers.RegisterProvider("BigGaming", providers.CategoryLiveCasino, &Win568BigGaming{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
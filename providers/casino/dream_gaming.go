@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("DreamGaming", &Win568DreamGaming{
+	providers.RegisterProvider("DreamGaming", providers.CategoryLiveCasino, &Win568DreamGaming{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
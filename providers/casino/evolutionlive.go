@@ -13,10 +13,10 @@ import (
 	"telo/database"
 	"telo/models"
 	"telo/providers"
+	"telo/services/evosession"
 	"time"
 
 	"github.com/joho/godotenv"
-	"gorm.io/gorm"
 )
 
 type EvolutionLive struct {
@@ -37,34 +37,16 @@ func (p *EvolutionLive) StartGame(req providers.LaunchRequest) (string, error) {
 		log.Printf("❌ [StartGame] User not found in DB: %s | Error: %v", req.UserCode, err)
 		return "", fmt.Errorf("user not found: %w", err)
 	}
-	log.Printf("✅ [StartGame] User found: ID=%d | Code=%s | Balance=%.2f | Country=%s | Currency=%s",
+	log.Printf("✅ [StartGame] User found: ID=%d | Code=%s | Balance=%s | Country=%s | Currency=%s",
 		user.ID, user.UserCode, user.Balance, user.Country, user.Currency)
 
-	// === 3. Buat atau perbarui session ===
-	var session models.Session
-	if err := database.DB.Where("user_id = ?", user.ID).First(&session).Error; err == nil {
-		log.Printf("[StartGame] 🔁 Existing session found: SID=%s | ExpiredAt=%v", session.SID, session.ExpiresAt)
-		session.ExpiresAt = time.Now().Add(24 * time.Hour)
-		if err := database.DB.Save(&session).Error; err != nil {
-			log.Printf("⚠️ [StartGame] Failed to update session expiry: %v", err)
-		} else {
-			log.Printf("✅ [StartGame] Session expiry updated: %v", session.ExpiresAt)
-		}
-	} else if errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("[StartGame] 🆕 No session found for user %s, creating a new one...", user.UserCode)
-		session = models.Session{
-			UserID:    user.ID,
-			ExpiresAt: time.Now().Add(24 * time.Hour),
-		}
-		if err := database.DB.Create(&session).Error; err != nil {
-			log.Printf("❌ [StartGame] Failed to create new session: %v", err)
-			return "", err
-		}
-		log.Printf("✅ [StartGame] New session created: SID=%s | ExpiresAt=%v", session.SID, session.ExpiresAt)
-	} else {
-		log.Printf("❌ [StartGame] Unexpected DB error during session lookup: %v", err)
+	// === 3. Buat session baru untuk launch ini ===
+	session, err := evosession.Create(database.DB, user, evosession.ProviderLive, req.GameCode)
+	if err != nil {
+		log.Printf("❌ [StartGame] Failed to create new session: %v", err)
 		return "", err
 	}
+	log.Printf("✅ [StartGame] New session created: SID=%s | ExpiresAt=%v", session.SID, session.ExpiresAt)
 
 	// === 4. Generate UUID ===
 	uuid := fmt.Sprintf("req-%s", req.UserCode)
@@ -185,7 +167,7 @@ func init() {
 		panic("❌ ENV EVOLUTION_API_URL not set")
 	}
 
-	providers.RegisterProvider("EVOLUTIONLIVE", &EvolutionLive{
+	providers.RegisterProvider("EVOLUTIONLIVE", providers.CategoryLiveCasino, &EvolutionLive{
 		ApiURL: apiURL,
 	})
 }
@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Ezugi", &Win568Ezugi{
+	providers.RegisterProvider("Ezugi", providers.CategoryLiveCasino, &Win568Ezugi{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Playtech", &Win568PlayTech{
+	providers.RegisterProvider("Playtech", providers.CategoryLiveCasino, &Win568PlayTech{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
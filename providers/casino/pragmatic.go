@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("PragmaticLive", &Win568PPLive{
+	providers.RegisterProvider("PragmaticLive", providers.CategoryLiveCasino, &Win568PPLive{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("AeSexy", &Win568SexyGaming{
+	providers.RegisterProvider("AeSexy", providers.CategoryLiveCasino, &Win568SexyGaming{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("WCasino", &Win568WCasino{
+	providers.RegisterProvider("WCasino", providers.CategoryLiveCasino, &Win568WCasino{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
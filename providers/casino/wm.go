@@ -110,7 +110,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("WanMei", &Win568WanMei{
+	providers.RegisterProvider("WanMei", providers.CategoryLiveCasino, &Win568WanMei{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
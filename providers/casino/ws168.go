@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("ws168", &Win568WS168{
+	providers.RegisterProvider("ws168", providers.CategoryLiveCasino, &Win568WS168{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -19,10 +19,55 @@ type GameProviderLauncher interface {
 	StartGame(req LaunchRequest) (string, error)
 }
 
+const (
+	CategorySlots      = "slots"
+	CategoryLiveCasino = "live_casino"
+	CategorySportsbook = "sportsbook"
+)
+
+var Categories = []string{CategorySlots, CategoryLiveCasino, CategorySportsbook}
+
 var GameLaunchers = map[string]GameProviderLauncher{}
 
-func RegisterProvider(name string, launcher GameProviderLauncher) {
+// ProviderCategories: kode provider (lowercase) → kategori.
+var ProviderCategories = map[string]string{}
+
+func RegisterProvider(name, category string, launcher GameProviderLauncher) {
 	GameLaunchers[strings.ToLower(name)] = launcher
+	ProviderCategories[strings.ToLower(name)] = category
+}
+
+func GetCategory(name string) string {
+	return ProviderCategories[strings.ToLower(name)]
+}
+
+func IsCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// walletProviderCategories memetakan nama provider di wallet_transactions
+// (callback seamless) ke kategori, dipakai untuk rate GGR per kategori.
+var walletProviderCategories = map[string]string{
+	"PRAGMATIC":      CategorySlots,
+	"TELO":           CategorySlots,
+	"PLAYSTAR":       CategorySlots,
+	"FASTSPIN":       CategorySlots,
+	"SPADEGAMING":    CategorySlots,
+	"EVOLUTION_SLOT": CategorySlots,
+	"EVOLUTION_LIVE": CategoryLiveCasino,
+	"SBO":            CategorySportsbook,
+	"SABA":           CategorySportsbook,
+	// transaksi Evolution lama sebelum slot dan live dipisah
+	"EVOLUTION": CategoryLiveCasino,
+}
+
+func WalletProviderCategory(provider string) string {
+	return walletProviderCategories[strings.ToUpper(provider)]
 }
 
 func GetProvider(name string) GameProviderLauncher {
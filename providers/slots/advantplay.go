@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("AdvantPlay", &Win568Advanplay{
+	providers.RegisterProvider("AdvantPlay", providers.CategorySlots, &Win568Advanplay{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
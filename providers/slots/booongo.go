@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Booongo", &Win568Booongo{
+	providers.RegisterProvider("Booongo", providers.CategorySlots, &Win568Booongo{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
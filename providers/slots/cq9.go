@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("CQ9", &Win568CQ9{
+	providers.RegisterProvider("CQ9", providers.CategorySlots, &Win568CQ9{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
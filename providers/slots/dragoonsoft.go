@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Dragoonsoft", &Win568Dragoonsoft{
+	providers.RegisterProvider("Dragoonsoft", providers.CategorySlots, &Win568Dragoonsoft{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
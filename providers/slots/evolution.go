@@ -14,10 +14,10 @@ import (
 	"telo/database"
 	"telo/models"
 	"telo/providers"
+	"telo/services/evosession"
 	"time"
 
 	"github.com/joho/godotenv"
-	"gorm.io/gorm"
 )
 
 type EvolutionSlot struct {
@@ -35,16 +35,11 @@ func (p *EvolutionSlot) StartGame(req providers.LaunchRequest) (string, error) {
 
 	uuid := fmt.Sprintf("req-%s", req.UserCode)
 
-	var session models.Session
-	if err := database.DB.Where("user_id = ?", user.ID).First(&session).Error; err == nil {
-		session.ExpiresAt = time.Now().Add(24 * time.Hour)
-		database.DB.Save(&session)
-	} else if errors.Is(err, gorm.ErrRecordNotFound) {
-		session = models.Session{
-			UserID:    user.ID,
-			ExpiresAt: time.Now().Add(24 * time.Hour),
-		}
-		database.DB.Create(&session)
+	// Session baru per launch; SID slot tidak berlaku untuk Evolution live.
+	session, err := evosession.Create(database.DB, user, evosession.ProviderSlot, req.GameCode)
+	if err != nil {
+		log.Printf("❌ [StartGame] Failed to create session: %v", err)
+		return "", err
 	}
 
 	parts := strings.Split(user.UserCode, "_")
@@ -179,7 +174,7 @@ func init() {
 		panic("❌ ENV EVOLUTION_API_URL not set")
 	}
 
-	providers.RegisterProvider("EVOLUTIONSLOT", &EvolutionSlot{
+	providers.RegisterProvider("EVOLUTIONSLOT", providers.CategorySlots, &EvolutionSlot{
 		ApiURL: apiURL,
 	})
 }
@@ -12,6 +12,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/shopspring/decimal"
+
 	"telo/database"
 	"telo/models"
 	"telo/providers"
@@ -125,8 +127,8 @@ func (p *FastSpinLauncher) StartGame(req providers.LaunchRequest) (string, error
 }
 
 // Helper function to format balance
-func formatBalance(balance float64) float64 {
-	return float64(int(balance*100)) / 100
+func formatBalance(balance decimal.Decimal) decimal.Decimal {
+	return balance.Truncate(2)
 }
 
 // Helper function to convert platform to mobile boolean
@@ -163,7 +165,7 @@ func getLanguageCode(lang string) string {
 }
 
 func init() {
-	providers.RegisterProvider("FASTSPIN", &FastSpinLauncher{
+	providers.RegisterProvider("FASTSPIN", providers.CategorySlots, &FastSpinLauncher{
 		ApiURL: os.Getenv("FASTSPIN_API_URL") + "/getAuthorize",
 	})
 }
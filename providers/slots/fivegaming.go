@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("FiveGaming", &Win568FiveGaming{
+	providers.RegisterProvider("FiveGaming", providers.CategorySlots, &Win568FiveGaming{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Habanero", &Win568Habanero{
+	providers.RegisterProvider("Habanero", providers.CategorySlots, &Win568Habanero{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
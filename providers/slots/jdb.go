@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("JDB", &Win568JDB{
+	providers.RegisterProvider("JDB", providers.CategorySlots, &Win568JDB{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Jili", &Win568Jili{
+	providers.RegisterProvider("Jili", providers.CategorySlots, &Win568Jili{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
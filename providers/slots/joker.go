@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("JokerGaming", &Win568Joker{
+	providers.RegisterProvider("JokerGaming", providers.CategorySlots, &Win568Joker{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
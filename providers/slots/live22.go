@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Live22", &Win568Live22{
+	providers.RegisterProvider("Live22", providers.CategorySlots, &Win568Live22{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("MicroGaming", &Win568MicroGaming{
+	providers.RegisterProvider("MicroGaming", providers.CategorySlots, &Win568MicroGaming{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
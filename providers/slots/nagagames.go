@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("NagaGames", &Win568NagaGames{
+	providers.RegisterProvider("NagaGames", providers.CategorySlots, &Win568NagaGames{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("NextSpin", &Win568Nextspin{
+	providers.RegisterProvider("NextSpin", providers.CategorySlots, &Win568Nextspin{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
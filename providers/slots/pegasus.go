@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Pegasus", &Win568Pegasus{
+	providers.RegisterProvider("Pegasus", providers.CategorySlots, &Win568Pegasus{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
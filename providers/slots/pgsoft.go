@@ -111,7 +111,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("PGSoft", &Win568PGsoft{
+	providers.RegisterProvider("PGSoft", providers.CategorySlots, &Win568PGsoft{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
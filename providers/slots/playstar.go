@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"os"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"telo/database"
 	"telo/models"
 	"telo/providers"
+	"telo/services/launchtoken"
 
 	"github.com/joho/godotenv"
 )
@@ -33,6 +35,16 @@ func (p *Win568Playstar) StartGame(req providers.LaunchRequest) (string, error)
 		return "", fmt.Errorf("user not found: %w", err)
 	}
 
+	// Token opaque untuk callback menggantikan user_code sebagai token.
+	// Belum ada kontrak Win568 yang memastikan field "Token" diteruskan ke
+	// PlayStar, jadi callback masih menerima user_code selama
+	// launchtoken.LegacyFallback aktif.
+	token, err := launchtoken.Issue(database.DB, user, "PLAYSTAR", req.GameCode)
+	if err != nil {
+		log.Printf("❌ [StartGame] Failed to issue launch token: %v", err)
+		return "", err
+	}
+
 	username := req.UserCode
 	if len(username) < 6 {
 		username = fmt.Sprintf("%s_user", username)
@@ -48,6 +60,7 @@ func (p *Win568Playstar) StartGame(req providers.LaunchRequest) (string, error)
 		"Device":     map[string]string{"mobile": "m", "desktop": "d"}[req.Platform],
 		"GpId":       "1044",
 		"GameId":     req.GameCode,
+		"Token":      token,
 	}
 
 	jsonBody, err := json.Marshal(payload)
@@ -57,7 +70,9 @@ func (p *Win568Playstar) StartGame(req providers.LaunchRequest) (string, error)
 	}
 
 	log.Printf("📤 [StartGame] URL: %s", p.ApiURL)
-	log.Printf("📤 [StartGame] Payload: %s", string(jsonBody))
+	logged := maps.Clone(payload)
+	logged["Token"] = "[redacted]"
+	log.Printf("📤 [StartGame] Payload: %v", logged)
 
 	// 🔹 Kirim request
 	resp, err := http.Post(p.ApiURL+"/web-root/restricted/player/v2/login.aspx", "application/json", bytes.NewBuffer(jsonBody))
@@ -111,7 +126,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("Playstar", &Win568Playstar{
+	providers.RegisterProvider("Playstar", providers.CategorySlots, &Win568Playstar{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -119,7 +119,7 @@ func (p *SpadeGamingLauncher) StartGame(req providers.LaunchRequest) (string, er
 }
 
 func init() {
-	providers.RegisterProvider("SPADEGAMING", &SpadeGamingLauncher{
+	providers.RegisterProvider("SPADEGAMING", providers.CategorySlots, &SpadeGamingLauncher{
 		ApiURL: os.Getenv("SPADE_GAMING_API_URL") + "/",
 	})
 }
@@ -71,7 +71,7 @@ func (p *TeloLauncherPG) StartGame(req providers.LaunchRequest) (string, error)
 }
 
 func init() {
-	providers.RegisterProvider("TPGSOFT", &TeloLauncherPG{
+	providers.RegisterProvider("TPGSOFT", providers.CategorySlots, &TeloLauncherPG{
 		ApiURL: "https://api.telo.is/api/v2/game_launch",
 	})
 }
@@ -71,7 +71,7 @@ func (p *TeloLauncherPP) StartGame(req providers.LaunchRequest) (string, error)
 }
 
 func init() {
-	providers.RegisterProvider("TPRAGMATIC", &TeloLauncherPP{
+	providers.RegisterProvider("TPRAGMATIC", providers.CategorySlots, &TeloLauncherPP{
 		ApiURL: "https://api.telo.is/api/v2/game_launch",
 	})
 }
@@ -119,7 +119,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("afb", &Win568AFB{
+	providers.RegisterProvider("afb", providers.CategorySportsbook, &Win568AFB{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -119,7 +119,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("bti", &Win568BTI{
+	providers.RegisterProvider("bti", providers.CategorySportsbook, &Win568BTI{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -119,7 +119,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("saba", &Win568Saba{
+	providers.RegisterProvider("saba", providers.CategorySportsbook, &Win568Saba{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
@@ -113,7 +113,7 @@ func init() {
 		panic("❌ ENV WIN568_API_URL / COMPANY_KEY / SERVER_ID not set")
 	}
 
-	providers.RegisterProvider("sbo", &Win568{
+	providers.RegisterProvider("sbo", providers.CategorySportsbook, &Win568{
 		ApiURL:     apiURL,
 		CompanyKey: companyKey,
 		ServerID:   serverID,
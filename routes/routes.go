@@ -2,13 +2,12 @@ package routes
 
 import (
 	"telo/controllers/agent"
-	"telo/controllers/callback/live_casino/evolutionlive"
-	"telo/controllers/callback/slots/evolutionslot"
-	"telo/controllers/callback/slots/fastspin"
+	"telo/controllers/callback/evolution"
 	"telo/controllers/callback/slots/playstar"
 	"telo/controllers/callback/slots/pragmatic"
-	"telo/controllers/callback/slots/spadegaming"
 	"telo/controllers/callback/slots/telo"
+	"telo/controllers/callback/slots/transfergateway"
+	"telo/controllers/callback/sportsbook/saba"
 	"telo/controllers/callback/sportsbook/sbo"
 	"telo/controllers/user"
 	"telo/middlewares"
@@ -17,16 +16,45 @@ import (
 )
 
 func Setup(app *fiber.App) {
+	// didaftarkan sebelum group /user supaya launch dari agent non-aktif tetap
+	// tercatat di audit sebelum ditolak
+	app.Post("/user/games/start", middlewares.UserAuthAllowInactive, user.LaunchGameHandler)
+
 	userroutes := app.Group("/user", middlewares.UserAuthMiddleware)
 	userroutes.Post("/balance", user.CheckUserBalance)
 	userroutes.Post("/register", user.RegisterUser)
 	userroutes.Post("/transfer", user.TransferBalance)
-	userroutes.Post("/games/start", user.LaunchGameHandler)
+	userroutes.Post("/transfer/status", user.TransferStatus)
+	userroutes.Post("/suspend", user.SuspendUser)
+	userroutes.Post("/reactivate", user.ReactivateUser)
+	userroutes.Post("/close", user.CloseUser)
+	userroutes.Post("/logout", user.LogoutUser)
+	userroutes.Post("/sessions/revoke", user.RevokeSessions)
+	userroutes.Post("/transactions", user.ListUserTransactions)
+	userroutes.Post("/game-history", user.GameHistory)
+	userroutes.Post("/game-rounds", user.GameRounds)
+
+	app.Post("/agent/info", middlewares.UserAuthMiddleware, agent.AgentInfo)
+
+	// didaftarkan sebelum group /agent supaya tidak kena AgentAuth (master env)
+	downline := app.Group("/agent/downline", middlewares.UserAuthMiddleware)
+	downline.Post("/register", agent.RegisterSubAgent)
+	downline.Post("/topup", agent.TopupSubAgent)
+	downline.Post("/summary", agent.DownlineSummary)
+	downline.Post("/settings", agent.UpdateDownlineSettings)
+	app.Post("/agent/secret/rotate", middlewares.UserAuthMiddleware, agent.RotateSecret)
+	app.Post("/agent/settlements", middlewares.UserAuthMiddleware, agent.ListSettlements)
+	app.Post("/agent/settings", middlewares.UserAuthMiddleware, agent.GetSettings)
+	app.Post("/agent/transactions", middlewares.UserAuthMiddleware, agent.ListAgentTransactions)
 
-	app.Post("/agent/info", agent.AgentInfo)
 	agentroutes := app.Group("/agent", middlewares.AgentAuth())
 	agentroutes.Post("/register", agent.RegisterAgent)
 	agentroutes.Post("/topup", agent.TopupAgentBalance)
+	agentroutes.Post("/rotate-secret", agent.RotateAgentSecret)
+	agentroutes.Post("/settings/update", agent.UpdateAgentSettings)
+	agentroutes.Post("/wallet/config", agent.UpdateWalletConfig)
+	agentroutes.Post("/suspend", agent.SuspendAgent)
+	agentroutes.Post("/reactivate", agent.ReactivateAgent)
 
 	//providers
 	teloroutes := app.Group("/seamless/slot/gold_api", middlewares.TeloAgentAuth())
@@ -46,38 +74,38 @@ func Setup(app *fiber.App) {
 	sboroutes.Post("/Rollback", sbo.RollbackBetHandler)
 	sboroutes.Post("/Bonus", sbo.BonusCreditHandler)
 
-	//evolutionslot
-	evo := app.Group("/seamless/live-slot/evolution", middlewares.CheckEvolutionToken())
-	evo.Post("/check", evolutionslot.BalanceHandler)
-	evo.Post("/balance", evolutionslot.BalanceHandler)
-	evo.Post("/debit", evolutionslot.DebitHandler)
-	evo.Post("/credit", evolutionslot.CreditHandler)
-	evo.Post("/cancel", evolutionslot.CancelHandler)
-	evo.Post("/sid", evolutionslot.UserHandler)
-
-	//evolutionlive
-	evolive := app.Group("/seamless/live-casino/evolution", middlewares.CheckEvolutionTokenLive())
-	evolive.Post("/check", evolutionlive.BalanceHandler)
-	evolive.Post("/balance", evolutionlive.BalanceHandler)
-	evolive.Post("/debit", evolutionlive.DebitHandler)
-	evolive.Post("/credit", evolutionlive.CreditHandler)
-	evolive.Post("/cancel", evolutionlive.CancelHandler)
-	evolive.Post("/sid", evolutionlive.UserHandler)
-
-	//fs
-	app.Post("/seamless/slot/fastspin", fastspin.GatewayHandler)
-	app.Post("/seamless/slot/spadegaming", spadegaming.GatewayHandler)
+	//saba
+	sabaroutes := app.Group("/seamless/sportsbook/saba", middlewares.SabaAuth())
+	sabaroutes.Post("/getbalance", saba.GetBalanceHandler)
+	sabaroutes.Post("/placebet", saba.PlaceBetHandler)
+	sabaroutes.Post("/confirmbet", saba.ConfirmBetHandler)
+	sabaroutes.Post("/cancelbet", saba.CancelBetHandler)
+	sabaroutes.Post("/settle", saba.SettleHandler)
+	sabaroutes.Post("/resettle", saba.ResettleHandler)
+	sabaroutes.Post("/unsettle", saba.UnsettleHandler)
+	sabaroutes.Post("/placebetparlay", saba.PlaceBetParlayHandler)
+
+	//evolution: satu implementasi wallet, dipasang per produk
+	evo := app.Group("/seamless/live-slot/evolution", middlewares.CheckEvolutionToken(evolution.Slot.AuthTokenEnv))
+	evolution.Mount(evo, evolution.Slot)
+
+	evolive := app.Group("/seamless/live-casino/evolution", middlewares.CheckEvolutionToken(evolution.Live.AuthTokenEnv))
+	evolution.Mount(evolive, evolution.Live)
+
+	//fastspin & spadegaming: satu gateway transfer, beda konfigurasi merchant
+	app.Post("/seamless/slot/fastspin", middlewares.FastSpinAuth(), transfergateway.FastSpin.GatewayHandler)
+	app.Post("/seamless/slot/spadegaming", middlewares.SpadeGamingAuth(), transfergateway.SpadeGaming.GatewayHandler)
 
 	//playstar
-	psroutes := app.Group("/seamless/slot/api")
-	psroutes.Get("/bet", playstar.BetHandler)
-	psroutes.Get("/result", playstar.ResultHandler)
-	psroutes.Get("/refund", playstar.RefundHandler)
-	psroutes.Get("/bonusaward", playstar.BonusAwardHandler)
-	psroutes.Get("/getbalance", playstar.GetBalanceHandler)
+	psroutes := app.Group("/seamless/slot/api", middlewares.IPAllowlist("PLAYSTAR", fiber.Map{"status_code": 5}))
+	psroutes.Get("/bet", middlewares.PlaystarAuth(true), playstar.BetHandler)
+	psroutes.Get("/result", middlewares.PlaystarAuth(false), playstar.ResultHandler)
+	psroutes.Get("/refund", middlewares.PlaystarAuth(false), playstar.RefundHandler)
+	psroutes.Get("/bonusaward", middlewares.PlaystarAuth(false), playstar.BonusAwardHandler)
+	psroutes.Get("/getbalance", middlewares.PlaystarAuth(true), playstar.GetBalanceHandler)
 
 	//pragmatic
-	prroutes := app.Group("/seamless/provider/pragmatic/")
+	prroutes := app.Group("/seamless/provider/pragmatic/", middlewares.PragmaticHash())
 	prroutes.Post("/authenticate", pragmatic.AuthenticateHandler)
 	prroutes.Post("/balance", pragmatic.Balance)
 	prroutes.Post("/bet", pragmatic.Bet)
@@ -0,0 +1,136 @@
+package agentsettings
+
+import (
+	"errors"
+	"strings"
+
+	"telo/models"
+	"telo/providers"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownCategory = errors.New("agentsettings: unknown category")
+	ErrUnknownProvider = errors.New("agentsettings: unknown provider")
+	ErrInvalidRate     = errors.New("agentsettings: rate must be between 0 and 100")
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// IsProviderEnabled: override provider menang; kalau tidak ada, ikut
+// setting kategori; default aktif.
+func IsProviderEnabled(db *gorm.DB, agentID uint, providerCode string) (bool, error) {
+	code := strings.ToLower(providerCode)
+
+	var ps models.AgentProviderSetting
+	err := db.Where("agent_id = ? AND provider_code = ?", agentID, code).First(&ps).Error
+	if err == nil {
+		return ps.Enabled, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	category := providers.GetCategory(code)
+	if category == "" {
+		return true, nil
+	}
+
+	var cs models.AgentCategorySetting
+	err = db.Where("agent_id = ? AND category = ?", agentID, category).First(&cs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return cs.Enabled, nil
+}
+
+// GGRRate mengembalikan rate GGR (persen) agent untuk kategori; fallback ke
+// Agent.GGR.
+func GGRRate(db *gorm.DB, agent models.Agent, category string) (decimal.Decimal, error) {
+	fallback := decimal.NewFromFloat(agent.GGR)
+	if category == "" {
+		return fallback, nil
+	}
+
+	var cs models.AgentCategorySetting
+	err := db.Where("agent_id = ? AND category = ?", agent.ID, category).First(&cs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fallback, nil
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !cs.GGRRate.Valid {
+		return fallback, nil
+	}
+	return cs.GGRRate.Decimal, nil
+}
+
+type CategoryUpdate struct {
+	Category string               `json:"category"`
+	Enabled  *bool                `json:"enabled"`
+	GGRRate  *decimal.NullDecimal `json:"ggr_rate"`
+}
+
+type ProviderUpdate struct {
+	ProviderCode string `json:"provider_code"`
+	// Enabled nil = hapus override, kembali ikut kategori.
+	Enabled *bool `json:"enabled"`
+}
+
+// Apply menyimpan perubahan setting agent dalam satu transaction.
+func Apply(db *gorm.DB, agentID uint, categories []CategoryUpdate, provs []ProviderUpdate) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, u := range categories {
+			if !providers.IsCategory(u.Category) {
+				return ErrUnknownCategory
+			}
+
+			cs := models.AgentCategorySetting{AgentID: agentID, Category: u.Category, Enabled: true}
+			if err := tx.Where("agent_id = ? AND category = ?", agentID, u.Category).
+				Attrs(cs).FirstOrInit(&cs).Error; err != nil {
+				return err
+			}
+			if u.Enabled != nil {
+				cs.Enabled = *u.Enabled
+			}
+			if u.GGRRate != nil {
+				if u.GGRRate.Valid && (u.GGRRate.Decimal.IsNegative() || u.GGRRate.Decimal.GreaterThan(hundred)) {
+					return ErrInvalidRate
+				}
+				cs.GGRRate = *u.GGRRate
+			}
+			if err := tx.Save(&cs).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, u := range provs {
+			code := strings.ToLower(u.ProviderCode)
+			if providers.GetProvider(code) == nil {
+				return ErrUnknownProvider
+			}
+			if u.Enabled == nil {
+				if err := tx.Unscoped().Where("agent_id = ? AND provider_code = ?", agentID, code).
+					Delete(&models.AgentProviderSetting{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			ps := models.AgentProviderSetting{AgentID: agentID, ProviderCode: code, Enabled: *u.Enabled}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "agent_id"}, {Name: "provider_code"}},
+				DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+			}).Create(&ps).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,452 @@
+// Package agentwallet meneruskan pergerakan saldo user milik agent mode
+// seamless ke wallet agent itu sendiri.
+//
+// Protokol ke agent (semua POST JSON, ditandatangani dengan skema yang sama
+// seperti request agent ke kita: X-Agent-Code, X-Timestamp, X-Nonce,
+// X-Signature dengan key hex(sha256(secret))):
+//
+//	{wallet_url}/balance   {user_code, currency}
+//	{wallet_url}/bet       {tx_id, user_code, amount, ...}   amount negatif, boleh ditolak
+//	{wallet_url}/settle    {tx_id, user_code, amount, ...}   amount >= 0, wajib diterima
+//	{wallet_url}/rollback  {tx_id, ref_tx_id, amount, ...}   amount bertanda, wajib diterima
+//
+// Response: {"status": "OK" | "INSUFFICIENT_FUNDS" | "TX_NOT_FOUND" | ..., "balance": 123.45}.
+// Agent wajib idempotent per tx_id: tx_id yang sama dikirim ulang saat retry.
+package agentwallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"telo/database"
+	"telo/helpers"
+	"telo/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	ModeTransfer = "transfer"
+	ModeSeamless = "seamless"
+
+	ActionBet      = "bet"
+	ActionSettle   = "settle"
+	ActionRollback = "rollback"
+
+	StatusPending  = "PENDING"
+	StatusOK       = "OK"
+	StatusRejected = "REJECTED"
+	StatusFailed   = "FAILED"
+
+	replyOK                = "OK"
+	replyInsufficientFunds = "INSUFFICIENT_FUNDS"
+	replyTxNotFound        = "TX_NOT_FOUND"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("agentwallet: insufficient balance")
+	ErrRejected          = errors.New("agentwallet: rejected by agent")
+	ErrUnavailable       = errors.New("agentwallet: agent wallet unavailable")
+	// ErrQueued: credit belum diterima agent dan akan di-retry oleh job.
+	// Untuk provider credit dianggap sukses.
+	ErrQueued = errors.New("agentwallet: credit queued for retry")
+)
+
+// Call adalah satu pergerakan saldo yang diteruskan ke agent. Amount bertanda:
+// positif menambah saldo user, negatif mengurangi.
+type Call struct {
+	UserCode   string
+	Currency   string
+	Provider   string
+	Operation  string
+	ExternalID string
+	RefTxID    string
+	Amount     decimal.Decimal
+}
+
+type payload struct {
+	TxID       string          `json:"tx_id,omitempty"`
+	RefTxID    string          `json:"ref_tx_id,omitempty"`
+	UserCode   string          `json:"user_code"`
+	Currency   string          `json:"currency"`
+	Amount     decimal.Decimal `json:"amount"`
+	Provider   string          `json:"provider,omitempty"`
+	Operation  string          `json:"operation,omitempty"`
+	ExternalID string          `json:"external_id,omitempty"`
+}
+
+type reply struct {
+	Status  string          `json:"status"`
+	Balance decimal.Decimal `json:"balance"`
+	Message string          `json:"message"`
+}
+
+// Seamless mengembalikan agent kalau agent tersebut memakai mode seamless,
+// atau nil untuk mode transfer.
+func Seamless(db *gorm.DB, agentCode string) (*models.Agent, error) {
+	var agent models.Agent
+	err := db.Where("agent_code = ? AND wallet_mode = ?", agentCode, ModeSeamless).First(&agent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// Balance menanyakan saldo user ke wallet agent.
+func Balance(agent *models.Agent, userCode, currency string) (decimal.Decimal, error) {
+	r, err := post(agent, "/balance", payload{UserCode: userCode, Currency: currency})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if r.Status != replyOK {
+		return decimal.Zero, fmt.Errorf("%w: %s %s", ErrRejected, r.Status, r.Message)
+	}
+	return r.Balance, nil
+}
+
+// Bet meneruskan debit ke agent. Kalau agent tidak menjawab (timeout), status
+// bet di sisi agent tidak diketahui sehingga rollback langsung diantrikan.
+func Bet(agent *models.Agent, call Call) (*models.AgentWalletCall, error) {
+	row, err := newCall(agent, ActionBet, call)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := post(agent, "/bet", callPayload(row))
+	row.Attempts = 1
+	switch {
+	case err != nil:
+		row.Status = StatusFailed
+		row.LastError = err.Error()
+		saveCall(row)
+		if _, qerr := queueRollback(agent, row); qerr != nil {
+			log.Printf("[AGENTWALLET] ❌ Failed to queue rollback tx=%s: %v", row.TxID, qerr)
+		}
+		return row, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	case r.Status == replyInsufficientFunds:
+		row.Status = StatusRejected
+		row.LastError = r.Status
+		saveCall(row)
+		return row, ErrInsufficientFunds
+	case r.Status != replyOK:
+		row.Status = StatusRejected
+		row.LastError = strings.TrimSpace(r.Status + " " + r.Message)
+		saveCall(row)
+		return row, fmt.Errorf("%w: %s", ErrRejected, row.LastError)
+	}
+
+	row.Status = StatusOK
+	row.AgentBalance = decimal.NewNullDecimal(r.Balance)
+	saveCall(row)
+	return row, nil
+}
+
+// Settle meneruskan credit (win) ke agent dengan satu percobaan langsung
+// (dibatasi AGENT_WALLET_TIMEOUT_MS). Kalau gagal, dikembalikan ErrQueued dan
+// job yang mencoba lagi dengan backoff, di luar transaction callback.
+func Settle(agent *models.Agent, call Call) (*models.AgentWalletCall, error) {
+	row, err := newCall(agent, ActionSettle, call)
+	if err != nil {
+		return nil, err
+	}
+	return row, deliver(agent, row)
+}
+
+// Rollback meneruskan pembalikan transaksi ke agent. Seperti settle, rollback
+// tidak boleh ditolak sehingga di-retry oleh job sampai diterima.
+func Rollback(agent *models.Agent, call Call) (*models.AgentWalletCall, error) {
+	row, err := newCall(agent, ActionRollback, call)
+	if err != nil {
+		return nil, err
+	}
+	return row, deliver(agent, row)
+}
+
+// RetryPending mengirim ulang settle/rollback yang belum diterima agent, dan
+// membatalkan bet yang sukses di agent tapi transaction lokalnya rollback.
+func RetryPending(db *gorm.DB, now time.Time) (int, error) {
+	if err := rollbackOrphanBets(db, now); err != nil {
+		return 0, err
+	}
+
+	var rows []models.AgentWalletCall
+	if err := db.Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", StatusPending, now).
+		Order("id").Limit(100).Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range rows {
+		row := &rows[i]
+		var agent models.Agent
+		if err := db.First(&agent, row.AgentID).Error; err != nil {
+			log.Printf("[AGENTWALLET] ❌ Agent %d not found for tx=%s: %v", row.AgentID, row.TxID, err)
+			continue
+		}
+		if err := deliver(&agent, row); err == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// rollbackOrphanBets: bet yang OK di agent tapi tidak pernah terhubung ke
+// WalletTransaction (transaction callback gagal commit) harus dikembalikan.
+func rollbackOrphanBets(db *gorm.DB, now time.Time) error {
+	var bets []models.AgentWalletCall
+	if err := db.Where("action = ? AND status = ? AND wallet_transaction_id IS NULL AND updated_at < ?",
+		ActionBet, StatusOK, now.Add(-orphanAfter())).
+		Where("NOT EXISTS (SELECT 1 FROM agent_wallet_calls r WHERE r.ref_tx_id = agent_wallet_calls.tx_id AND r.action = ?)", ActionRollback).
+		Limit(100).Find(&bets).Error; err != nil {
+		return err
+	}
+
+	for i := range bets {
+		var agent models.Agent
+		if err := db.First(&agent, bets[i].AgentID).Error; err != nil {
+			continue
+		}
+		log.Printf("[AGENTWALLET] ⚠️ Orphan bet tx=%s user=%s, queue rollback", bets[i].TxID, bets[i].UserCode)
+		if _, err := queueRollback(&agent, &bets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queueRollback(agent *models.Agent, bet *models.AgentWalletCall) (*models.AgentWalletCall, error) {
+	row, err := newCall(agent, ActionRollback, Call{
+		UserCode:   bet.UserCode,
+		Currency:   bet.Currency,
+		Provider:   bet.Provider,
+		Operation:  bet.Operation,
+		ExternalID: bet.ExternalID,
+		RefTxID:    bet.TxID,
+		Amount:     bet.Amount.Neg(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// dicoba langsung oleh job berikutnya
+	return row, nil
+}
+
+// deliver mengirim settle/rollback satu kali. Baris tetap PENDING (dengan
+// backoff untuk job) kalau belum berhasil, kecuali sudah melewati batas
+// AGENT_WALLET_MAX_ATTEMPTS.
+func deliver(agent *models.Agent, row *models.AgentWalletCall) error {
+	row.Attempts++
+
+	r, err := post(agent, "/"+row.Action, callPayload(row))
+	if err == nil && (r.Status == replyOK || (row.Action == ActionRollback && r.Status == replyTxNotFound)) {
+		row.Status = StatusOK
+		row.LastError = ""
+		row.NextAttemptAt = nil
+		if r.Status == replyOK {
+			row.AgentBalance = decimal.NewNullDecimal(r.Balance)
+		}
+		saveCall(row)
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: %s %s", ErrRejected, r.Status, r.Message)
+	}
+
+	row.LastError = err.Error()
+	if row.Attempts >= maxAttempts() {
+		row.Status = StatusFailed
+		row.NextAttemptAt = nil
+		log.Printf("[AGENTWALLET] ❌ Giving up %s tx=%s agent=%s user=%s amount=%s: %v",
+			row.Action, row.TxID, row.AgentCode, row.UserCode, row.Amount, err)
+	} else {
+		next := time.Now().Add(backoff(row.Attempts))
+		row.NextAttemptAt = &next
+	}
+	saveCall(row)
+	return fmt.Errorf("%w: %v", ErrQueued, err)
+}
+
+// newCall menyimpan baris call lewat database.DB (bukan transaction caller)
+// supaya jejak request ke agent tidak ikut hilang kalau caller rollback.
+//
+// Settle/rollback dengan ExternalID memakai TxID deterministik: kalau
+// transaction callback gagal dan provider mengirim ulang, agent menerima tx_id
+// yang sama dan tidak meng-credit dua kali. Bet selalu mendapat TxID baru
+// karena bet yang gagal sudah di-rollback.
+func newCall(agent *models.Agent, action string, call Call) (*models.AgentWalletCall, error) {
+	txID := uuid.New().String()
+	if action != ActionBet && call.ExternalID != "" {
+		sum := sha1.Sum([]byte(strings.Join([]string{action, call.Provider, call.Operation, call.UserCode, call.ExternalID}, "|")))
+		txID = hex.EncodeToString(sum[:])
+
+		var existing models.AgentWalletCall
+		if err := database.DB.Where("tx_id = ?", txID).First(&existing).Error; err == nil {
+			return &existing, nil
+		}
+	}
+
+	row := &models.AgentWalletCall{
+		AgentID:    agent.ID,
+		AgentCode:  agent.AgentCode,
+		UserCode:   call.UserCode,
+		Action:     action,
+		TxID:       txID,
+		RefTxID:    call.RefTxID,
+		Provider:   call.Provider,
+		Operation:  call.Operation,
+		ExternalID: call.ExternalID,
+		Amount:     call.Amount,
+		Currency:   call.Currency,
+		Status:     StatusPending,
+	}
+	if err := database.DB.Create(row).Error; err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func saveCall(row *models.AgentWalletCall) {
+	if err := database.DB.Model(&models.AgentWalletCall{}).Where("id = ?", row.ID).Updates(map[string]any{
+		"status":          row.Status,
+		"attempts":        row.Attempts,
+		"next_attempt_at": row.NextAttemptAt,
+		"last_error":      row.LastError,
+		"agent_balance":   row.AgentBalance,
+	}).Error; err != nil {
+		log.Printf("[AGENTWALLET] ❌ Failed to save call tx=%s: %v", row.TxID, err)
+	}
+}
+
+// post mengirim request bertanda tangan ke wallet agent.
+func post(agent *models.Agent, path string, body any) (*reply, error) {
+	if agent.WalletURL == "" {
+		return nil, errors.New("wallet_url is not configured")
+	}
+
+	keys, err := agent.SigningKeys(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	target := strings.TrimRight(agent.WalletURL, "/") + path
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	n := hex.EncodeToString(nonce)
+	canonical := helpers.CanonicalRequest(http.MethodPost, u.RequestURI(), ts, n, raw)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Agent-Code", agent.AgentCode)
+	req.Header.Set("X-Timestamp", ts)
+	req.Header.Set("X-Nonce", n)
+	req.Header.Set("X-Signature", helpers.SignRequest(keys[0], canonical))
+
+	client := &http.Client{Timeout: timeout()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("agent wallet HTTP %d", resp.StatusCode)
+	}
+
+	var r reply
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid agent wallet response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return &r, nil
+}
+
+func callPayload(row *models.AgentWalletCall) payload {
+	return payload{
+		TxID:       row.TxID,
+		RefTxID:    row.RefTxID,
+		UserCode:   row.UserCode,
+		Currency:   row.Currency,
+		Amount:     row.Amount,
+		Provider:   row.Provider,
+		Operation:  row.Operation,
+		ExternalID: row.ExternalID,
+	}
+}
+
+// timeout: AGENT_WALLET_TIMEOUT_MS, default 2 detik. Batas total satu request
+// ke agent (connect sampai body terbaca); callback provider hanya menunggu
+// satu request.
+func timeout() time.Duration {
+	return envDuration("AGENT_WALLET_TIMEOUT_MS", time.Millisecond, 2*time.Second)
+}
+
+// maxAttempts: AGENT_WALLET_MAX_ATTEMPTS, default 50.
+func maxAttempts() int {
+	return envInt("AGENT_WALLET_MAX_ATTEMPTS", 50)
+}
+
+// orphanAfter: bet OK yang belum terhubung ke WalletTransaction setelah
+// selang ini dianggap yatim.
+func orphanAfter() time.Duration {
+	return 2 * time.Minute
+}
+
+func backoff(attempts int) time.Duration {
+	d := time.Duration(attempts) * 10 * time.Second
+	if d > 10*time.Minute {
+		d = 10 * time.Minute
+	}
+	return d
+}
+
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+func envDuration(key string, unit, def time.Duration) time.Duration {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return time.Duration(v) * unit
+	}
+	return def
+}
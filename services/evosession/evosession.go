@@ -0,0 +1,123 @@
+// Package evosession mengelola SID Evolution: dibuat per launch dan per
+// produk (slot/live), punya expiry, bisa di-refresh lewat /sid dan di-revoke.
+package evosession
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"telo/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ProviderSlot = "EVOLUTION_SLOT"
+	ProviderLive = "EVOLUTION_LIVE"
+)
+
+const defaultTTL = 24 * time.Hour
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrExpired  = errors.New("session expired")
+	ErrRevoked  = errors.New("session revoked")
+)
+
+// TTL dibaca dari EVOLUTION_SESSION_TTL (format time.ParseDuration), default 24 jam.
+func TTL() time.Duration {
+	if v := os.Getenv("EVOLUTION_SESSION_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️  Invalid value for EVOLUTION_SESSION_TTL: %s\n", v)
+	}
+	return defaultTTL
+}
+
+// Create membuat session baru untuk satu launch.
+func Create(db *gorm.DB, user models.User, provider, gameCode string) (models.Session, error) {
+	s := models.Session{
+		UserID:    user.ID,
+		Provider:  provider,
+		GameCode:  gameCode,
+		ExpiresAt: time.Now().Add(TTL()),
+	}
+	err := db.Create(&s).Error
+	return s, err
+}
+
+// Validate memastikan SID milik user, untuk produk yang benar, belum expired
+// dan belum di-revoke.
+func Validate(db *gorm.DB, sid string, userID uint, provider string) (models.Session, error) {
+	var s models.Session
+	err := db.Where("s_id = ? AND user_id = ? AND provider = ?", sid, userID, provider).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s, ErrNotFound
+	}
+	if err != nil {
+		return s, err
+	}
+	return s, check(s)
+}
+
+func check(s models.Session) error {
+	switch {
+	case s.RevokedAt != nil:
+		return ErrRevoked
+	case time.Now().After(s.ExpiresAt):
+		return ErrExpired
+	}
+	return nil
+}
+
+// Refresh menerbitkan SID baru menggantikan SID yang masih aktif (endpoint
+// /sid). SID lama di-revoke supaya hanya satu SID yang berlaku per launch.
+func Refresh(db *gorm.DB, sid string, userID uint, provider string) (models.Session, error) {
+	var fresh models.Session
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var old models.Session
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("s_id = ? AND user_id = ? AND provider = ?", sid, userID, provider).
+			First(&old).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := check(old); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&old).Update("revoked_at", &now).Error; err != nil {
+			return err
+		}
+
+		fresh = models.Session{
+			UserID:        userID,
+			Provider:      provider,
+			GameCode:      old.GameCode,
+			ExpiresAt:     now.Add(TTL()),
+			RefreshedFrom: old.SID,
+		}
+		return tx.Create(&fresh).Error
+	})
+	return fresh, err
+}
+
+// RevokeUser mencabut semua session aktif user. provider kosong berarti
+// semua produk Evolution.
+func RevokeUser(db *gorm.DB, userID uint, provider string) (int64, error) {
+	q := db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now())
+	if provider != "" {
+		q = q.Where("provider = ?", provider)
+	}
+	res := q.Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
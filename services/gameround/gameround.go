@@ -0,0 +1,163 @@
+// Package gameround memelihara GameRound: satu baris per (provider, round,
+// user) yang diperbarui oleh setiap callback provider.
+package gameround
+
+import (
+	"errors"
+	"time"
+
+	"telo/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Event adalah satu callback pada sebuah round. Amount ditambahkan ke total
+// round (Win boleh negatif untuk adjustment).
+type Event struct {
+	User     models.User
+	Provider string
+	RoundID  string
+	GameID   string
+
+	Bet     decimal.Decimal
+	Win     decimal.Decimal
+	Jackpot decimal.Decimal
+	Promo   decimal.Decimal
+	Refund  decimal.Decimal
+
+	// Close menandai round selesai dari sisi provider (result/endRound).
+	Close bool
+}
+
+// Apply menambahkan event ke round-nya, membuat round kalau belum ada.
+// Harus dipanggil di dalam tx yang sama dengan pergerakan saldo dan hanya
+// untuk request yang bukan duplikat. Event tanpa RoundID diabaikan.
+//
+// Transisi status:
+//   - bet pada round yang sudah ditutup membuka round lagi;
+//   - refund yang menutup seluruh bet → Cancelled;
+//   - Close → Settled (round Cancelled tetap Cancelled).
+func Apply(tx *gorm.DB, e Event) error {
+	if e.RoundID == "" {
+		return nil
+	}
+
+	r, err := lockOrCreate(tx, e)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r.BetAmount = r.BetAmount.Add(e.Bet)
+	r.WinAmount = r.WinAmount.Add(e.Win)
+	r.JackpotAmount = r.JackpotAmount.Add(e.Jackpot)
+	r.PromoAmount = r.PromoAmount.Add(e.Promo)
+	r.RefundAmount = r.RefundAmount.Add(e.Refund)
+	r.EventCount++
+	r.LastEventAt = now
+	if r.GameID == "" {
+		r.GameID = e.GameID
+	}
+
+	if e.Bet.IsPositive() && r.Status != models.RoundStatusOpen {
+		r.Status = models.RoundStatusOpen
+		r.ClosedAt = nil
+	}
+	switch {
+	case e.Refund.IsPositive() && r.RefundAmount.GreaterThanOrEqual(r.BetAmount):
+		r.Status = models.RoundStatusCancelled
+		r.ClosedAt = &now
+	case e.Close && r.Status == models.RoundStatusOpen:
+		r.Status = models.RoundStatusSettled
+		r.ClosedAt = &now
+	}
+
+	return tx.Save(r).Error
+}
+
+// Sync menimpa total round dengan nilai absolut, untuk provider yang
+// menyimpan state bet lengkap di tabelnya sendiri (SBO).
+func Sync(tx *gorm.DB, e Event, status string) error {
+	if e.RoundID == "" {
+		return nil
+	}
+
+	r, err := lockOrCreate(tx, e)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r.BetAmount = e.Bet
+	r.WinAmount = e.Win
+	r.JackpotAmount = e.Jackpot
+	r.PromoAmount = e.Promo
+	r.RefundAmount = e.Refund
+	r.EventCount++
+	r.LastEventAt = now
+	if r.Status != status {
+		r.Status = status
+		r.ClosedAt = nil
+		if status != models.RoundStatusOpen {
+			r.ClosedAt = &now
+		}
+	}
+
+	return tx.Save(r).Error
+}
+
+func lockOrCreate(tx *gorm.DB, e Event) (*models.GameRound, error) {
+	now := time.Now()
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.GameRound{
+		Provider:      e.Provider,
+		RoundID:       e.RoundID,
+		UserID:        e.User.ID,
+		UserCode:      e.User.UserCode,
+		AgentCode:     e.User.AgentCode,
+		GameID:        e.GameID,
+		Currency:      e.User.Currency,
+		Status:        models.RoundStatusOpen,
+		BetAmount:     decimal.Zero,
+		WinAmount:     decimal.Zero,
+		JackpotAmount: decimal.Zero,
+		PromoAmount:   decimal.Zero,
+		RefundAmount:  decimal.Zero,
+		OpenedAt:      now,
+		LastEventAt:   now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var r models.GameRound
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND round_id = ? AND user_id = ?", e.Provider, e.RoundID, e.User.ID).
+		First(&r).Error
+	return &r, err
+}
+
+// Close menutup round yang sudah ada (endRound) tanpa mengubah total.
+// Mengembalikan false kalau round belum pernah tercatat.
+func Close(tx *gorm.DB, provider, roundID string, userID uint) (bool, error) {
+	var r models.GameRound
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND round_id = ? AND user_id = ?", provider, roundID, userID).
+		First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	r.EventCount++
+	r.LastEventAt = now
+	if r.Status == models.RoundStatusOpen {
+		r.Status = models.RoundStatusSettled
+		r.ClosedAt = &now
+	}
+	return true, tx.Save(&r).Error
+}
@@ -0,0 +1,230 @@
+package gametx
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"telo/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Backfill membangun baris UserGameTransaction dari tabel transaksi tiap
+// provider. Aman dijalankan berulang: baris dikunci oleh (provider,
+// provider_tx) dan nilainya dihitung ulang dari tabel provider, bukan
+// ditambahkan. Saldo before/after tidak diketahui untuk data lama kecuali
+// provider menyimpannya sendiri.
+func Backfill(db *gorm.DB) (map[string]int64, error) {
+	counts := map[string]int64{}
+
+	for _, s := range aggregateSources {
+		res := db.Exec(s.sql)
+		if res.Error != nil {
+			return counts, res.Error
+		}
+		counts[s.provider] = res.RowsAffected
+		log.Printf("✅ [GAMETX] backfill %s: %d rows", s.provider, res.RowsAffected)
+	}
+
+	n, err := backfillSBO(db)
+	counts["SBO"] = n
+	if err != nil {
+		return counts, err
+	}
+	log.Printf("✅ [GAMETX] backfill SBO: %d rows", n)
+	return counts, nil
+}
+
+const upsertColumns = `
+	INSERT INTO user_game_transactions (created_at, updated_at, user_id, user_code, agent_code,
+		game_id, sub_game_id, provider_tx, provider, bet_amount, win_amount, bonus_amount,
+		refund_amount, jp_contrib, currency, balance_before, balance_after, status, note, ref_id, round_id)`
+
+const onConflictUpdate = `
+	ON CONFLICT (provider_tx, provider) DO UPDATE SET
+		bet_amount = EXCLUDED.bet_amount,
+		win_amount = EXCLUDED.win_amount,
+		bonus_amount = EXCLUDED.bonus_amount,
+		refund_amount = EXCLUDED.refund_amount,
+		status = EXCLUDED.status,
+		round_id = EXCLUDED.round_id,
+		updated_at = EXCLUDED.updated_at`
+
+// transferSQL: FastSpin & SpadeGaming punya skema yang sama. Type 1 = bet
+// (kunci transfer_id), 4 = payout (kunci reference_id bet), 7 = bonus dan
+// 8 = jackpot. Cancel (type 2) merujuk bet lewat reference_id atau lewat
+// ref_ticket_ids (ticket_id bet, dipisah koma atau array JSON lama); yang
+// kedua dipecah per bet dengan nominal stake bet itu. Bet yang sudah di-cancel
+// berstatus Cancelled. Baris tanpa kunci dilewati.
+const transferSQL = upsertColumns + `
+	SELECT MIN(t.created_at), MAX(t.updated_at), u.id, u.user_code, u.agent_code,
+		MAX(t.game_code), 0, t.tx_key, '%[1]s',
+		SUM(CASE WHEN t.type = 1 THEN t.amount * 1000 ELSE 0 END),
+		SUM(CASE WHEN t.type IN (4, 8) THEN t.amount * 1000 ELSE 0 END),
+		SUM(CASE WHEN t.type = 7 THEN t.amount * 1000 ELSE 0 END),
+		SUM(CASE WHEN t.type = 2 THEN t.amount * 1000 ELSE 0 END),
+		0, u.currency,
+		(array_agg(t.balance_before ORDER BY t.id))[1],
+		(array_agg(t.balance_after ORDER BY t.id DESC))[1],
+		CASE WHEN bool_or(t.type = 2) THEN 'Refund' WHEN bool_or(t.type IN (4, 7, 8)) THEN 'Settled' ELSE 'Running' END,
+		'backfill', t.tx_key, MAX(t.ticket_id)
+	FROM (
+		SELECT id, created_at, updated_at, acct_id, game_code, ticket_id, type, amount,
+			balance_before, balance_after,
+			CASE WHEN type = 1 THEN transfer_id
+				WHEN type = 2 THEN reference_id
+				ELSE COALESCE(NULLIF(reference_id, ''), transfer_id) END AS tx_key
+		FROM %[2]s
+		WHERE deleted_at IS NULL AND status IN ('Success', 'Cancelled')
+			AND NOT (type = 2 AND COALESCE(reference_id, '') = '')
+		UNION ALL
+		SELECT c.id, c.created_at, c.updated_at, c.acct_id, b.game_code, b.ticket_id, 2, b.amount,
+			c.balance_before, c.balance_after, b.transfer_id
+		FROM %[2]s c
+		JOIN %[2]s b ON b.type = 1 AND b.deleted_at IS NULL AND b.acct_id = c.acct_id
+			AND b.ticket_id = ANY(string_to_array(translate(c.ref_ticket_ids, '[]" ', ''), ','))
+		WHERE c.deleted_at IS NULL AND c.status = 'Success' AND c.type = 2
+			AND COALESCE(c.reference_id, '') = '' AND COALESCE(c.ref_ticket_ids, '') <> ''
+	) t
+	JOIN users u ON u.user_code = t.acct_id
+	WHERE COALESCE(t.tx_key, '') <> ''
+	GROUP BY t.tx_key, u.id, u.user_code, u.agent_code, u.currency` + onConflictUpdate
+
+var aggregateSources = []struct {
+	provider string
+	sql      string
+}{
+	{
+		// Cancel Evolution menandai baris DEBIT dengan status CANCEL; promo
+		// payout tercatat dengan ref_id sendiri (PROMO-<id>).
+		provider: "EVOLUTION",
+		sql: upsertColumns + `
+			SELECT MIN(e.created_at), MAX(e.updated_at), u.id, u.user_code, u.agent_code,
+				MAX(e.game_type), 0, e.ref_id,
+				CASE MAX(e.provider) WHEN 'Evolution Slot' THEN 'EVOLUTION_SLOT' ELSE 'EVOLUTION_LIVE' END,
+				SUM(CASE WHEN e.type = 'DEBIT' THEN e.amount ELSE 0 END),
+				SUM(CASE WHEN e.type = 'CREDIT' THEN e.amount ELSE 0 END),
+				SUM(CASE WHEN e.type = 'PROMO_PAYOUT' THEN e.amount ELSE 0 END),
+				SUM(CASE WHEN e.type = 'DEBIT' AND e.status = 'CANCEL' THEN e.amount ELSE 0 END),
+				0, u.currency, 0, 0,
+				CASE WHEN bool_or(e.status = 'CANCEL') THEN 'Refund' WHEN bool_or(e.type IN ('CREDIT', 'PROMO_PAYOUT')) THEN 'Settled' ELSE 'Running' END,
+				'backfill', MIN(e.tx_id), MAX(e.game_id)
+			FROM evolution_transactions e
+			JOIN users u ON u.id = e.user_id
+			WHERE e.deleted_at IS NULL AND e.ref_id <> ''
+			GROUP BY e.ref_id, u.id, u.user_code, u.agent_code, u.currency` + onConflictUpdate,
+	},
+	{provider: "FASTSPIN", sql: fmt.Sprintf(transferSQL, "FASTSPIN", "fast_spin_transactions")},
+	{provider: "SPADEGAMING", sql: fmt.Sprintf(transferSQL, "SPADEGAMING", "spade_gaming_transactions")},
+	{
+		// TeloSlotTransaction dibersihkan berkala, jadi baris yang sudah ada
+		// tidak ditimpa dengan data yang mungkin tinggal sebagian.
+		provider: "TELO",
+		sql: upsertColumns + `
+			SELECT MIN(t.created_at), MAX(t.updated_at), u.id, u.user_code, u.agent_code,
+				MAX(t.game_code), 0, t.txn_id, 'TELO',
+				MAX(COALESCE(NULLIF(t.bet, ''), '0')::numeric),
+				MAX(COALESCE(NULLIF(t.win, ''), '0')::numeric),
+				0, 0, 0, u.currency,
+				MIN(COALESCE(NULLIF(t.user_before_balance, ''), '0')::numeric),
+				MAX(COALESCE(NULLIF(t.user_after_balance, ''), '0')::numeric),
+				CASE WHEN bool_or(t.is_round_finished) OR MAX(COALESCE(NULLIF(t.win, ''), '0')::numeric) > 0
+					THEN 'Settled' ELSE 'Running' END,
+				'backfill', t.txn_id, MAX(t.round_id)
+			FROM telo_slot_transactions t
+			JOIN users u ON u.user_code = t.user_code
+			WHERE t.deleted_at IS NULL AND t.txn_id <> ''
+			GROUP BY t.txn_id, u.id, u.user_code, u.agent_code, u.currency
+			ON CONFLICT (provider_tx, provider) DO NOTHING`,
+	},
+}
+
+// backfillSBO memakai mapping yang sama dengan callback SBO (FromSBO /
+// FromWmSubBet) supaya hasilnya identik dengan baris yang ditulis live.
+func backfillSBO(db *gorm.DB) (int64, error) {
+	users := map[string]*models.User{}
+	lookup := func(code string) (*models.User, error) {
+		if u, ok := users[code]; ok {
+			return u, nil
+		}
+		var u models.User
+		err := db.Where("user_code = ?", code).First(&u).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			users[code] = nil
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		users[code] = &u
+		return &u, nil
+	}
+
+	var total int64
+	save := func(row models.UserGameTransaction) error {
+		row.BalanceBefore = decimal.Zero
+		row.BalanceAfter = decimal.Zero
+		row.Note = "backfill"
+		if err := Sync(db, row, true); err != nil {
+			return err
+		}
+		total++
+		return nil
+	}
+
+	var trxs []models.X568WinTransaction
+	err := db.Model(&models.X568WinTransaction{}).FindInBatches(&trxs, 500, func(_ *gorm.DB, _ int) error {
+		for _, trx := range trxs {
+			u, err := lookup(trx.Username)
+			if err != nil {
+				return err
+			}
+			if u == nil {
+				continue
+			}
+			row := FromSBO(*u, trx, SBORate(u.Currency))
+			row.CreatedAt = trx.CreatedAt
+			if err := save(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return total, err
+	}
+
+	var bets []models.WmSubBet
+	err = db.Model(&models.WmSubBet{}).FindInBatches(&bets, 500, func(_ *gorm.DB, _ int) error {
+		for _, b := range bets {
+			u, err := lookup(b.UserCode)
+			if err != nil {
+				return err
+			}
+			if u == nil {
+				continue
+			}
+			row := FromWmSubBet(*u, b)
+			row.CreatedAt = b.CreatedAt
+			if err := save(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	return total, err
+}
+
+// SBORate sama dengan getRate di handler SBO: IDR/VND ditampilkan dalam
+// ribuan.
+func SBORate(currency string) decimal.Decimal {
+	switch strings.ToUpper(strings.TrimSpace(currency)) {
+	case "IDR", "VND":
+		return decimal.NewFromInt(1000)
+	default:
+		return decimal.NewFromInt(1)
+	}
+}
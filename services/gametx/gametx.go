@@ -0,0 +1,130 @@
+// Package gametx menulis baris UserGameTransaction yang ternormalisasi dari
+// callback semua provider, supaya laporan dan riwayat pemain cukup satu query.
+package gametx
+
+import (
+	"errors"
+
+	"telo/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StatusRunning = "Running"
+	StatusSettled = "Settled"
+	StatusRefund  = "Refund"
+)
+
+// ProviderSBOWM dipakai untuk sub-bet WM supaya TransactionId WM tidak
+// bentrok dengan TransferCode SBO di unique index (provider_tx, provider).
+const ProviderSBOWM = "SBO_WM"
+
+// Entry adalah satu kejadian pada sebuah bet. Bet/Win/Bonus/Refund ditambahkan
+// ke baris yang sudah ada (boleh negatif untuk rollback); Status kosong berarti
+// status tidak berubah.
+type Entry struct {
+	User       models.User
+	Provider   string
+	ProviderTx string
+	RoundID    string
+	GameID     string
+
+	Bet    decimal.Decimal
+	Win    decimal.Decimal
+	Bonus  decimal.Decimal
+	Refund decimal.Decimal
+
+	Status       string
+	BalanceAfter decimal.Decimal
+	Note         string
+	RefID        string
+}
+
+// Record membuat atau memperbarui baris (Provider, ProviderTx). Harus
+// dipanggil di dalam transaction callback yang sama dengan pergerakan saldo,
+// dan hanya untuk request yang bukan duplikat.
+func Record(tx *gorm.DB, e Entry) error {
+	var row models.UserGameTransaction
+	lock := func() error {
+		return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND provider_tx = ?", e.Provider, e.ProviderTx).
+			First(&row).Error
+	}
+	err := lock()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		status := e.Status
+		if status == "" {
+			status = StatusRunning
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserGameTransaction{
+			UserID:        e.User.ID,
+			UserCode:      e.User.UserCode,
+			AgentCode:     e.User.AgentCode,
+			GameID:        e.GameID,
+			ProviderTx:    e.ProviderTx,
+			Provider:      e.Provider,
+			RoundID:       e.RoundID,
+			BetAmount:     e.Bet,
+			WinAmount:     e.Win,
+			BonusAmount:   e.Bonus,
+			RefundAmount:  e.Refund,
+			JPContrib:     decimal.Zero,
+			Currency:      e.User.Currency,
+			BalanceBefore: e.BalanceAfter.Sub(e.Win.Add(e.Bonus).Add(e.Refund).Sub(e.Bet)),
+			BalanceAfter:  e.BalanceAfter,
+			Status:        status,
+			Note:          e.Note,
+			RefID:         e.RefID,
+		})
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		// Callback lain untuk bet yang sama menyisipkan baris lebih dulu
+		// (FOR UPDATE tidak bisa mengunci baris yang belum ada): lock baris
+		// itu lalu tambahkan nilainya seperti biasa.
+		err = lock()
+	}
+	if err != nil {
+		return err
+	}
+
+	row.BetAmount = row.BetAmount.Add(e.Bet)
+	row.WinAmount = row.WinAmount.Add(e.Win)
+	row.BonusAmount = row.BonusAmount.Add(e.Bonus)
+	row.RefundAmount = row.RefundAmount.Add(e.Refund)
+	row.BalanceAfter = e.BalanceAfter
+	if e.Status != "" {
+		row.Status = e.Status
+	}
+	if row.RoundID == "" {
+		row.RoundID = e.RoundID
+	}
+	if row.GameID == "" {
+		row.GameID = e.GameID
+	}
+	if e.Note != "" {
+		row.Note = e.Note
+	}
+	return tx.Save(&row).Error
+}
+
+// Sync menulis baris dengan nilai absolut (bukan penambahan), untuk provider
+// yang menyimpan state bet lengkap di tabelnya sendiri (SBO). BalanceAfter
+// hanya ditimpa kalau keepBalance false.
+func Sync(tx *gorm.DB, row models.UserGameTransaction, keepBalance bool) error {
+	if row.Status == "" {
+		row.Status = StatusRunning
+	}
+	columns := []string{"bet_amount", "win_amount", "bonus_amount", "refund_amount", "status", "round_id", "game_id", "updated_at"}
+	if !keepBalance {
+		row.BalanceBefore = row.BalanceAfter.Sub(row.WinAmount.Add(row.BonusAmount).Add(row.RefundAmount).Sub(row.BetAmount))
+		columns = append(columns, "balance_after")
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider_tx"}, {Name: "provider"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&row).Error
+}
@@ -0,0 +1,25 @@
+package gametx
+
+import "telo/models"
+
+// FromSaba membangun baris ternormalisasi dari SabaTransaction. Nilai bet
+// SABA sudah dalam satuan saldo user; unsettle membuka kembali bet.
+func FromSaba(user models.User, t models.SabaTransaction) models.UserGameTransaction {
+	row := baseRow(user, "SABA", t.RefID)
+	row.GameID = t.GameID
+	row.RoundID = t.RefID
+	row.RefID = t.TxID
+	row.Note = t.BetType
+	row.BetAmount = t.BetAmount
+	switch t.Status {
+	case "SETTLE", "RESETTLE":
+		row.Status = StatusSettled
+		row.WinAmount = t.WinAmount
+	case "CANCEL":
+		row.Status = StatusRefund
+		row.RefundAmount = t.RefundAmount
+	default:
+		row.Status = StatusRunning
+	}
+	return row
+}
@@ -0,0 +1,111 @@
+package gametx
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"telo/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// FromSBO membangun baris ternormalisasi dari X568WinTransaction. Amount dan
+// WinLoss SBO dalam satuan tampilan, jadi dikali rate ke satuan saldo user.
+func FromSBO(user models.User, trx models.X568WinTransaction, rate decimal.Decimal) models.UserGameTransaction {
+	row := baseRow(user, "SBO", trx.TransferCode)
+	row.GameID = strconv.Itoa(trx.GameId)
+	if trx.GameRoundId != nil {
+		row.RoundID = *trx.GameRoundId
+	}
+	row.RefID = trx.TransactionId
+
+	stake := trx.Amount.Mul(rate)
+	winLoss := trx.WinLoss.Mul(rate)
+
+	// Bonus disimpan tanpa stake dengan nominal bonus di WinLoss.
+	if trx.Amount.IsZero() && trx.WinLoss.IsPositive() {
+		row.Note = "SBO bonus"
+		if trx.Status == "Void" {
+			row.Status = StatusRefund
+		} else {
+			row.BonusAmount = winLoss
+			row.Status = StatusSettled
+		}
+		return row
+	}
+
+	row.BetAmount = stake
+	switch trx.Status {
+	case "Settled":
+		row.Status = StatusSettled
+		row.WinAmount = sboPayout(trx, stake, winLoss)
+	case "Void":
+		row.Status = StatusRefund
+		row.RefundAmount = stake
+	default:
+		row.Status = StatusRunning
+	}
+	return row
+}
+
+// sboPayout mengikuti SettleHandler: draw mengembalikan stake, kalah tidak
+// dapat apa-apa, sisanya (menang / cash out) dibayar sebesar WinLoss.
+func sboPayout(trx models.X568WinTransaction, stake, winLoss decimal.Decimal) decimal.Decimal {
+	if trx.IsCashOut {
+		return winLoss
+	}
+	var info struct {
+		ResultType *int `json:"resultType"`
+	}
+	_ = json.Unmarshal(trx.ExtraInfo, &info)
+	if info.ResultType == nil {
+		return winLoss
+	}
+	switch *info.ResultType {
+	case 0:
+		return winLoss
+	case 2:
+		return stake
+	default:
+		return decimal.Zero
+	}
+}
+
+// FromWmSubBet membangun baris dari sub-bet WM (product type 9). Nilai
+// sub-bet sudah dalam satuan saldo user.
+func FromWmSubBet(user models.User, b models.WmSubBet) models.UserGameTransaction {
+	row := baseRow(user, ProviderSBOWM, b.TransactionId)
+	row.GameID = strconv.Itoa(b.GameId)
+	row.RoundID = b.TransferCode
+	row.RefID = b.TransferCode
+	row.BetAmount = b.Amount
+	switch b.Status {
+	case "Settled":
+		row.Status = StatusSettled
+		row.WinAmount = b.WinLoss
+	case "Void":
+		row.Status = StatusRefund
+		row.RefundAmount = b.Amount
+	default:
+		row.Status = StatusRunning
+	}
+	return row
+}
+
+func baseRow(user models.User, provider, providerTx string) models.UserGameTransaction {
+	return models.UserGameTransaction{
+		UserID:        user.ID,
+		UserCode:      user.UserCode,
+		AgentCode:     user.AgentCode,
+		Provider:      provider,
+		ProviderTx:    providerTx,
+		BetAmount:     decimal.Zero,
+		WinAmount:     decimal.Zero,
+		BonusAmount:   decimal.Zero,
+		RefundAmount:  decimal.Zero,
+		JPContrib:     decimal.Zero,
+		Currency:      user.Currency,
+		BalanceAfter:  user.Balance,
+		BalanceBefore: decimal.Zero,
+	}
+}
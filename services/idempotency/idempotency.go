@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"telo/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEmptyKey = errors.New("idempotency: provider, operation and external id are required")
+	// ErrConflict: key sudah dipakai request dengan Fingerprint berbeda.
+	ErrConflict = errors.New("idempotency: key reused with a different request")
+)
+
+type Key struct {
+	Provider   string
+	Operation  string
+	ExternalID string
+	// Fingerprint opsional, lihat Fingerprint(). Kalau diisi, replay hanya
+	// diberikan untuk request dengan fingerprint yang sama.
+	Fingerprint string
+}
+
+// Fingerprint membuat hash dari field request yang menentukan hasilnya.
+func Fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Response adalah response yang harus dikirim ke provider. Replayed = true
+// berarti body diambil dari pemrosesan pertama.
+type Response struct {
+	Status   int
+	Body     []byte
+	Replayed bool
+}
+
+func (r *Response) Send(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Status(r.Status).Send(r.Body)
+}
+
+// Abort dikembalikan dari fn untuk membatalkan transaction tanpa menyimpan
+// key (misalnya error DB sementara). Response-nya tetap dikirim ke provider,
+// tapi retry berikutnya akan diproses ulang.
+type Abort struct {
+	Status int
+	Body   any
+}
+
+func (a *Abort) Error() string { return "idempotency: aborted" }
+
+// Do meng-claim key di dalam DB transaction lalu menjalankan fn di transaction
+// yang sama. Kalau key sudah ada, fn tidak dijalankan dan response tersimpan
+// di-replay. Insert key memakai ON CONFLICT DO NOTHING, jadi request bersamaan
+// dengan key yang sama menunggu sampai transaction pertama selesai.
+//
+// Response dari fn (termasuk penolakan bisnis seperti saldo kurang) disimpan.
+// Kalau fn mengembalikan error, transaction di-rollback dan key dilepas.
+func Do(db *gorm.DB, key Key, fn func(tx *gorm.DB) (int, any, error)) (*Response, error) {
+	if key.Provider == "" || key.Operation == "" || key.ExternalID == "" {
+		return nil, ErrEmptyKey
+	}
+
+	var resp Response
+	var abort *Abort
+	err := db.Transaction(func(tx *gorm.DB) error {
+		rec := models.IdempotencyKey{
+			Provider:    key.Provider,
+			Operation:   key.Operation,
+			ExternalID:  key.ExternalID,
+			Fingerprint: key.Fingerprint,
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rec)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if err := tx.Where("provider = ? AND operation = ? AND external_id = ?",
+				key.Provider, key.Operation, key.ExternalID).First(&rec).Error; err != nil {
+				return err
+			}
+			if key.Fingerprint != "" && rec.Fingerprint != "" && rec.Fingerprint != key.Fingerprint {
+				return ErrConflict
+			}
+			resp = Response{Status: rec.StatusCode, Body: []byte(rec.ResponseBody), Replayed: true}
+			return nil
+		}
+
+		status, body, err := fn(tx)
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&rec).Updates(map[string]any{
+			"status_code":   status,
+			"response_body": string(raw),
+		}).Error; err != nil {
+			return err
+		}
+		resp = Response{Status: status, Body: raw}
+		return nil
+	})
+
+	if errors.As(err, &abort) {
+		raw, mErr := json.Marshal(abort.Body)
+		if mErr != nil {
+			return nil, mErr
+		}
+		return &Response{Status: abort.Status, Body: raw}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
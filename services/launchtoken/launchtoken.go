@@ -0,0 +1,180 @@
+// Package launchtoken menerbitkan dan me-resolve token launch game. Token
+// dikirim ke provider saat launch dan dipakai callback untuk menemukan user,
+// jadi user_code tidak berfungsi sebagai kredensial (kecuali selama
+// LegacyFallback aktif).
+package launchtoken
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"telo/models"
+
+	"gorm.io/gorm"
+)
+
+const defaultTTL = 2 * time.Hour
+
+var (
+	ErrNotFound = errors.New("launch token not found")
+	ErrExpired  = errors.New("launch token expired")
+	ErrRevoked  = errors.New("launch token revoked")
+)
+
+// TTL dibaca dari LAUNCH_TOKEN_TTL (format time.ParseDuration), default 2 jam.
+// Expiry bergeser setiap kali token dipakai, jadi sesi yang aktif tidak putus.
+func TTL() time.Duration {
+	if v := os.Getenv("LAUNCH_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️  Invalid value for LAUNCH_TOKEN_TTL: %s\n", v)
+	}
+	return defaultTTL
+}
+
+// Issue membuat token baru untuk user pada provider + game tertentu.
+func Issue(db *gorm.DB, user models.User, provider, gameCode string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	t := models.LaunchToken{
+		Token:     hex.EncodeToString(raw),
+		UserID:    user.ID,
+		UserCode:  user.UserCode,
+		Provider:  provider,
+		GameCode:  gameCode,
+		ExpiresAt: time.Now().Add(TTL()),
+	}
+	if err := db.Create(&t).Error; err != nil {
+		return "", err
+	}
+	return t.Token, nil
+}
+
+// Resolve mencari user pemilik token untuk provider yang diberikan dan
+// memperpanjang expiry-nya. Token provider lain diperlakukan tidak ada.
+func Resolve(db *gorm.DB, token, provider string) (models.User, models.LaunchToken, error) {
+	var user models.User
+	t, err := Find(db, token, provider)
+	if err != nil {
+		return user, t, err
+	}
+
+	now := time.Now()
+	switch {
+	case t.RevokedAt != nil:
+		return user, t, ErrRevoked
+	case now.After(t.ExpiresAt):
+		return user, t, ErrExpired
+	}
+
+	if err := db.First(&user, t.UserID).Error; err != nil {
+		return user, t, err
+	}
+
+	t.ExpiresAt = now.Add(TTL())
+	t.LastUsedAt = &now
+	if err := db.Model(&t).Updates(map[string]any{
+		"expires_at":   t.ExpiresAt,
+		"last_used_at": t.LastUsedAt,
+	}).Error; err != nil {
+		return user, t, err
+	}
+	return user, t, nil
+}
+
+// Find mencari token tanpa memeriksa expiry/revoke, untuk callback
+// settlement (result/refund) yang tetap harus diterima setelah sesi berakhir.
+func Find(db *gorm.DB, token, provider string) (models.LaunchToken, error) {
+	var t models.LaunchToken
+	if token == "" {
+		return t, ErrNotFound
+	}
+	err := db.Where("token = ? AND provider = ?", token, provider).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// LegacyFallback dibaca dari LAUNCH_TOKEN_LEGACY_FALLBACK, default true.
+//
+// Token dikirim ke aggregator Win568 sebagai field "Token" di login.aspx, tapi
+// kontrak Win568 belum memastikan field itu diteruskan ke Pragmatic/PlayStar.
+// Sebelum launch token ada, provider mengirim balik user_code sebagai token;
+// selama fallback aktif, token yang tidak dikenal tapi sama dengan user_code
+// di callback tetap diterima. Matikan setelah provider terbukti mengirim
+// balik launch token.
+func LegacyFallback() bool {
+	v := os.Getenv("LAUNCH_TOKEN_LEGACY_FALLBACK")
+	if v == "" {
+		return true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("⚠️  Invalid value for LAUNCH_TOKEN_LEGACY_FALLBACK: %s\n", v)
+		return true
+	}
+	return b
+}
+
+// ResolveOrLegacy seperti Resolve, dengan fallback ke user_code (lihat
+// LegacyFallback). userCode adalah id user yang dikirim provider di callback;
+// kosong berarti token itu sendiri dianggap user_code.
+func ResolveOrLegacy(db *gorm.DB, token, provider, userCode string) (models.User, models.LaunchToken, error) {
+	user, t, err := Resolve(db, token, provider)
+	if errors.Is(err, ErrNotFound) {
+		return legacy(db, token, provider, userCode, err)
+	}
+	return user, t, err
+}
+
+// FindOrLegacy seperti Find, dengan fallback yang sama dengan ResolveOrLegacy.
+func FindOrLegacy(db *gorm.DB, token, provider, userCode string) (models.LaunchToken, error) {
+	t, err := Find(db, token, provider)
+	if errors.Is(err, ErrNotFound) {
+		_, t, err = legacy(db, token, provider, userCode, err)
+	}
+	return t, err
+}
+
+// legacy menerima token = user_code. LaunchToken yang dikembalikan tidak
+// tersimpan; hanya UserID/UserCode/Provider yang terisi.
+func legacy(db *gorm.DB, token, provider, userCode string, notFound error) (models.User, models.LaunchToken, error) {
+	var user models.User
+	if token == "" || !LegacyFallback() || (userCode != "" && token != userCode) {
+		return user, models.LaunchToken{}, notFound
+	}
+	if err := db.Where("user_code = ?", token).Limit(1).Find(&user).Error; err != nil {
+		return user, models.LaunchToken{}, err
+	}
+	if user.ID == 0 {
+		return user, models.LaunchToken{}, notFound
+	}
+	log.Printf("⚠️  [LAUNCHTOKEN] %s callback authenticated by user_code %s (legacy fallback)", provider, user.UserCode)
+	return user, models.LaunchToken{UserID: user.ID, UserCode: user.UserCode, Provider: provider}, nil
+}
+
+// Revoke mencabut satu token. Mengembalikan false kalau token tidak aktif.
+func Revoke(db *gorm.DB, token string) (bool, error) {
+	res := db.Model(&models.LaunchToken{}).
+		Where("token = ? AND revoked_at IS NULL", token).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeUser mencabut semua token aktif milik user (logout, suspend, close).
+func RevokeUser(db *gorm.DB, userID uint) (int64, error) {
+	res := db.Model(&models.LaunchToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+
+	"telo/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const AccountOpening = "EQUITY:OPENING"
+
+var ErrUnbalancedJournal = errors.New("ledger: journal does not balance to zero")
+
+func UserAccount(userCode string) string { return "USER:" + userCode }
+
+func AgentAccount(agentCode string) string { return "AGENT:" + agentCode }
+
+func ProviderAccount(provider string) string { return "PROVIDER:" + strings.ToUpper(provider) }
+
+// Posting adalah satu baris jurnal. Amount positif menambah saldo akun,
+// negatif mengurangi.
+type Posting struct {
+	Account string
+	UserID  *uint
+	Amount  decimal.Decimal
+}
+
+type Journal struct {
+	Currency            string
+	Provider            string
+	Operation           string
+	Note                string
+	WalletTransactionID *uint
+	Postings            []Posting
+}
+
+// Post menulis semua posting jurnal dengan JournalID yang sama. Total Amount
+// harus nol.
+func Post(tx *gorm.DB, j Journal) error {
+	if len(j.Postings) < 2 {
+		return ErrUnbalancedJournal
+	}
+	total := decimal.Zero
+	for _, p := range j.Postings {
+		total = total.Add(p.Amount)
+	}
+	if !total.IsZero() {
+		return ErrUnbalancedJournal
+	}
+
+	journalID := uuid.New().String()
+	entries := make([]models.LedgerEntry, 0, len(j.Postings))
+	for _, p := range j.Postings {
+		entries = append(entries, models.LedgerEntry{
+			JournalID:           journalID,
+			Account:             p.Account,
+			UserID:              p.UserID,
+			Amount:              p.Amount,
+			Currency:            j.Currency,
+			Provider:            j.Provider,
+			Operation:           j.Operation,
+			WalletTransactionID: j.WalletTransactionID,
+			Note:                j.Note,
+		})
+	}
+	return tx.Create(&entries).Error
+}
+
+// PostUserMovement mencatat perubahan saldo user sebesar amount terhadap akun
+// lawan counterAccount.
+func PostUserMovement(tx *gorm.DB, user models.User, counterAccount string, amount decimal.Decimal, provider, operation, note string, walletTxID *uint) error {
+	userID := user.ID
+	return Post(tx, Journal{
+		Currency:            user.Currency,
+		Provider:            provider,
+		Operation:           operation,
+		Note:                note,
+		WalletTransactionID: walletTxID,
+		Postings: []Posting{
+			{Account: UserAccount(user.UserCode), UserID: &userID, Amount: amount},
+			{Account: counterAccount, Amount: amount.Neg()},
+		},
+	})
+}
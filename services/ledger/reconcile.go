@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"telo/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type balanceRow struct {
+	UserID        uint
+	UserCode      string
+	Currency      string
+	UserBalance   decimal.Decimal
+	LedgerBalance decimal.Decimal
+}
+
+// EnsureOpeningBalances membuat jurnal opening untuk user yang belum punya
+// entry ledger sama sekali (user lama sebelum ledger ada), supaya total ledger
+// mereka sama dengan saldo saat ini.
+func EnsureOpeningBalances(db *gorm.DB) (int, error) {
+	var ids []uint
+	if err := db.Model(&models.User{}).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_entries l WHERE l.user_id = users.id)").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, id := range ids {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&models.LedgerEntry{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+			created++
+			return PostUserMovement(tx, user, AccountOpening, user.Balance, "", "OPENING", "Opening balance", nil)
+		})
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// Reconcile menghitung ulang saldo setiap user dari ledger dan mencatat
+// selisihnya ke ledger_mismatches. Perbandingan dilakukan di satu snapshot
+// (repeatable read) karena saldo user dan entry ledger selalu di-commit dalam
+// transaction yang sama.
+func Reconcile(db *gorm.DB) (string, int, error) {
+	if _, err := EnsureOpeningBalances(db); err != nil {
+		return "", 0, err
+	}
+
+	runID := uuid.New().String()
+	checkedAt := time.Now()
+
+	var rows []balanceRow
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Raw(`
+			SELECT u.id AS user_id, u.user_code, u.currency, u.balance AS user_balance,
+			       COALESCE(SUM(l.amount), 0) AS ledger_balance
+			FROM users u
+			LEFT JOIN ledger_entries l ON l.user_id = u.id
+			WHERE u.deleted_at IS NULL
+			GROUP BY u.id, u.user_code, u.currency, u.balance
+			HAVING u.balance <> COALESCE(SUM(l.amount), 0)`).
+			Scan(&rows).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return runID, 0, err
+	}
+
+	for _, r := range rows {
+		m := models.LedgerMismatch{
+			RunID:         runID,
+			UserID:        r.UserID,
+			UserCode:      r.UserCode,
+			Currency:      r.Currency,
+			UserBalance:   r.UserBalance,
+			LedgerBalance: r.LedgerBalance,
+			Drift:         r.UserBalance.Sub(r.LedgerBalance),
+			CheckedAt:     checkedAt,
+		}
+		if err := db.Create(&m).Error; err != nil {
+			return runID, 0, err
+		}
+		log.Printf("⚠️ [LEDGER] mismatch user=%s balance=%s ledger=%s drift=%s",
+			r.UserCode, r.UserBalance, r.LedgerBalance, m.Drift)
+	}
+	return runID, len(rows), nil
+}
@@ -0,0 +1,196 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"telo/models"
+	"telo/providers"
+	"telo/services/agentsettings"
+	"telo/services/wallet"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Period settlement harian. Periode dihitung di UTC supaya sama antar server.
+const Period = 24 * time.Hour
+
+// maxPeriodsPerRun membatasi catch-up supaya satu run tidak terlalu lama.
+const maxPeriodsPerRun = 31
+
+var hundred = decimal.NewFromInt(100)
+
+type providerGGR struct {
+	AgentCode   string
+	Provider    string
+	TxCount     int64
+	TotalBet    decimal.Decimal
+	TotalRefund decimal.Decimal
+	TotalWin    decimal.Decimal
+}
+
+// PeriodStart mengembalikan awal periode yang memuat t.
+func PeriodStart(t time.Time) time.Time {
+	return t.UTC().Truncate(Period)
+}
+
+// SettlePending men-settle semua periode yang sudah selesai sebelum now dan
+// belum pernah di-settle. Aman dijalankan berulang: statement unik per
+// agent + provider + periode.
+func SettlePending(db *gorm.DB, now time.Time) (int, error) {
+	current := PeriodStart(now)
+
+	var last struct{ PeriodEnd *time.Time }
+	if err := db.Model(&models.AgentSettlement{}).Select("MAX(period_end) AS period_end").Scan(&last).Error; err != nil {
+		return 0, err
+	}
+
+	var start time.Time
+	if last.PeriodEnd != nil {
+		start = last.PeriodEnd.UTC()
+	}
+
+	total := 0
+	for i := 0; i < maxPeriodsPerRun; i++ {
+		// Loncat ke periode berikutnya yang punya transaksi, supaya periode
+		// kosong tidak dihitung ulang terus.
+		var next struct{ CreatedAt *time.Time }
+		if err := db.Model(&models.WalletTransaction{}).
+			Select("MIN(created_at) AS created_at").
+			Where("created_at >= ? AND provider <> ?", start, "AGENT").
+			Scan(&next).Error; err != nil {
+			return total, err
+		}
+		if next.CreatedAt == nil {
+			break
+		}
+		start = PeriodStart(*next.CreatedAt)
+		if !start.Before(current) {
+			break
+		}
+
+		n, err := SettlePeriod(db, start, start.Add(Period))
+		if err != nil {
+			return total, fmt.Errorf("settle %s: %w", start.Format("2006-01-02"), err)
+		}
+		total += n
+		start = start.Add(Period)
+	}
+	return total, nil
+}
+
+// SettlePeriod menghitung GGR per agent per provider dari wallet_transactions
+// dalam [from, to) dan membuat statement + mutasi saldo agent. Transfer
+// agent ↔ user (provider AGENT) tidak dihitung.
+//
+// Cancel (stake dikembalikan) dicatat sebagai TotalRefund, bukan win, dan
+// rollback mengurangi TotalWin karena membatalkan credit sebelumnya.
+func SettlePeriod(db *gorm.DB, from, to time.Time) (int, error) {
+	var rows []providerGGR
+	if err := db.Model(&models.WalletTransaction{}).
+		Select(`agent_code, provider, COUNT(*) AS tx_count,
+			COALESCE(SUM(CASE WHEN operation = ? THEN -amount ELSE 0 END), 0) AS total_bet,
+			COALESCE(SUM(CASE WHEN operation = ? THEN amount ELSE 0 END), 0) AS total_refund,
+			COALESCE(SUM(CASE WHEN operation IN ? THEN amount ELSE 0 END), 0) AS total_win`,
+			wallet.OpDebit, wallet.OpCancel, []string{wallet.OpCredit, wallet.OpRollback}).
+		Where("created_at >= ? AND created_at < ? AND provider <> ? AND agent_code <> ''", from, to, "AGENT").
+		Group("agent_code, provider").
+		Order("agent_code, provider").
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, r := range rows {
+		ok, err := settleOne(db, r, from, to)
+		if err != nil {
+			return settled, err
+		}
+		if ok {
+			settled++
+		}
+	}
+	if settled > 0 {
+		log.Printf("💰 [SETTLEMENT] %s: %d statements", from.Format("2006-01-02"), settled)
+	}
+	return settled, nil
+}
+
+func settleOne(db *gorm.DB, r providerGGR, from, to time.Time) (bool, error) {
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var agent models.Agent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_code = ?", r.AgentCode).First(&agent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("⚠️ [SETTLEMENT] agent %s not found, skipped", r.AgentCode)
+				return nil
+			}
+			return err
+		}
+
+		ggr := r.TotalBet.Sub(r.TotalRefund).Sub(r.TotalWin)
+		rate, err := agentsettings.GGRRate(tx, agent, providers.WalletProviderCategory(r.Provider))
+		if err != nil {
+			return err
+		}
+		amount := ggr.Mul(rate).Div(hundred).Round(4)
+
+		st := models.AgentSettlement{
+			AgentID:       agent.ID,
+			AgentCode:     agent.AgentCode,
+			Provider:      r.Provider,
+			PeriodStart:   from,
+			PeriodEnd:     to,
+			Currency:      agent.Currency,
+			TxCount:       r.TxCount,
+			TotalBet:      r.TotalBet,
+			TotalRefund:   r.TotalRefund,
+			TotalWin:      r.TotalWin,
+			GGR:           ggr,
+			Rate:          rate,
+			Amount:        amount,
+			BalanceBefore: agent.Balance,
+			BalanceAfter:  agent.Balance.Sub(amount),
+			RefID:         uuid.New().String(),
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&st)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil // sudah di-settle
+		}
+		created = true
+
+		if amount.IsZero() {
+			return nil
+		}
+
+		if err := tx.Model(&agent).Update("balance", st.BalanceAfter).Error; err != nil {
+			return err
+		}
+
+		trxType := "GGR_DEBIT"
+		if amount.IsNegative() {
+			trxType = "GGR_CREDIT"
+		}
+		return tx.Create(&models.AgentTransaction{
+			AgentID:       agent.ID,
+			AgentCode:     agent.AgentCode,
+			TrxType:       trxType,
+			Amount:        amount.Abs(),
+			BalanceBefore: st.BalanceBefore,
+			BalanceAfter:  st.BalanceAfter,
+			Currency:      agent.Currency,
+			Note:          fmt.Sprintf("GGR settlement %s %s", r.Provider, from.Format("2006-01-02")),
+			RefID:         st.RefID,
+		}).Error
+	})
+	return created, err
+}
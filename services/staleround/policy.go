@@ -0,0 +1,143 @@
+package staleround
+
+import (
+	"errors"
+	"fmt"
+
+	"telo/models"
+	"telo/services/gameround"
+	"telo/services/gametx"
+	"telo/services/wallet"
+
+	"gorm.io/gorm"
+)
+
+// refundProviders: provider yang refund-nya di wallet memakai ExternalID =
+// kunci bet (ProviderTx), jadi auto-refund di sini dan refund/cancel yang
+// datang terlambat dari provider tidak mengembalikan stake dua kali.
+// Settlement yang datang terlambat ditolak: Pragmatic result, Evolution credit
+// dan PlayStar result memeriksa wallet.Refunded di bawah wallet.LockBet yang
+// sama dengan refund di sini.
+var refundProviders = map[string]bool{
+	"PRAGMATIC":      true,
+	"PLAYSTAR":       true,
+	"EVOLUTION_SLOT": true,
+	"EVOLUTION_LIVE": true,
+}
+
+var errBetClosed = errors.New("bet closed by provider during refund")
+
+func refundable(c candidate) bool {
+	return refundProviders[c.Provider] && (c.Source == SourceGameTx || c.Source == SourceEvolution)
+}
+
+// refund mengembalikan stake yang masih terbuka dan menutup bet di tabel
+// provider, UserGameTransaction dan GameRound dalam satu transaction.
+func refund(db *gorm.DB, c candidate) error {
+	if !c.Amount.IsPositive() {
+		return errors.New("nothing to refund")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := wallet.LockBet(tx, c.Provider, c.Ref); err != nil {
+			return err
+		}
+		res, err := wallet.Cancel(tx, wallet.Request{
+			UserCode:   c.User.UserCode,
+			Provider:   c.Provider,
+			ExternalID: c.Ref,
+			Amount:     c.Amount,
+			RefID:      c.Ref,
+			Note:       "Stale round auto-refund",
+		})
+		if err != nil {
+			return err
+		}
+
+		if c.Source == SourceEvolution {
+			if err := tx.Model(&models.EvolutionTransaction{}).
+				Where("ref_id = ? AND type = ? AND status = ?", c.Ref, "DEBIT", "SUCCESS").
+				Update("status", "CANCEL").Error; err != nil {
+				return err
+			}
+		}
+
+		roundID := c.Ref
+		row := c.gameTx
+		if row == nil {
+			provider := c.Provider
+			if c.Source == SourceWM {
+				provider = gametx.ProviderSBOWM
+			}
+			var found models.UserGameTransaction
+			if err := tx.Where("provider = ? AND provider_tx = ?", provider, c.Ref).
+				Limit(1).Find(&found).Error; err != nil {
+				return err
+			}
+			if found.ID != 0 {
+				row = &found
+			}
+		}
+		if row != nil {
+			upd := tx.Model(&models.UserGameTransaction{}).
+				Where("id = ? AND status IN ?", row.ID, []string{gametx.StatusRunning, "BET"}).
+				Updates(map[string]any{
+					"status":        gametx.StatusRefund,
+					"refund_amount": row.RefundAmount.Add(c.Amount),
+					"balance_after": res.BalanceAfter,
+					"note":          "Stale round auto-refund",
+				})
+			if upd.Error != nil {
+				return upd.Error
+			}
+			if upd.RowsAffected == 0 {
+				return errBetClosed
+			}
+			// Pragmatic mengelompokkan bet per roundId, provider lain per bet.
+			if c.Provider == "PRAGMATIC" && row.RoundID != "" {
+				roundID = row.RoundID
+			}
+		}
+
+		if res.Duplicate {
+			return nil
+		}
+		return gameround.Apply(tx, gameround.Event{
+			User:     res.User,
+			Provider: c.Provider,
+			RoundID:  roundID,
+			Refund:   c.Amount,
+		})
+	})
+}
+
+// checkSBO membandingkan status bet menurut record kita (jawaban yang akan
+// diberikan GetBetStatus ke SBO) dengan laporan bet list SBO (Win568Bet).
+// Kalau SBO sudah menyelesaikan bet tapi settle tidak pernah sampai, bet
+// ditandai supaya job resend meminta SBO mengirim ulang callback-nya.
+func checkSBO(db *gorm.DB, c candidate) (string, string, error) {
+	var ours models.X568WinTransaction
+	if err := db.Where("transfer_code = ? AND username = ?", c.Ref, c.User.UserCode).
+		Limit(1).Find(&ours).Error; err != nil {
+		return "", "", err
+	}
+
+	var report models.Win568Bet
+	if err := db.Where("ref_no = ?", c.Ref).Limit(1).Find(&report).Error; err != nil {
+		return "", "", err
+	}
+	if report.ID == 0 {
+		return ActionProviderNotFound, fmt.Sprintf("our status=%s, bet not in SBO bet list", ours.Status), nil
+	}
+
+	detail := fmt.Sprintf("our status=%s, SBO status=%s", ours.Status, report.Status)
+	if report.Status == "running" {
+		return ActionProviderRunning, detail, nil
+	}
+
+	if err := db.Model(&models.Win568Bet{}).Where("id = ?", report.ID).
+		Update("is_resend", false).Error; err != nil {
+		return "", "", err
+	}
+	return ActionProviderSettled, detail + ", resend requested", nil
+}
@@ -0,0 +1,403 @@
+// Package staleround mencari bet/round yang tidak pernah ditutup provider,
+// mencatatnya ke stale_round_alerts, lalu menjalankan policy per provider.
+//
+// Batas waktu dan policy diatur lewat env:
+//
+//	STALE_ROUND_AFTER                default batas umur round (default 2h)
+//	STALE_ROUND_AFTER_<PROVIDER>     override per provider, mis. STALE_ROUND_AFTER_SBO=24h
+//	STALE_ROUND_POLICY_<PROVIDER>    alert | refund | check_status (default alert,
+//	                                 SBO default check_status)
+package staleround
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"telo/models"
+	"telo/services/gametx"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	SourceGameTx    = "user_game_transaction"
+	SourceSBO       = "x568win_transaction"
+	SourceWM        = "wm_sub_bet"
+	SourceEvolution = "evolution_transaction"
+
+	ActionAlerted          = "ALERTED"
+	ActionRefunded         = "REFUNDED"
+	ActionRefundFailed     = "REFUND_FAILED"
+	ActionProviderSettled  = "PROVIDER_SETTLED"
+	ActionProviderRunning  = "PROVIDER_RUNNING"
+	ActionProviderNotFound = "PROVIDER_NOT_FOUND"
+
+	// batch per sumber per run, supaya satu run tidak memuat seluruh tabel
+	scanLimit = 500
+)
+
+// evolutionProviderSQL memetakan label EvolutionTransaction.Provider ke label
+// provider di wallet. Sebelum produk dipisah, slot juga berlabel
+// "Evolution Live".
+const evolutionProviderSQL = `CASE MAX(e.provider) WHEN 'Evolution Slot' THEN 'EVOLUTION_SLOT' ELSE 'EVOLUTION_LIVE' END`
+
+var (
+	defaultAfter = 2 * time.Hour
+	// sportsbook wajar terbuka lebih lama (pertandingan belum mulai)
+	providerAfter  = map[string]time.Duration{"SBO": 24 * time.Hour}
+	providerPolicy = map[string]string{"SBO": models.StalePolicyCheckStatus}
+
+	knownProviders = []string{"PRAGMATIC", "PLAYSTAR", "EVOLUTION_SLOT", "EVOLUTION_LIVE", "FASTSPIN", "SPADEGAMING", "TELO", "SBO"}
+)
+
+// Config adalah batas umur dan policy untuk satu provider.
+type Config struct {
+	After  time.Duration
+	Policy string
+}
+
+// ConfigFor membaca konfigurasi provider dari env.
+func ConfigFor(provider string) Config {
+	provider = strings.ToUpper(provider)
+
+	cfg := Config{After: defaultAfter, Policy: models.StalePolicyAlert}
+	if d, ok := providerAfter[provider]; ok {
+		cfg.After = d
+	}
+	if p, ok := providerPolicy[provider]; ok {
+		cfg.Policy = p
+	}
+
+	for _, key := range []string{"STALE_ROUND_AFTER", "STALE_ROUND_AFTER_" + provider} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.After = d
+		} else {
+			log.Printf("⚠️  Invalid value for %s: %s\n", key, v)
+		}
+	}
+
+	if v := strings.ToLower(os.Getenv("STALE_ROUND_POLICY_" + provider)); v != "" {
+		switch v {
+		case models.StalePolicyAlert, models.StalePolicyRefund, models.StalePolicyCheckStatus:
+			cfg.Policy = v
+		default:
+			log.Printf("⚠️  Invalid value for STALE_ROUND_POLICY_%s: %s\n", provider, v)
+		}
+	}
+	return cfg
+}
+
+// candidate adalah satu bet yang masih terbuka, dari tabel mana pun.
+type candidate struct {
+	Provider string
+	Source   string
+	Ref      string
+	User     models.User
+	Amount   decimal.Decimal
+	OpenedAt time.Time
+
+	gameTx *models.UserGameTransaction
+}
+
+// Scan menjalankan satu putaran deteksi. Mengembalikan jumlah round stale
+// yang diproses.
+func Scan(db *gorm.DB, now time.Time) (int, error) {
+	cands, err := collect(db, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range cands {
+		if err := process(db, c, now); err != nil {
+			log.Printf("❌ [STALEROUND] %s %s: %v", c.Provider, c.Ref, err)
+		}
+	}
+
+	if err := resolveClosed(db, now); err != nil {
+		return len(cands), err
+	}
+	return len(cands), nil
+}
+
+// collect mengumpulkan bet terbuka dari semua sumber. Sumber spesifik provider
+// dibaca dulu supaya policy check_status SBO tahu sumber aslinya; baris
+// UserGameTransaction dengan kunci yang sama dilewati.
+func collect(db *gorm.DB, now time.Time) ([]candidate, error) {
+	var out []candidate
+	seen := map[string]bool{}
+	users := map[string]*models.User{}
+
+	add := func(c candidate) {
+		key := c.Provider + "|" + c.Ref
+		if seen[key] || c.Ref == "" {
+			return
+		}
+		if now.Sub(c.OpenedAt) < ConfigFor(c.Provider).After {
+			return
+		}
+		seen[key] = true
+		out = append(out, c)
+	}
+	lookup := func(code string) (*models.User, error) {
+		if u, ok := users[code]; ok {
+			return u, nil
+		}
+		var u models.User
+		if err := db.Where("user_code = ?", code).Limit(1).Find(&u).Error; err != nil {
+			return nil, err
+		}
+		if u.ID == 0 {
+			users[code] = nil
+			return nil, nil
+		}
+		users[code] = &u
+		return &u, nil
+	}
+
+	// Evolution: debit tanpa credit dan belum di-cancel.
+	var evo []struct {
+		RefID     string
+		UserID    uint
+		Provider  string
+		Amount    decimal.Decimal
+		CreatedAt time.Time
+	}
+	evoAfter := ConfigFor("EVOLUTION_SLOT").After
+	if d := ConfigFor("EVOLUTION_LIVE").After; d < evoAfter {
+		evoAfter = d
+	}
+	err := db.Raw(`
+		SELECT e.ref_id, e.user_id, `+evolutionProviderSQL+` AS provider,
+			SUM(e.amount) AS amount, MIN(e.created_at) AS created_at
+		FROM evolution_transactions e
+		WHERE e.deleted_at IS NULL AND e.type = 'DEBIT' AND e.status = 'SUCCESS' AND e.created_at < ?
+			AND NOT EXISTS (
+				SELECT 1 FROM evolution_transactions c
+				WHERE c.ref_id = e.ref_id AND c.type = 'CREDIT' AND c.deleted_at IS NULL)
+		GROUP BY e.ref_id, e.user_id
+		ORDER BY MIN(e.created_at)
+		LIMIT ?`, now.Add(-evoAfter), scanLimit).Scan(&evo).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range evo {
+		var u models.User
+		if err := db.Limit(1).Find(&u, e.UserID).Error; err != nil {
+			return nil, err
+		}
+		if u.ID == 0 {
+			continue
+		}
+		add(candidate{Provider: e.Provider, Source: SourceEvolution, Ref: e.RefID, User: u, Amount: e.Amount, OpenedAt: e.CreatedAt})
+	}
+
+	sboCutoff := now.Add(-ConfigFor("SBO").After)
+
+	var trxs []models.X568WinTransaction
+	if err := db.Where("status = ? AND updated_at < ?", "Running", sboCutoff).
+		Order("id").Limit(scanLimit).Find(&trxs).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range trxs {
+		u, err := lookup(t.Username)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			continue
+		}
+		add(candidate{
+			Provider: "SBO", Source: SourceSBO, Ref: t.TransferCode, User: *u,
+			Amount:   t.Amount.Mul(gametx.SBORate(u.Currency)),
+			OpenedAt: t.UpdatedAt,
+		})
+	}
+
+	var bets []models.WmSubBet
+	if err := db.Where("status = ? AND updated_at < ?", "Running", sboCutoff).
+		Order("id").Limit(scanLimit).Find(&bets).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range bets {
+		u, err := lookup(b.UserCode)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			continue
+		}
+		add(candidate{Provider: "SBO", Source: SourceWM, Ref: b.TransactionId, User: *u, Amount: b.Amount, OpenedAt: b.UpdatedAt})
+	}
+
+	// Playstar menulis status BET untuk bet yang belum ada result.
+	var rows []models.UserGameTransaction
+	if err := db.Where("status IN ? AND updated_at < ?", []string{gametx.StatusRunning, "BET"}, now.Add(-minAfter())).
+		Order("id").Limit(scanLimit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		// Sub-bet WM sudah dipindai dari wm_sub_bets di atas.
+		if rows[i].Provider == gametx.ProviderSBOWM {
+			continue
+		}
+		var u models.User
+		if err := db.Limit(1).Find(&u, rows[i].UserID).Error; err != nil {
+			return nil, err
+		}
+		if u.ID == 0 {
+			continue
+		}
+		add(candidate{
+			Provider: strings.ToUpper(rows[i].Provider), Source: SourceGameTx, Ref: rows[i].ProviderTx, User: u,
+			Amount:   rows[i].BetAmount.Sub(rows[i].RefundAmount),
+			OpenedAt: rows[i].UpdatedAt,
+			gameTx:   &rows[i],
+		})
+	}
+
+	return out, nil
+}
+
+// minAfter: batas terkecil di antara semua provider, untuk query
+// UserGameTransaction yang mencakup banyak provider sekaligus. Filter per
+// provider dilakukan di add.
+func minAfter() time.Duration {
+	m := ConfigFor("").After
+	for _, p := range knownProviders {
+		if d := ConfigFor(p).After; d < m {
+			m = d
+		}
+	}
+	return m
+}
+
+func process(db *gorm.DB, c candidate, now time.Time) error {
+	cfg := ConfigFor(c.Provider)
+
+	var alert models.StaleRoundAlert
+	if err := db.Where("provider = ? AND ref = ?", c.Provider, c.Ref).Limit(1).Find(&alert).Error; err != nil {
+		return err
+	}
+	isNew := alert.ID == 0
+	// Refund hanya dicoba sekali sampai berhasil; alert lain dievaluasi ulang.
+	if alert.Action == ActionRefunded {
+		return nil
+	}
+
+	action, detail := ActionAlerted, ""
+	switch cfg.Policy {
+	case models.StalePolicyRefund:
+		if !refundable(c) {
+			detail = "auto-refund not supported for this provider"
+			break
+		}
+		if err := refund(db, c); err != nil {
+			action, detail = ActionRefundFailed, err.Error()
+		} else {
+			action, detail = ActionRefunded, "stake returned to user"
+		}
+	case models.StalePolicyCheckStatus:
+		if c.Source != SourceSBO {
+			detail = "status check not supported for this source"
+			break
+		}
+		var err error
+		action, detail, err = checkSBO(db, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	alert.Provider = c.Provider
+	alert.Ref = c.Ref
+	alert.Source = c.Source
+	alert.UserCode = c.User.UserCode
+	alert.AgentCode = c.User.AgentCode
+	alert.Amount = c.Amount
+	alert.OpenedAt = c.OpenedAt
+	alert.Policy = cfg.Policy
+	alert.Action = action
+	alert.Detail = truncate(detail, 255)
+	alert.Checks++
+	alert.LastCheckedAt = now
+	alert.Status = models.StaleAlertOpen
+	alert.ResolvedAt = nil
+	if action == ActionRefunded {
+		alert.Status = models.StaleAlertResolved
+		alert.ResolvedAt = &now
+	}
+	if err := db.Save(&alert).Error; err != nil {
+		return err
+	}
+
+	if isNew || action != ActionAlerted {
+		log.Printf("⚠️ [STALEROUND] %s %s user=%s amount=%s open since %s → %s %s",
+			c.Provider, c.Ref, c.User.UserCode, c.Amount, c.OpenedAt.Format(time.RFC3339), action, detail)
+	}
+	return nil
+}
+
+// resolveClosed menutup alert OPEN yang bet-nya sudah diselesaikan provider.
+func resolveClosed(db *gorm.DB, now time.Time) error {
+	var alerts []models.StaleRoundAlert
+	if err := db.Where("status = ?", models.StaleAlertOpen).Find(&alerts).Error; err != nil {
+		return err
+	}
+	for _, a := range alerts {
+		open, err := stillOpen(db, a)
+		if err != nil {
+			return err
+		}
+		if open {
+			continue
+		}
+		if err := db.Model(&a).Updates(map[string]any{
+			"status":      models.StaleAlertResolved,
+			"resolved_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		log.Printf("✅ [STALEROUND] %s %s closed by provider", a.Provider, a.Ref)
+	}
+	return nil
+}
+
+func stillOpen(db *gorm.DB, a models.StaleRoundAlert) (bool, error) {
+	var n int64
+	var err error
+	switch a.Source {
+	case SourceEvolution:
+		err = db.Raw(`
+			SELECT COUNT(*) FROM evolution_transactions e
+			WHERE e.deleted_at IS NULL AND e.ref_id = ? AND e.type = 'DEBIT' AND e.status = 'SUCCESS'
+				AND NOT EXISTS (
+					SELECT 1 FROM evolution_transactions c
+					WHERE c.ref_id = e.ref_id AND c.type = 'CREDIT' AND c.deleted_at IS NULL)`, a.Ref).
+			Scan(&n).Error
+	case SourceSBO:
+		err = db.Model(&models.X568WinTransaction{}).
+			Where("transfer_code = ? AND username = ? AND status = ?", a.Ref, a.UserCode, "Running").Count(&n).Error
+	case SourceWM:
+		err = db.Model(&models.WmSubBet{}).
+			Where("transaction_id = ? AND status = ?", a.Ref, "Running").Count(&n).Error
+	default:
+		err = db.Model(&models.UserGameTransaction{}).
+			Where("provider = ? AND provider_tx = ? AND status IN ?", a.Provider, a.Ref, []string{gametx.StatusRunning, "BET"}).
+			Count(&n).Error
+	}
+	return n > 0, err
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
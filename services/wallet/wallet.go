@@ -0,0 +1,389 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"telo/models"
+	"telo/services/agentwallet"
+	"telo/services/ledger"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OpDebit    = "DEBIT"
+	OpCredit   = "CREDIT"
+	OpRollback = "ROLLBACK"
+	OpCancel   = "CANCEL"
+)
+
+var (
+	ErrUserNotFound = errors.New("wallet: user not found")
+	ErrUserInactive = errors.New("wallet: user inactive")
+	// ErrAgentInactive membungkus ErrUserInactive supaya mapping error provider
+	// yang sudah ada tetap berlaku untuk user dari agent yang di-suspend.
+	ErrAgentInactive     = fmt.Errorf("%w: agent inactive", ErrUserInactive)
+	ErrInsufficientFunds = errors.New("wallet: insufficient balance")
+	ErrInvalidAmount     = errors.New("wallet: invalid amount")
+)
+
+// Request menjelaskan satu pergerakan saldo user.
+//
+// Amount selalu positif, arah debit/credit ditentukan oleh operasi yang
+// dipanggil. Kalau ExternalID diisi, kombinasi Provider + operasi +
+// ExternalID hanya akan diproses sekali per user; request berikutnya mendapat
+// Result dengan Duplicate = true dan saldo dari pemrosesan pertama.
+type Request struct {
+	UserCode   string
+	Provider   string
+	ExternalID string
+	Amount     decimal.Decimal
+	RefID      string
+	Note       string
+	// CounterAccount adalah akun ledger lawan; default akun provider.
+	CounterAccount string
+
+	// RequireActive menolak pergerakan saldo kalau user atau agent-nya tidak
+	// aktif. Debit (bet baru) selalu memerlukan akun aktif; settle, refund dan
+	// rollback tetap diterima supaya round yang sudah berjalan bisa ditutup.
+	RequireActive bool
+	// AllowNegative mengizinkan debit membuat saldo minus.
+	AllowNegative bool
+}
+
+type Result struct {
+	User          models.User
+	BalanceBefore decimal.Decimal
+	BalanceAfter  decimal.Decimal
+	Duplicate     bool
+	TransactionID uint
+}
+
+// Debit mengurangi saldo user, misalnya untuk bet. User dan agent harus aktif.
+func Debit(db *gorm.DB, req Request) (*Result, error) {
+	req.RequireActive = true
+	return apply(db, OpDebit, true, req)
+}
+
+// Withdraw menarik saldo user kembali ke agent (/user/transfer dengan amount
+// negatif). Berbeda dengan Debit, user yang di-suspend tetap boleh ditarik
+// saldonya supaya bisa ditutup; saldo tetap tidak boleh minus.
+func Withdraw(db *gorm.DB, req Request) (*Result, error) {
+	return apply(db, OpDebit, true, req)
+}
+
+// Credit menambah saldo user, misalnya untuk win atau bonus.
+func Credit(db *gorm.DB, req Request) (*Result, error) {
+	return apply(db, OpCredit, false, req)
+}
+
+// Rollback membatalkan credit sebelumnya. Saldo boleh menjadi minus karena
+// provider tidak bisa menolak rollback.
+func Rollback(db *gorm.DB, req Request) (*Result, error) {
+	req.AllowNegative = true
+	return apply(db, OpRollback, true, req)
+}
+
+// Cancel mengembalikan stake yang sudah di-debit ke user.
+func Cancel(db *gorm.DB, req Request) (*Result, error) {
+	return apply(db, OpCancel, false, req)
+}
+
+// apply cek idempotency, meneruskan ke wallet agent (mode seamless), lalu
+// mengunci row user, update saldo dan menulis WalletTransaction dalam satu DB
+// transaction. Kalau db sudah berada di dalam transaction, gorm memakai
+// savepoint sehingga caller tetap bisa menggabungkan operasi ini dengan
+// update tabel provider.
+//
+// Result selalu dikembalikan (juga saat error) supaya caller bisa memakai
+// saldo terakhir user di response error.
+func apply(db *gorm.DB, op string, debit bool, req Request) (*Result, error) {
+	res := &Result{}
+
+	if req.Amount.IsNegative() {
+		return res, ErrInvalidAmount
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("user_code = ?", req.UserCode).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		res.User = user
+		res.BalanceBefore = user.Balance
+		res.BalanceAfter = user.Balance
+
+		if dup, err := duplicate(tx, user.ID, op, req, res); dup || err != nil {
+			return err
+		}
+
+		var agent models.Agent
+		if err := tx.Where("agent_code = ?", user.AgentCode).First(&agent).Error; err != nil &&
+			!errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if req.RequireActive {
+			if err := activeError(user, agent); err != nil {
+				return err
+			}
+		}
+
+		amount := req.Amount
+		if debit {
+			amount = amount.Neg()
+		}
+
+		// Saldo agent mode seamless dipegang agent: users.balance hanya cermin
+		// saldo terakhir yang dilaporkan agent. Panggilan ke agent dilakukan
+		// sebelum row user di-lock supaya agent yang lambat tidak menahan
+		// callback lain untuk user ini. Baris AgentWalletCall (disimpan di luar
+		// tx) adalah reservasinya: bet yang tidak pernah terhubung ke
+		// WalletTransaction di bawah di-rollback oleh job.
+		var call *models.AgentWalletCall
+		var callErr error
+		if agent.WalletMode == agentwallet.ModeSeamless {
+			call, callErr = forward(tx, &agent, op, user, req, amount)
+			switch {
+			case errors.Is(callErr, agentwallet.ErrInsufficientFunds):
+				return ErrInsufficientFunds
+			case errors.Is(callErr, agentwallet.ErrQueued):
+				// credit akan di-retry job; saldo cermin diperkirakan
+			case callErr != nil:
+				return callErr
+			}
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+			return err
+		}
+		res.User = user
+		res.BalanceBefore = user.Balance
+		res.BalanceAfter = user.Balance
+
+		// Retry bersamaan dengan ExternalID yang sama bisa sudah tercatat
+		// selama panggilan ke agent.
+		if dup, err := duplicate(tx, user.ID, op, req, res); dup || err != nil {
+			return err
+		}
+
+		mirror := user.Balance
+		before := user.Balance
+		after := before.Add(amount)
+
+		if call != nil {
+			if callErr == nil && call.AgentBalance.Valid {
+				after = call.AgentBalance.Decimal
+				before = after.Sub(amount)
+			}
+			if err := syncMirror(tx, &agent, user, before); err != nil {
+				return err
+			}
+			mirror = before
+		} else if debit && !req.AllowNegative && after.IsNegative() {
+			return ErrInsufficientFunds
+		}
+
+		if !after.Equal(mirror) {
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+				Update("balance", after).Error; err != nil {
+				return err
+			}
+		}
+
+		wtx := models.WalletTransaction{
+			UserID:        user.ID,
+			UserCode:      user.UserCode,
+			AgentCode:     user.AgentCode,
+			Provider:      req.Provider,
+			Operation:     op,
+			ExternalID:    req.ExternalID,
+			Amount:        amount,
+			BalanceBefore: before,
+			BalanceAfter:  after,
+			Currency:      user.Currency,
+			RefID:         req.RefID,
+			Note:          req.Note,
+		}
+		if err := tx.Create(&wtx).Error; err != nil {
+			return err
+		}
+
+		if !amount.IsZero() {
+			counter := req.CounterAccount
+			if counter == "" {
+				counter = ledger.ProviderAccount(req.Provider)
+			}
+			if err := ledger.PostUserMovement(tx, user, counter, amount, req.Provider, op, req.Note, &wtx.ID); err != nil {
+				return err
+			}
+		}
+
+		if call != nil {
+			// Hanya ter-commit bersama transaction ini; bet yang tidak
+			// terhubung dianggap yatim dan di-rollback oleh job.
+			if err := tx.Model(&models.AgentWalletCall{}).Where("id = ?", call.ID).
+				Update("wallet_transaction_id", wtx.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		user.Balance = after
+		res.User = user
+		res.BalanceBefore = before
+		res.BalanceAfter = after
+		res.TransactionID = wtx.ID
+		return nil
+	})
+
+	if err != nil {
+		res.BalanceAfter = res.BalanceBefore
+		res.User.Balance = res.BalanceBefore
+		return res, err
+	}
+	return res, nil
+}
+
+// duplicate mengisi res dari WalletTransaction yang sudah ada untuk
+// Provider + op + ExternalID ini.
+func duplicate(tx *gorm.DB, userID uint, op string, req Request, res *Result) (bool, error) {
+	if req.ExternalID == "" {
+		return false, nil
+	}
+	var existing models.WalletTransaction
+	err := tx.Where("user_id = ? AND provider = ? AND operation = ? AND external_id = ?",
+		userID, req.Provider, op, req.ExternalID).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	res.BalanceBefore = existing.BalanceBefore
+	res.BalanceAfter = existing.BalanceAfter
+	res.Duplicate = true
+	res.TransactionID = existing.ID
+	return true, nil
+}
+
+// forward meneruskan pergerakan saldo ke wallet agent mode seamless. amount
+// bertanda (negatif = debit).
+func forward(tx *gorm.DB, agent *models.Agent, op string, user models.User, req Request, amount decimal.Decimal) (*models.AgentWalletCall, error) {
+	call := agentwallet.Call{
+		UserCode:   user.UserCode,
+		Currency:   user.Currency,
+		Provider:   req.Provider,
+		Operation:  op,
+		ExternalID: req.ExternalID,
+		Amount:     amount,
+	}
+
+	switch op {
+	case OpDebit:
+		return agentwallet.Bet(agent, call)
+	case OpCredit:
+		return agentwallet.Settle(agent, call)
+	default:
+		// cancel bet: sertakan tx_id bet asal kalau ketemu
+		if op == OpCancel && req.ExternalID != "" {
+			var bet models.AgentWalletCall
+			if err := tx.Where("user_code = ? AND provider = ? AND external_id = ? AND action = ? AND status = ?",
+				user.UserCode, req.Provider, req.ExternalID, agentwallet.ActionBet, agentwallet.StatusOK).
+				Order("id DESC").First(&bet).Error; err == nil {
+				call.RefTxID = bet.TxID
+			}
+		}
+		return agentwallet.Rollback(agent, call)
+	}
+}
+
+// syncMirror menyamakan saldo cermin user dengan saldo yang dilaporkan agent
+// (misalnya karena saldo di wallet agent berubah di luar kita), lengkap dengan
+// jurnal SYNC supaya ledger tetap cocok dengan users.balance.
+func syncMirror(tx *gorm.DB, agent *models.Agent, user models.User, reported decimal.Decimal) error {
+	diff := reported.Sub(user.Balance)
+	if diff.IsZero() {
+		return nil
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("balance", reported).Error; err != nil {
+		return err
+	}
+	return ledger.PostUserMovement(tx, user, ledger.AgentAccount(agent.AgentCode), diff, "AGENT", "SYNC",
+		"Agent wallet balance sync", nil)
+}
+
+// RefreshBalance memperbarui saldo user dari wallet agent kalau agent-nya
+// mode seamless; untuk mode transfer tidak melakukan apa-apa. Dipakai endpoint
+// balance provider. Kalau agent tidak bisa dihubungi, user tetap berisi saldo
+// cermin terakhir dan error dikembalikan.
+func RefreshBalance(db *gorm.DB, user *models.User) error {
+	agent, err := agentwallet.Seamless(db, user.AgentCode)
+	if err != nil || agent == nil {
+		return err
+	}
+
+	reported, err := agentwallet.Balance(agent, user.UserCode, user.Currency)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var locked models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, user.ID).Error; err != nil {
+			return err
+		}
+		if err := syncMirror(tx, agent, locked, reported); err != nil {
+			return err
+		}
+		user.Balance = reported
+		return nil
+	})
+}
+
+// LockBet mengambil advisory lock transaction-level untuk satu bet provider.
+// Refund (provider maupun job stale round) dan settlement bet yang sama
+// memanggilnya sebelum memeriksa Refunded, jadi keduanya tidak bisa saling
+// menyelip tanpa menahan row user selama panggilan ke wallet agent.
+func LockBet(tx *gorm.DB, provider, externalID string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", provider+":"+externalID).Error
+}
+
+// Refunded mengembalikan true kalau stake dengan ExternalID ini sudah
+// dikembalikan lewat Cancel, oleh provider maupun job stale round.
+func Refunded(tx *gorm.DB, userID uint, provider, externalID string) (bool, error) {
+	var count int64
+	err := tx.Model(&models.WalletTransaction{}).
+		Where("user_id = ? AND provider = ? AND operation = ? AND external_id = ?",
+			userID, provider, OpCancel, externalID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CheckActive mengembalikan ErrUserInactive / ErrAgentInactive kalau user atau
+// agent-nya di-suspend. Dipakai callback yang membuka sesi game baru
+// (authenticate, user check, get balance awal).
+func CheckActive(db *gorm.DB, user models.User) error {
+	var agent models.Agent
+	if err := db.Where("agent_code = ?", user.AgentCode).First(&agent).Error; err != nil &&
+		!errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return activeError(user, agent)
+}
+
+func activeError(user models.User, agent models.Agent) error {
+	if !user.IsActive {
+		return ErrUserInactive
+	}
+	if agent.ID != 0 && !agent.IsActive {
+		return ErrAgentInactive
+	}
+	return nil
+}
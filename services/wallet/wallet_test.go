@@ -0,0 +1,220 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"telo/database"
+	"telo/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Test wallet butuh postgres (row lock, partial unique index, advisory lock).
+// Set TEST_DATABASE_URL ke database kosong untuk menjalankannya; setiap test
+// berjalan di dalam transaction yang di-rollback.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Agent{},
+		&models.User{},
+		&models.UserTransaction{},
+		&models.UserGameTransaction{},
+		&models.WalletTransaction{},
+		&models.LedgerEntry{},
+		&models.AgentWalletCall{},
+	); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// agentwallet menulis AgentWalletCall lewat database.DB, di luar tx test.
+	database.DB = db
+
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func seedUser(t *testing.T, tx *gorm.DB, balance string, userActive, agentActive bool, walletURL string) models.User {
+	t.Helper()
+	code := fmt.Sprintf("T%d", time.Now().UnixNano())
+
+	agent := models.Agent{AgentCode: code, SecretKey: "secret", IsActive: true}
+	if walletURL != "" {
+		agent.WalletMode = "seamless"
+		agent.WalletURL = walletURL
+	}
+	if err := tx.Create(&agent).Error; err != nil {
+		t.Fatalf("create agent: %v", err)
+	}
+	user := models.User{UserCode: code, AgentCode: code, Balance: decimal.RequireFromString(balance), Currency: "IDR", IsActive: true}
+	if err := tx.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	// default:true membuat false tidak ikut ter-insert
+	if err := tx.Model(&agent).Update("is_active", agentActive).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Model(&user).Update("is_active", userActive).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func balanceOf(t *testing.T, tx *gorm.DB, userID uint) decimal.Decimal {
+	t.Helper()
+	var u models.User
+	if err := tx.First(&u, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u.Balance
+}
+
+func TestApply(t *testing.T) {
+	type step struct {
+		op      func(*gorm.DB, Request) (*Result, error)
+		extID   string
+		amount  string
+		wantErr error
+		wantDup bool
+		want    string // saldo user setelah step
+	}
+	tests := []struct {
+		name        string
+		balance     string
+		userActive  bool
+		agentActive bool
+		steps       []step
+	}{
+		{
+			name: "debit then credit", balance: "100", userActive: true, agentActive: true,
+			steps: []step{
+				{op: Debit, extID: "b1", amount: "30", want: "70"},
+				{op: Credit, extID: "b1", amount: "50", want: "120"},
+			},
+		},
+		{
+			name: "duplicate external id is applied once", balance: "100", userActive: true, agentActive: true,
+			steps: []step{
+				{op: Debit, extID: "b1", amount: "30", want: "70"},
+				{op: Debit, extID: "b1", amount: "30", wantDup: true, want: "70"},
+				{op: Credit, extID: "b1", amount: "10", want: "80"},
+				{op: Credit, extID: "b1", amount: "10", wantDup: true, want: "80"},
+			},
+		},
+		{
+			name: "insufficient funds", balance: "10", userActive: true, agentActive: true,
+			steps: []step{
+				{op: Debit, extID: "b1", amount: "30", wantErr: ErrInsufficientFunds, want: "10"},
+				{op: Debit, extID: "b2", amount: "10", want: "0"},
+			},
+		},
+		{
+			name: "rollback may go negative", balance: "10", userActive: true, agentActive: true,
+			steps: []step{
+				{op: Rollback, extID: "w1", amount: "30", want: "-20"},
+			},
+		},
+		{
+			name: "inactive user can settle but not bet", balance: "100", userActive: false, agentActive: true,
+			steps: []step{
+				{op: Debit, extID: "b1", amount: "30", wantErr: ErrUserInactive, want: "100"},
+				{op: Credit, extID: "b0", amount: "20", want: "120"},
+				{op: Cancel, extID: "b0", amount: "5", want: "125"},
+				{op: Withdraw, extID: "w1", amount: "125", want: "0"},
+			},
+		},
+		{
+			name: "inactive agent", balance: "100", userActive: true, agentActive: false,
+			steps: []step{
+				{op: Debit, extID: "b1", amount: "30", wantErr: ErrAgentInactive, want: "100"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := testDB(t)
+			user := seedUser(t, tx, tt.balance, tt.userActive, tt.agentActive, "")
+
+			for i, s := range tt.steps {
+				res, err := s.op(tx, Request{
+					UserCode:   user.UserCode,
+					Provider:   "TEST",
+					ExternalID: s.extID,
+					Amount:     decimal.RequireFromString(s.amount),
+				})
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: err = %v, want %v", i, err, s.wantErr)
+				}
+				if err == nil && res.Duplicate != s.wantDup {
+					t.Fatalf("step %d: duplicate = %v, want %v", i, res.Duplicate, s.wantDup)
+				}
+				if got := balanceOf(t, tx, user.ID); !got.Equal(decimal.RequireFromString(s.want)) {
+					t.Fatalf("step %d: balance = %s, want %s", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplySeamlessSyncsMirror(t *testing.T) {
+	// Wallet agent melaporkan saldo 500 setelah bet 20, padahal cermin kita
+	// 100: saldo cermin harus disamakan (dengan jurnal SYNC) sebelum bet.
+	var reply struct {
+		Status  string          `json:"status"`
+		Balance decimal.Decimal `json:"balance"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(reply)
+	}))
+	defer srv.Close()
+
+	tx := testDB(t)
+	user := seedUser(t, tx, "100", true, true, srv.URL)
+
+	reply.Status, reply.Balance = "OK", decimal.NewFromInt(500)
+	res, err := Debit(tx, Request{UserCode: user.UserCode, Provider: "TEST", ExternalID: "b1", Amount: decimal.NewFromInt(20)})
+	if err != nil {
+		t.Fatalf("debit: %v", err)
+	}
+	if !res.BalanceBefore.Equal(decimal.NewFromInt(520)) || !res.BalanceAfter.Equal(decimal.NewFromInt(500)) {
+		t.Fatalf("balance %s -> %s, want 520 -> 500", res.BalanceBefore, res.BalanceAfter)
+	}
+	if got := balanceOf(t, tx, user.ID); !got.Equal(decimal.NewFromInt(500)) {
+		t.Fatalf("mirror balance = %s, want 500", got)
+	}
+
+	var sync int64
+	if err := tx.Model(&models.LedgerEntry{}).
+		Where("user_id = ? AND operation = ?", user.ID, "SYNC").Count(&sync).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sync == 0 {
+		t.Fatal("expected a SYNC ledger entry for the mirror correction")
+	}
+
+	reply.Status = "INSUFFICIENT_FUNDS"
+	if _, err := Debit(tx, Request{UserCode: user.UserCode, Provider: "TEST", ExternalID: "b2", Amount: decimal.NewFromInt(1000)}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("debit rejected by agent: err = %v, want ErrInsufficientFunds", err)
+	}
+	if got := balanceOf(t, tx, user.ID); !got.Equal(decimal.NewFromInt(500)) {
+		t.Fatalf("balance after rejected bet = %s, want 500", got)
+	}
+}